@@ -0,0 +1,100 @@
+package bus
+
+import (
+	"log"
+	"time"
+	"transport/lib/database"
+	"transport/lib/nulltypes"
+
+	"github.com/lib/pq"
+)
+
+// AffectedRoute is a route and direction disrupted by a ServiceAlert
+type AffectedRoute struct {
+	RouteID     string
+	DirectionID int
+}
+
+// ServiceAlert is a disruption published by the MTA in the SIRI SituationExchange, such as
+// a detour or suspended stop. Vehicles on affected trips list the alert's SituationNumber
+// in their SituationRef.
+type ServiceAlert struct {
+	SituationNumber string
+	Severity        string
+	Summary         string
+	Description     string
+	CreationTime    database.Timestamp
+	// The alert is shown to riders from PublicationStart until PublicationEnd,
+	// which is null if the alert has no planned end
+	PublicationStart database.Timestamp
+	PublicationEnd   nulltypes.Timestamp
+	Affected         []AffectedRoute
+}
+
+// Active returns true if the alert's publication window contains `t`
+func (a ServiceAlert) Active(t time.Time) bool {
+	if t.Before(a.PublicationStart.Time) {
+		return false
+	}
+	return !a.PublicationEnd.Valid || t.Before(a.PublicationEnd.Time)
+}
+
+// AffectsRoute returns true if the alert disrupts the route in the given direction
+func (a ServiceAlert) AffectsRoute(routeID string, directionID int) bool {
+	for _, affected := range a.Affected {
+		if affected.RouteID == routeID && affected.DirectionID == directionID {
+			return true
+		}
+	}
+	return false
+}
+
+// AffectsJourney returns true if the vehicle references the alert in its SituationRef,
+// or is running on an affected route and direction
+func (a ServiceAlert) AffectsJourney(vj VehicleJourney) bool {
+	for _, ref := range vj.SituationRef.StringSlice {
+		if ref == a.SituationNumber {
+			return true
+		}
+	}
+	return a.AffectsRoute(vj.LineRef.String, int(vj.DirectionRef.Int64))
+}
+
+// AlertsForJourney joins alerts to a vehicle, returning the alerts that affect it
+func AlertsForJourney(alerts []ServiceAlert, vj VehicleJourney) []ServiceAlert {
+	var matching []ServiceAlert
+	for _, alert := range alerts {
+		if alert.AffectsJourney(vj) {
+			matching = append(matching, alert)
+		}
+	}
+	return matching
+}
+
+// ServiceAlertToInterface converts a slice of ServiceAlert structs into a slice of interface{}
+func ServiceAlertToInterface(alerts []ServiceAlert) []interface{} {
+	r := make([]interface{}, len(alerts))
+	for i, alert := range alerts {
+		r[i] = alert
+	}
+	return r
+}
+
+// ExtractEntriesFromServiceAlert converts a single ServiceAlert struct into
+// a slice of interface{} which represents the database row. The affected routes
+// are stored as two arrays, where the n-th direction belongs to the n-th route.
+func ExtractEntriesFromServiceAlert(aEntry interface{}) []interface{} {
+	a, ok := aEntry.(ServiceAlert)
+	if !ok {
+		log.Panicf("ExtractEntriesFromServiceAlert: entry passed in is not a ServiceAlert struct")
+	}
+	routeIDs, directionIDs := make([]string, len(a.Affected)), make([]int64, len(a.Affected))
+	for i, affected := range a.Affected {
+		routeIDs[i], directionIDs[i] = affected.RouteID, int64(affected.DirectionID)
+	}
+	return []interface{}{
+		a.SituationNumber, a.Severity, a.Summary, a.Description,
+		a.CreationTime.Time, a.PublicationStart.Time, a.PublicationEnd,
+		pq.Array(routeIDs), pq.Array(directionIDs),
+	}
+}
@@ -0,0 +1,44 @@
+package bus
+
+import (
+	"testing"
+	"time"
+	"transport/lib/database"
+	"transport/lib/nulltypes"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/guregu/null.v3"
+)
+
+var alertStart = time.Date(2019, 6, 3, 6, 0, 0, 0, time.UTC)
+
+var detour = ServiceAlert{
+	SituationNumber:  "MTA NYCT_224082",
+	Summary:          "Detour",
+	PublicationStart: database.Timestamp{Time: alertStart},
+	PublicationEnd:   nulltypes.TimestampFrom(database.Timestamp{Time: alertStart.Add(4 * time.Hour)}),
+	Affected:         []AffectedRoute{{RouteID: "MTA NYCT_B41", DirectionID: 1}},
+}
+
+func TestServiceAlert_Active(t *testing.T) {
+	assert.False(t, detour.Active(alertStart.Add(-time.Minute)))
+	assert.True(t, detour.Active(alertStart))
+	assert.False(t, detour.Active(alertStart.Add(4*time.Hour)))
+
+	openEnded := detour
+	openEnded.PublicationEnd = nulltypes.Timestamp{}
+	assert.True(t, openEnded.Active(alertStart.Add(24*time.Hour)))
+}
+
+func TestAlertsForJourney(t *testing.T) {
+	onRoute := VehicleJourney{LineRef: null.StringFrom("MTA NYCT_B41"), DirectionRef: null.IntFrom(1)}
+	otherDirection := VehicleJourney{LineRef: null.StringFrom("MTA NYCT_B41"), DirectionRef: null.IntFrom(0)}
+	referenced := VehicleJourney{
+		LineRef:      null.StringFrom("MTA NYCT_M55"),
+		SituationRef: nulltypes.StringSliceFrom([]string{"MTA NYCT_224082"}),
+	}
+	alerts := []ServiceAlert{detour}
+	assert.Equal(t, alerts, AlertsForJourney(alerts, onRoute))
+	assert.Empty(t, AlertsForJourney(alerts, otherDirection))
+	assert.Equal(t, alerts, AlertsForJourney(alerts, referenced))
+}
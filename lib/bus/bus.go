@@ -122,6 +122,56 @@ func LabelledJourneyFrom(mvmt VehicleJourney, timeToStop int) LabelledJourney {
 	}
 }
 
+// ScanStopDistanceRows scans rows selected from the stop_distance table into StopDistance structs
+func ScanStopDistanceRows(rows *sql.Rows) ([]StopDistance, error) {
+	var distances []StopDistance
+	for rows.Next() {
+		var sd StopDistance
+		err := rows.Scan(&sd.RouteID, &sd.FromID, &sd.ToID, &sd.Distance, &sd.DirectionID)
+		if err != nil {
+			log.Printf("ScanStopDistanceRows: error whilst scanning row from DB into a struct: %s", err)
+			return nil, err
+		}
+		distances = append(distances, sd)
+	}
+	err := rows.Err()
+	if err != nil {
+		log.Printf("ScanStopDistanceRows: error whilst scanning rows from DB: %s\n", err)
+		return nil, err
+	}
+	return distances, nil
+}
+
+// ScanVehicleJourneyRows scans rows selected from the vehicle_journey table (including
+// the trailing entry_id column, which is discarded) into VehicleJourney structs
+func ScanVehicleJourneyRows(rows *sql.Rows) ([]VehicleJourney, error) {
+	// Don't need to store the entryID from each row, so just write it
+	// to this placeholder and ignore it
+	entryIDPtr := 0
+	var journeys []VehicleJourney
+	for rows.Next() {
+		journey := VehicleJourney{}
+		err := rows.Scan(
+			&journey.LineRef, &journey.DirectionRef, &journey.TripID, &journey.PublishedLineName, &journey.OperatorRef,
+			&journey.OriginRef, &journey.DestinationRef, &journey.OriginAimedDepartureTime, &journey.SituationRef,
+			&journey.Longitude, &journey.Latitude, &journey.ProgressRate, &journey.Occupancy, &journey.VehicleRef,
+			&journey.ExpectedArrivalTime, &journey.ExpectedDepartureTime, &journey.DistanceFromStop,
+			&journey.NumberOfStopsAway, &journey.StopPointRef, &journey.Timestamp, &entryIDPtr,
+		)
+		if err != nil {
+			log.Printf("ScanVehicleJourneyRows: error whilst scanning row from DB into a struct: %s", err)
+			return nil, err
+		}
+		journeys = append(journeys, journey)
+	}
+	err := rows.Err()
+	if err != nil {
+		log.Printf("ScanVehicleJourneyRows: error whilst scanning rows from DB: %s\n", err)
+		return nil, err
+	}
+	return journeys, nil
+}
+
 func ScanLabelledJourneyRows(rows *sql.Rows) ([]LabelledJourney, error) {
 	var journeys []LabelledJourney
 	for rows.Next() {
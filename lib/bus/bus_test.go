@@ -2,6 +2,11 @@ package bus
 
 import (
 	"testing"
+	"time"
+	"transport/lib/calendar"
+	"transport/lib/database"
+	"transport/lib/dates"
+	"transport/lib/nulltypes"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -27,3 +32,18 @@ func TestRemoveAgencyID(t *testing.T) {
 		assert.Equal(t, expectedOutput, actualOutput)
 	}
 }
+
+func TestStratifyByDayType(t *testing.T) {
+	cal := calendar.New(dates.DefaultServiceCalendar)
+	at := func(day int) LabelledJourney {
+		ts := database.Timestamp{Time: time.Date(2019, 6, day, 12, 0, 0, 0, database.TimeLoc)}
+		return LabelledJourney{Timestamp: nulltypes.TimestampFrom(ts)}
+	}
+	// 1st June 2019 is a Saturday
+	journeys := []LabelledJourney{at(1), at(2), at(3), at(4)}
+	stratified := StratifyByDayType(journeys, cal)
+	assert.Equal(t, []LabelledJourney{at(1)}, stratified[calendar.Saturday])
+	assert.Equal(t, []LabelledJourney{at(2)}, stratified[calendar.Sunday])
+	assert.Equal(t, []LabelledJourney{at(3), at(4)}, stratified[calendar.Weekday])
+	assert.Equal(t, []LabelledJourney{at(1), at(2)}, FilterByDayType(journeys, cal, calendar.Saturday, calendar.Sunday))
+}
@@ -0,0 +1,202 @@
+package bus
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"transport/lib/geo"
+)
+
+// RejectReason describes why a movement was dropped by a Cleaner. Each reason
+// corresponds to a rule that can be disabled using DisableRulesOption.
+type RejectReason string
+
+const (
+	// The vehicle would have had to travel faster than the maximum speed to reach the reported position
+	ReasonGPSJump RejectReason = "gps_jump"
+	// The movement is older than a movement already received from the same vehicle
+	ReasonOutOfOrder RejectReason = "out_of_order"
+	// The movement has the same timestamp as one already received from the same vehicle,
+	// which happens when a vehicle stops reporting and the feed repeats its last report
+	ReasonStale RejectReason = "stale"
+	// The movement's StopPointRef isn't one of the stops on its route and direction
+	ReasonStopNotOnRoute RejectReason = "stop_not_on_route"
+)
+
+// DefaultMaxSpeed is the fastest (in metres per second) a bus can plausibly travel
+// between two reports, around 110km/h
+const DefaultMaxSpeed = 30.0
+
+// maxConsecutiveJumps is the number of GPS jumps in a row after which the new position
+// is accepted, in case it was the earlier position that was wrong
+const maxConsecutiveJumps = 3
+
+// Rejection is a movement that was dropped by a Cleaner, along with the reason why
+type Rejection struct {
+	Movement VehicleJourney
+	Reason   RejectReason
+}
+
+// CleanResult holds the outcome of cleaning a set of movements
+type CleanResult struct {
+	Kept     []VehicleJourney
+	Rejected []Rejection
+}
+
+// Counts returns the number of movements rejected for each reason
+func (cr CleanResult) Counts() map[RejectReason]int {
+	counts := map[RejectReason]int{}
+	for _, r := range cr.Rejected {
+		counts[r.Reason]++
+	}
+	return counts
+}
+
+// String summarises the result, listing rejection reasons in alphabetical order
+func (cr CleanResult) String() string {
+	counts := cr.Counts()
+	var reasons []string
+	for reason, count := range counts {
+		reasons = append(reasons, fmt.Sprintf("%s: %d", reason, count))
+	}
+	sort.Strings(reasons)
+	return fmt.Sprintf("kept %d, rejected %d (%s)", len(cr.Kept), len(cr.Rejected), strings.Join(reasons, ", "))
+}
+
+// Cleaner removes invalid movements from raw vehicle data. Every rule is enabled by
+// default, except ReasonStopNotOnRoute, which is enabled by passing the stops on each
+// route using RouteStopsOption.
+type Cleaner struct {
+	maxSpeed   float64
+	disabled   map[RejectReason]bool
+	routeStops map[string]map[int]map[string]bool
+}
+
+// NewCleaner creates a Cleaner using the DefaultMaxSpeed
+// Example Usage:
+// cleaner := bus.NewCleaner(bus.DisableRulesOption(bus.ReasonGPSJump), bus.RouteStopsOption(routeStops))
+func NewCleaner(options ...func(*Cleaner)) *Cleaner {
+	c := &Cleaner{maxSpeed: DefaultMaxSpeed, disabled: map[RejectReason]bool{}}
+	for _, option := range options {
+		option(c)
+	}
+	return c
+}
+
+// MaxSpeedOption returns a function that can be passed to NewCleaner to change
+// the speed (in metres per second) above which movements are treated as GPS jumps
+func MaxSpeedOption(metresPerSecond float64) func(*Cleaner) {
+	return func(c *Cleaner) {
+		c.maxSpeed = metresPerSecond
+	}
+}
+
+// DisableRulesOption returns a function that can be passed to NewCleaner to turn
+// off the rules that reject movements for the given reasons
+func DisableRulesOption(reasons ...RejectReason) func(*Cleaner) {
+	return func(c *Cleaner) {
+		for _, reason := range reasons {
+			c.disabled[reason] = true
+		}
+	}
+}
+
+// RouteStopsOption returns a function that can be passed to NewCleaner to reject
+// movements whose StopPointRef isn't on their route. `routeStops` maps route IDs to
+// direction IDs to the IDs of the stops served. Routes that aren't in the map are
+// assumed to be valid.
+func RouteStopsOption(routeStops map[string]map[int][]string) func(*Cleaner) {
+	return func(c *Cleaner) {
+		c.routeStops = map[string]map[int]map[string]bool{}
+		for routeID, directions := range routeStops {
+			c.routeStops[routeID] = map[int]map[string]bool{}
+			for directionID, stops := range directions {
+				c.routeStops[routeID][directionID] = map[string]bool{}
+				for _, stopID := range stops {
+					c.routeStops[routeID][directionID][stopID] = true
+				}
+			}
+		}
+	}
+}
+
+// vehicleState is the most recent accepted movement (with a position) from a vehicle
+type vehicleState struct {
+	last  VehicleJourney
+	jumps int
+}
+
+// Clean checks every movement against the enabled rules. Movements are expected in the
+// order they were received, and the movements that are kept remain in that order.
+func (c *Cleaner) Clean(mvmts []VehicleJourney) CleanResult {
+	var result CleanResult
+	vehicles := map[string]*vehicleState{}
+	for _, mvmt := range mvmts {
+		state := vehicles[mvmt.VehicleRef.String]
+		reason, ok := c.check(mvmt, state)
+		if !ok {
+			result.Rejected = append(result.Rejected, Rejection{Movement: mvmt, Reason: reason})
+			continue
+		}
+		// Movements without a position can't be used to check the next movement for a jump
+		if hasPosition(mvmt) {
+			vehicles[mvmt.VehicleRef.String] = &vehicleState{last: mvmt}
+		}
+		result.Kept = append(result.Kept, mvmt)
+	}
+	return result
+}
+
+// check returns the reason the movement should be rejected, if any rule fails
+func (c *Cleaner) check(mvmt VehicleJourney, state *vehicleState) (RejectReason, bool) {
+	if c.enabled(ReasonStopNotOnRoute) && !c.stopOnRoute(mvmt) {
+		return ReasonStopNotOnRoute, false
+	}
+	if state == nil {
+		return "", true
+	}
+	elapsed := mvmt.Timestamp.Sub(state.last.Timestamp.Time).Seconds()
+	if c.enabled(ReasonStale) && elapsed == 0 {
+		return ReasonStale, false
+	}
+	if c.enabled(ReasonOutOfOrder) && elapsed < 0 {
+		return ReasonOutOfOrder, false
+	}
+	if c.enabled(ReasonGPSJump) && elapsed > 0 && hasPosition(mvmt) && state.jumps < maxConsecutiveJumps {
+		distance := geo.Haversine(position(state.last), position(mvmt))
+		if distance/elapsed > c.maxSpeed {
+			state.jumps++
+			return ReasonGPSJump, false
+		}
+	}
+	return "", true
+}
+
+func (c *Cleaner) enabled(reason RejectReason) bool {
+	if reason == ReasonStopNotOnRoute && c.routeStops == nil {
+		return false
+	}
+	return !c.disabled[reason]
+}
+
+func (c *Cleaner) stopOnRoute(mvmt VehicleJourney) bool {
+	directions, found := c.routeStops[mvmt.LineRef.String]
+	if !found {
+		return true
+	}
+	stops, found := directions[int(mvmt.DirectionRef.Int64)]
+	if !found {
+		return true
+	}
+	return stops[mvmt.StopPointRef.String]
+}
+
+// hasPosition returns true if the movement has both a latitude and a longitude. Movements
+// without them would otherwise be treated as being at (0, 0).
+func hasPosition(mvmt VehicleJourney) bool {
+	return mvmt.Latitude.Valid && mvmt.Longitude.Valid
+}
+
+func position(mvmt VehicleJourney) geo.Point {
+	return geo.Point{Latitude: mvmt.Latitude.Float64, Longitude: mvmt.Longitude.Float64}
+}
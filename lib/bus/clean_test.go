@@ -0,0 +1,71 @@
+package bus
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/guregu/null.v3"
+)
+
+// report creates a movement for vehicle "A", `seconds` after 8am, `metresNorth` of a fixed point
+func report(seconds int, metresNorth float64, stopID string) VehicleJourney {
+	return ExampleJourney(
+		VehicleRefOption("A"), LineRefOption("MTA NYCT_M55"), DirectionRefOption(0),
+		StopPointRefOption(stopID), PositionOption(40.7+metresNorth/111195, -73.99),
+		TimestampOption(time.Date(2019, 6, 3, 8, 0, seconds, 0, time.UTC)),
+	)
+}
+
+func TestCleaner_Clean(t *testing.T) {
+	mvmts := []VehicleJourney{
+		report(0, 0, "1"),
+		report(30, 200, "1"),
+		// Repeated report
+		report(30, 200, "1"),
+		// Arrived late from a previous poll
+		report(15, 100, "1"),
+		// 5km in 30 seconds
+		report(60, 5000, "1"),
+		report(60, 400, "2"),
+		// Not a stop on the route
+		report(90, 600, "99"),
+	}
+	cleaner := NewCleaner(RouteStopsOption(map[string]map[int][]string{"MTA NYCT_M55": {0: {"1", "2"}}}))
+	result := cleaner.Clean(mvmts)
+	assert.Equal(t, []VehicleJourney{mvmts[0], mvmts[1], mvmts[5]}, result.Kept)
+	var reasons []RejectReason
+	for _, r := range result.Rejected {
+		reasons = append(reasons, r.Reason)
+	}
+	assert.Equal(t, []RejectReason{ReasonStale, ReasonOutOfOrder, ReasonGPSJump, ReasonStopNotOnRoute}, reasons)
+	assert.Equal(t, "kept 3, rejected 4 (gps_jump: 1, out_of_order: 1, stale: 1, stop_not_on_route: 1)", result.String())
+}
+
+func TestCleaner_DisableRules(t *testing.T) {
+	mvmts := []VehicleJourney{report(0, 0, "1"), report(30, 200, "1"), report(30, 200, "1"), report(60, 5000, "99")}
+	result := NewCleaner(DisableRulesOption(ReasonStale, ReasonGPSJump)).Clean(mvmts)
+	// The stop rule is only enabled when route stops are provided
+	assert.Equal(t, mvmts, result.Kept)
+}
+
+func TestCleaner_MissingPosition(t *testing.T) {
+	missing := report(30, 0, "1")
+	missing.Latitude, missing.Longitude = null.Float{}, null.Float{}
+	mvmts := []VehicleJourney{report(0, 0, "1"), missing, report(60, 400, "1"), report(90, 5000, "1")}
+	result := NewCleaner().Clean(mvmts)
+	// The missing position is neither a jump itself, nor the baseline for the next movement
+	assert.Equal(t, mvmts[:3], result.Kept)
+	assert.Equal(t, []Rejection{{Movement: mvmts[3], Reason: ReasonGPSJump}}, result.Rejected)
+}
+
+func TestCleaner_AcceptsPositionAfterRepeatedJumps(t *testing.T) {
+	// The first report was in the wrong place, so every later report looks like a jump
+	mvmts := []VehicleJourney{report(0, 10000, "1")}
+	for i := 1; i <= 5; i++ {
+		mvmts = append(mvmts, report(i*30, float64(i*200), "1"))
+	}
+	result := NewCleaner().Clean(mvmts)
+	assert.Equal(t, maxConsecutiveJumps, result.Counts()[ReasonGPSJump])
+	assert.Equal(t, []VehicleJourney{mvmts[0], mvmts[4], mvmts[5]}, result.Kept)
+}
@@ -0,0 +1,30 @@
+package bus
+
+import "transport/lib/calendar"
+
+// StratifyByDayType splits labelled journeys up by the type of service day (weekday,
+// Saturday, holiday etc.) that each movement was recorded on
+func StratifyByDayType(journeys []LabelledJourney, cal *calendar.Calendar) map[calendar.DayType][]LabelledJourney {
+	result := map[calendar.DayType][]LabelledJourney{}
+	for _, journey := range journeys {
+		dayType := cal.ClassifyTime(journey.Timestamp.Time)
+		result[dayType] = append(result[dayType], journey)
+	}
+	return result
+}
+
+// FilterByDayType returns only the labelled journeys that were recorded on
+// a service day of one of the given types
+func FilterByDayType(journeys []LabelledJourney, cal *calendar.Calendar, dayTypes ...calendar.DayType) []LabelledJourney {
+	wanted := map[calendar.DayType]bool{}
+	for _, dayType := range dayTypes {
+		wanted[dayType] = true
+	}
+	var filtered []LabelledJourney
+	for _, journey := range journeys {
+		if wanted[cal.ClassifyTime(journey.Timestamp.Time)] {
+			filtered = append(filtered, journey)
+		}
+	}
+	return filtered
+}
@@ -70,3 +70,96 @@ var ExampleVJs = []VehicleJourney{
 		Timestamp:                nulltypes.TimestampFrom(database.Timestamp{Time: time.Date(2019, 3, 22, 5, 31, 31, 338000000, time.UTC)}),
 	},
 }
+
+// ExampleJourney creates a journey for use in tests. Every field is null unless it is set
+// by one of the options.
+// Example Usage:
+// vj := bus.ExampleJourney(bus.VehicleRefOption("A"), bus.LineRefOption("MTA NYCT_B41"), bus.TimestampOption(ts))
+func ExampleJourney(options ...func(*VehicleJourney)) VehicleJourney {
+	var vj VehicleJourney
+	for _, option := range options {
+		option(&vj)
+	}
+	return vj
+}
+
+// VehicleRefOption returns a function that can be passed to ExampleJourney to set the VehicleRef
+func VehicleRefOption(vehicleRef string) func(*VehicleJourney) {
+	return func(vj *VehicleJourney) {
+		vj.VehicleRef = null.StringFrom(vehicleRef)
+	}
+}
+
+// TimestampOption returns a function that can be passed to ExampleJourney to set when the journey was reported
+func TimestampOption(t time.Time) func(*VehicleJourney) {
+	return func(vj *VehicleJourney) {
+		vj.Timestamp = nulltypes.TimestampFrom(database.Timestamp{Time: t})
+	}
+}
+
+// LineRefOption returns a function that can be passed to ExampleJourney to set the route
+func LineRefOption(lineRef string) func(*VehicleJourney) {
+	return func(vj *VehicleJourney) {
+		vj.LineRef = null.StringFrom(lineRef)
+	}
+}
+
+// DirectionRefOption returns a function that can be passed to ExampleJourney to set the direction
+func DirectionRefOption(directionID int) func(*VehicleJourney) {
+	return func(vj *VehicleJourney) {
+		vj.DirectionRef = null.IntFrom(int64(directionID))
+	}
+}
+
+// TripIDOption returns a function that can be passed to ExampleJourney to set the trip
+func TripIDOption(tripID string) func(*VehicleJourney) {
+	return func(vj *VehicleJourney) {
+		vj.TripID = null.StringFrom(tripID)
+	}
+}
+
+// ProgressRateOption returns a function that can be passed to ExampleJourney to set the ProgressRate
+func ProgressRateOption(progressRate string) func(*VehicleJourney) {
+	return func(vj *VehicleJourney) {
+		vj.ProgressRate = null.StringFrom(progressRate)
+	}
+}
+
+// PositionOption returns a function that can be passed to ExampleJourney to set the vehicle's location
+func PositionOption(latitude float64, longitude float64) func(*VehicleJourney) {
+	return func(vj *VehicleJourney) {
+		vj.Latitude = null.FloatFrom(latitude)
+		vj.Longitude = null.FloatFrom(longitude)
+	}
+}
+
+// StopPointRefOption returns a function that can be passed to ExampleJourney to set the next stop
+func StopPointRefOption(stopID string) func(*VehicleJourney) {
+	return func(vj *VehicleJourney) {
+		vj.StopPointRef = null.StringFrom(stopID)
+	}
+}
+
+// DistanceFromStopOption returns a function that can be passed to ExampleJourney to set
+// how many metres the vehicle is from the next stop
+func DistanceFromStopOption(metres int64) func(*VehicleJourney) {
+	return func(vj *VehicleJourney) {
+		vj.DistanceFromStop = null.IntFrom(metres)
+	}
+}
+
+// NumberOfStopsAwayOption returns a function that can be passed to ExampleJourney to set
+// how many stops away the vehicle is from the monitored stop
+func NumberOfStopsAwayOption(stops int64) func(*VehicleJourney) {
+	return func(vj *VehicleJourney) {
+		vj.NumberOfStopsAway = null.IntFrom(stops)
+	}
+}
+
+// SituationRefOption returns a function that can be passed to ExampleJourney to set the
+// service alerts that affect the journey
+func SituationRefOption(situationRefs ...string) func(*VehicleJourney) {
+	return func(vj *VehicleJourney) {
+		vj.SituationRef = nulltypes.StringSliceFrom(situationRefs)
+	}
+}
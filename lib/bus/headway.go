@@ -0,0 +1,376 @@
+package bus
+
+import (
+	"log"
+	"sort"
+	"time"
+	"transport/lib/database"
+	"transport/lib/stats"
+)
+
+// HeadwayStatus describes how a headway compares to the expected headway on its route
+type HeadwayStatus string
+
+const (
+	HeadwayNormal HeadwayStatus = "normal"
+	// The vehicle arrived too soon after the one in front, so the two are travelling together
+	HeadwayBunched HeadwayStatus = "bunched"
+	// The vehicle arrived too long after the one in front, leaving passengers waiting
+	HeadwayGap HeadwayStatus = "gap"
+)
+
+const (
+	// DefaultBunchingFraction is the fraction of the expected headway below which vehicles are bunched
+	DefaultBunchingFraction = 0.25
+	// DefaultGapFactor is the multiple of the expected headway above which there is a gap in service
+	DefaultGapFactor = 2.0
+)
+
+// RouteDirection identifies a route travelling in one direction, regardless of vehicle
+type RouteDirection struct {
+	RouteID     string
+	DirectionID int
+}
+
+// Headway is the time between two consecutive vehicles on a route arriving at the same stop
+type Headway struct {
+	RouteID        string
+	DirectionID    int
+	StopID         string
+	VehicleRef     string
+	LeadVehicleRef string
+	Arrival        database.Timestamp
+	LeadArrival    database.Timestamp
+	HeadwaySeconds int
+	// The expected headway that HeadwaySeconds was compared against to determine the
+	// Status, 0 if there wasn't enough data to know the expected headway
+	ReferenceSeconds int
+	Status           HeadwayStatus
+}
+
+// HeadwayAnalyser computes headways from the times vehicles arrived at stops, and classifies
+// each one by comparing it to the expected headway on the route. The expected headway is the
+// scheduled headway if one is known, otherwise it is the median of the route's observed headways.
+type HeadwayAnalyser struct {
+	bunchingFraction  float64
+	gapFactor         float64
+	scheduledHeadways map[RouteDirection]time.Duration
+}
+
+// NewHeadwayAnalyser creates a HeadwayAnalyser using the DefaultBunchingFraction and DefaultGapFactor
+// Example Usage:
+// analyser := bus.NewHeadwayAnalyser(bus.BunchingFractionOption(0.2), bus.ScheduledHeadwaysOption(schedule))
+func NewHeadwayAnalyser(options ...func(*HeadwayAnalyser)) *HeadwayAnalyser {
+	a := &HeadwayAnalyser{
+		bunchingFraction:  DefaultBunchingFraction,
+		gapFactor:         DefaultGapFactor,
+		scheduledHeadways: map[RouteDirection]time.Duration{},
+	}
+	for _, option := range options {
+		option(a)
+	}
+	return a
+}
+
+// BunchingFractionOption returns a function that can be passed to NewHeadwayAnalyser to change
+// the fraction of the expected headway below which vehicles are classed as bunched
+func BunchingFractionOption(fraction float64) func(*HeadwayAnalyser) {
+	return func(a *HeadwayAnalyser) {
+		a.bunchingFraction = fraction
+	}
+}
+
+// GapFactorOption returns a function that can be passed to NewHeadwayAnalyser to change
+// the multiple of the expected headway above which a headway is classed as a gap
+func GapFactorOption(factor float64) func(*HeadwayAnalyser) {
+	return func(a *HeadwayAnalyser) {
+		a.gapFactor = factor
+	}
+}
+
+// ScheduledHeadwaysOption returns a function that can be passed to NewHeadwayAnalyser to compare
+// headways against the scheduled headway of each route, rather than the median observed headway
+func ScheduledHeadwaysOption(scheduled map[RouteDirection]time.Duration) func(*HeadwayAnalyser) {
+	return func(a *HeadwayAnalyser) {
+		for rd, headway := range scheduled {
+			a.scheduledHeadways[rd] = headway
+		}
+	}
+}
+
+// stopKey identifies a stop on a route travelling in one direction
+type stopKey struct {
+	route  RouteDirection
+	stopID string
+}
+
+// Headways returns the headway between every pair of consecutive arrivals at each stop,
+// ordered by route, direction, stop and then arrival time
+func (a *HeadwayAnalyser) Headways(events []StopEvent) []Headway {
+	byStop := map[stopKey][]StopEvent{}
+	for _, event := range events {
+		key := stopKey{RouteDirection{event.RouteID, event.DirectionID}, event.StopID}
+		byStop[key] = append(byStop[key], event)
+	}
+	var headways []Headway
+	for _, stopEvents := range byStop {
+		sort.SliceStable(stopEvents, func(i, j int) bool {
+			return stopEvents[i].Arrival.Before(stopEvents[j].Arrival.Time)
+		})
+		for i := 1; i < len(stopEvents); i++ {
+			lead, follower := stopEvents[i-1], stopEvents[i]
+			// The same vehicle can't be in front of itself, so the later event is a duplicate
+			if lead.VehicleRef == follower.VehicleRef {
+				continue
+			}
+			headways = append(headways, Headway{
+				RouteID:        follower.RouteID,
+				DirectionID:    follower.DirectionID,
+				StopID:         follower.StopID,
+				VehicleRef:     follower.VehicleRef,
+				LeadVehicleRef: lead.VehicleRef,
+				Arrival:        follower.Arrival,
+				LeadArrival:    lead.Arrival,
+				HeadwaySeconds: int(follower.Arrival.Sub(lead.Arrival.Time).Seconds()),
+			})
+		}
+	}
+	a.classify(headways)
+	sort.Slice(headways, func(i, j int) bool {
+		hi, hj := headways[i], headways[j]
+		if hi.RouteID != hj.RouteID {
+			return hi.RouteID < hj.RouteID
+		}
+		if hi.DirectionID != hj.DirectionID {
+			return hi.DirectionID < hj.DirectionID
+		}
+		if hi.StopID != hj.StopID {
+			return hi.StopID < hj.StopID
+		}
+		return hi.Arrival.Before(hj.Arrival.Time)
+	})
+	return headways
+}
+
+// classify sets the ReferenceSeconds and Status of each headway
+func (a *HeadwayAnalyser) classify(headways []Headway) {
+	references := a.references(headways)
+	for i := range headways {
+		reference := references[RouteDirection{headways[i].RouteID, headways[i].DirectionID}]
+		headways[i].ReferenceSeconds = int(reference)
+		headways[i].Status = a.Classify(float64(headways[i].HeadwaySeconds), reference)
+	}
+}
+
+// Classify returns the status of a headway, given the expected headway (both in seconds).
+// If the expected headway is unknown (0), every headway is normal.
+func (a *HeadwayAnalyser) Classify(headway float64, reference float64) HeadwayStatus {
+	switch {
+	case reference <= 0:
+		return HeadwayNormal
+	case headway < reference*a.bunchingFraction:
+		return HeadwayBunched
+	case headway > reference*a.gapFactor:
+		return HeadwayGap
+	default:
+		return HeadwayNormal
+	}
+}
+
+// minHeadwaysForMedian is the number of headways required on a route before
+// their median is used as the expected headway
+const minHeadwaysForMedian = 3
+
+// references returns the expected headway (in seconds) of each route in `headways`
+func (a *HeadwayAnalyser) references(headways []Headway) map[RouteDirection]float64 {
+	observed := map[RouteDirection][]float64{}
+	for _, h := range headways {
+		rd := RouteDirection{h.RouteID, h.DirectionID}
+		observed[rd] = append(observed[rd], float64(h.HeadwaySeconds))
+	}
+	references := map[RouteDirection]float64{}
+	for rd, values := range observed {
+		if scheduled, found := a.scheduledHeadways[rd]; found {
+			references[rd] = scheduled.Seconds()
+		} else if len(values) >= minHeadwaysForMedian {
+			references[rd] = stats.Median(values)
+		}
+	}
+	return references
+}
+
+// DefaultPassageWindow is how long a StopPassageTracker remembers arrivals for
+const DefaultPassageWindow = 2 * time.Hour
+
+// StopPassageTracker detects when vehicles arrive at stops from successive snapshots
+// of live data, so that headways can be computed while the vehicles are running.
+// Arrivals are only estimated, as vehicles report every 30 seconds or so: if a vehicle
+// goes past a stop between two reports, its arrival is interpolated using the distance
+// between the stops (see StopDistancesOption). Live events don't track when vehicles
+// depart, so their Departure is the same as their Arrival.
+type StopPassageTracker struct {
+	window        time.Duration
+	last          map[string]VehicleJourney
+	events        []StopEvent
+	stopDistances map[StopDistanceKey]float64
+}
+
+// StopDistanceKey identifies the distance from one stop to another along a route and direction
+type StopDistanceKey struct {
+	RouteID     string
+	DirectionID int
+	FromID      string
+	ToID        string
+}
+
+// NewStopPassageTracker creates a StopPassageTracker which remembers the arrivals
+// from the `window` before the most recent snapshot. Without any stop distances,
+// stops are assumed to be the AverageDistanceBetweenStops apart.
+// Example Usage:
+// tracker := bus.NewStopPassageTracker(bus.DefaultPassageWindow, bus.StopDistancesOption(distances))
+func NewStopPassageTracker(window time.Duration, options ...func(*StopPassageTracker)) *StopPassageTracker {
+	t := &StopPassageTracker{window: window, last: map[string]VehicleJourney{}, stopDistances: map[StopDistanceKey]float64{}}
+	for _, option := range options {
+		option(t)
+	}
+	return t
+}
+
+// StopDistancesOption returns a function that can be passed to NewStopPassageTracker so that
+// arrivals are interpolated using the distances between stops. The AverageDistanceBetweenStops
+// is still used for any pair of stops that isn't in `distances`.
+func StopDistancesOption(distances []StopDistance) func(*StopPassageTracker) {
+	return func(t *StopPassageTracker) {
+		for _, sd := range distances {
+			t.stopDistances[StopDistanceKey{sd.RouteID, sd.DirectionID, sd.FromID, sd.ToID}] = sd.Distance
+		}
+	}
+}
+
+// Observe compares a snapshot of every vehicle's position to the previous snapshot,
+// and returns the arrivals that happened in between
+func (t *StopPassageTracker) Observe(snapshot []VehicleJourney) []StopEvent {
+	var arrivals []StopEvent
+	var latest time.Time
+	for _, cur := range snapshot {
+		if cur.Timestamp.After(latest) {
+			latest = cur.Timestamp.Time
+		}
+		prev, found := t.last[cur.VehicleRef.String]
+		if found && !cur.Timestamp.After(prev.Timestamp.Time) {
+			// The vehicle hasn't reported since the last snapshot
+			continue
+		}
+		t.last[cur.VehicleRef.String] = cur
+		if found && samePassage(prev, cur) {
+			arrivals = append(arrivals, t.passagesBetween(prev, cur)...)
+		}
+	}
+	t.events = append(t.events, arrivals...)
+	t.expire(latest)
+	return arrivals
+}
+
+// Events returns every arrival that happened within the window, in the order they were observed
+func (t *StopPassageTracker) Events() []StopEvent {
+	events := make([]StopEvent, len(t.events))
+	copy(events, t.events)
+	return events
+}
+
+// expire forgets the arrivals and vehicles that are older than the window
+func (t *StopPassageTracker) expire(latest time.Time) {
+	cutoff := latest.Add(-t.window)
+	var kept []StopEvent
+	for _, event := range t.events {
+		if !event.Arrival.Before(cutoff) {
+			kept = append(kept, event)
+		}
+	}
+	t.events = kept
+	for vehicleRef, mvmt := range t.last {
+		if mvmt.Timestamp.Before(cutoff) {
+			delete(t.last, vehicleRef)
+		}
+	}
+}
+
+// samePassage returns true if both movements were sent on the same trip along the route
+func samePassage(prev VehicleJourney, cur VehicleJourney) bool {
+	return prev.LineRef == cur.LineRef && prev.DirectionRef == cur.DirectionRef && prev.TripID == cur.TripID
+}
+
+// passagesBetween returns the arrivals at stops between two consecutive movements from a vehicle
+func (t *StopPassageTracker) passagesBetween(prev VehicleJourney, cur VehicleJourney) []StopEvent {
+	var arrivals []StopEvent
+	prevAtStop := prev.DistanceFromStop.Valid && prev.DistanceFromStop.Int64 == 0
+	curAtStop := cur.DistanceFromStop.Valid && cur.DistanceFromStop.Int64 == 0
+	changedStop := prev.StopPointRef != cur.StopPointRef
+	if changedStop && !prevAtStop {
+		// Went past the previous stop without reporting from it
+		arrival := t.interpolatePassage(prev, cur)
+		arrivals = append(arrivals, passageEvent(prev, arrival, true))
+	}
+	if curAtStop && (changedStop || !prevAtStop) {
+		arrivals = append(arrivals, passageEvent(cur, cur.Timestamp.Time, false))
+	}
+	return arrivals
+}
+
+// interpolatePassage estimates when the vehicle reached prev's stop, assuming it travelled at a constant speed
+func (t *StopPassageTracker) interpolatePassage(prev VehicleJourney, cur VehicleJourney) time.Time {
+	distanceToStop := float64(prev.DistanceFromStop.Int64)
+	distancePastStop := t.distanceBetweenStops(prev, cur) - float64(cur.DistanceFromStop.Int64)
+	if distancePastStop < 0 {
+		distancePastStop = AverageDistanceBetweenStops * 0.1
+	}
+	fraction := distanceToStop / (distanceToStop + distancePastStop)
+	elapsed := cur.Timestamp.Sub(prev.Timestamp.Time)
+	return prev.Timestamp.Add(time.Duration(fraction * float64(elapsed)))
+}
+
+// distanceBetweenStops returns the distance along the route from prev's stop to cur's stop,
+// or the AverageDistanceBetweenStops if it isn't known
+func (t *StopPassageTracker) distanceBetweenStops(prev VehicleJourney, cur VehicleJourney) float64 {
+	key := StopDistanceKey{prev.LineRef.String, int(prev.DirectionRef.Int64), prev.StopPointRef.String, cur.StopPointRef.String}
+	if distance, found := t.stopDistances[key]; found {
+		return distance
+	}
+	return AverageDistanceBetweenStops
+}
+
+func passageEvent(mvmt VehicleJourney, arrival time.Time, interpolated bool) StopEvent {
+	ts := database.Timestamp{Time: arrival}
+	return StopEvent{
+		RouteID:      mvmt.LineRef.String,
+		DirectionID:  int(mvmt.DirectionRef.Int64),
+		TripID:       mvmt.TripID.String,
+		VehicleRef:   mvmt.VehicleRef.String,
+		StopID:       mvmt.StopPointRef.String,
+		Arrival:      ts,
+		Departure:    ts,
+		Interpolated: interpolated,
+	}
+}
+
+// HeadwayToInterface converts a slice of Headway structs into a slice of interface{}
+func HeadwayToInterface(headways []Headway) []interface{} {
+	r := make([]interface{}, len(headways))
+	for i, headway := range headways {
+		r[i] = headway
+	}
+	return r
+}
+
+// ExtractEntriesFromHeadway converts a single Headway struct into
+// a slice of interface{} which represents the database row
+func ExtractEntriesFromHeadway(hEntry interface{}) []interface{} {
+	h, ok := hEntry.(Headway)
+	if !ok {
+		log.Panicf("ExtractEntriesFromHeadway: entry passed in is not a Headway struct")
+	}
+	return []interface{}{
+		h.RouteID, h.DirectionID, h.StopID, h.VehicleRef, h.LeadVehicleRef,
+		h.Arrival.Time, h.LeadArrival.Time, h.HeadwaySeconds, h.ReferenceSeconds, string(h.Status),
+	}
+}
@@ -0,0 +1,95 @@
+package bus
+
+import (
+	"testing"
+	"time"
+	"transport/lib/database"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var headwayStart = time.Date(2019, 6, 3, 8, 0, 0, 0, time.UTC)
+
+// arrival creates a StopEvent for `vehicleRef` arriving at stop "1", `minutes` after 8am
+func arrival(vehicleRef string, minutes float64) StopEvent {
+	ts := database.Timestamp{Time: headwayStart.Add(time.Duration(minutes * float64(time.Minute)))}
+	return StopEvent{RouteID: "MTA NYCT_B41", StopID: "1", VehicleRef: vehicleRef, Arrival: ts, Departure: ts}
+}
+
+func TestHeadwayAnalyser_MedianReference(t *testing.T) {
+	events := []StopEvent{
+		arrival("E", 41), arrival("A", 0), arrival("B", 10), arrival("C", 20), arrival("D", 21),
+	}
+	headways := NewHeadwayAnalyser().Headways(events)
+	var statuses []HeadwayStatus
+	for _, h := range headways {
+		assert.Equal(t, 600, h.ReferenceSeconds)
+		statuses = append(statuses, h.Status)
+	}
+	// Headways of 10, 10, 1 and 20 minutes, with a median of 10
+	assert.Equal(t, []HeadwayStatus{HeadwayNormal, HeadwayNormal, HeadwayBunched, HeadwayNormal}, statuses)
+	assert.Equal(t, "C", headways[2].LeadVehicleRef)
+	assert.Equal(t, "D", headways[2].VehicleRef)
+	assert.Equal(t, 60, headways[2].HeadwaySeconds)
+}
+
+func TestHeadwayAnalyser_ScheduledReference(t *testing.T) {
+	schedule := map[RouteDirection]time.Duration{{RouteID: "MTA NYCT_B41"}: 5 * time.Minute}
+	headways := NewHeadwayAnalyser(ScheduledHeadwaysOption(schedule)).Headways([]StopEvent{
+		arrival("A", 0), arrival("B", 12),
+	})
+	assert.Len(t, headways, 1)
+	assert.Equal(t, 300, headways[0].ReferenceSeconds)
+	assert.Equal(t, HeadwayGap, headways[0].Status)
+}
+
+func TestHeadwayAnalyser_NoReference(t *testing.T) {
+	headways := NewHeadwayAnalyser().Headways([]StopEvent{arrival("A", 0), arrival("B", 1), arrival("B", 1.5)})
+	// The duplicate arrival from B is ignored, and one headway isn't enough to know what to expect
+	assert.Len(t, headways, 1)
+	assert.Equal(t, 0, headways[0].ReferenceSeconds)
+	assert.Equal(t, HeadwayNormal, headways[0].Status)
+}
+
+func livePosition(seconds int, stopID string, distance int64) VehicleJourney {
+	return ExampleJourney(
+		VehicleRefOption("A"), LineRefOption("MTA NYCT_B41"), DirectionRefOption(0), TripIDOption("T1"),
+		StopPointRefOption(stopID), DistanceFromStopOption(distance),
+		TimestampOption(headwayStart.Add(time.Duration(seconds)*time.Second)),
+	)
+}
+
+func TestStopPassageTracker_Observe(t *testing.T) {
+	tracker := NewStopPassageTracker(DefaultPassageWindow)
+	assert.Empty(t, tracker.Observe([]VehicleJourney{livePosition(0, "1", 100)}))
+
+	// Reports from the stop itself
+	arrivals := tracker.Observe([]VehicleJourney{livePosition(30, "1", 0)})
+	assert.Len(t, arrivals, 1)
+	assert.False(t, arrivals[0].Interpolated)
+	assert.Equal(t, headwayStart.Add(30*time.Second), arrivals[0].Arrival.Time)
+
+	// Still at the stop, and then a repeat of the same report
+	assert.Empty(t, tracker.Observe([]VehicleJourney{livePosition(60, "1", 0)}))
+	assert.Empty(t, tracker.Observe([]VehicleJourney{livePosition(60, "1", 0)}))
+
+	// Goes past stop 2 without reporting from it: 100m before and 117m after, so around half way
+	assert.Empty(t, tracker.Observe([]VehicleJourney{livePosition(90, "2", 100)}))
+	arrivals = tracker.Observe([]VehicleJourney{livePosition(120, "3", 200)})
+	assert.Len(t, arrivals, 1)
+	assert.Equal(t, "2", arrivals[0].StopID)
+	assert.True(t, arrivals[0].Interpolated)
+	assert.WithinDuration(t, headwayStart.Add(104*time.Second), arrivals[0].Arrival.Time, time.Second)
+
+	assert.Len(t, tracker.Events(), 2)
+}
+
+func TestStopPassageTracker_StopDistances(t *testing.T) {
+	// Stops 2 and 3 are 1100m apart, so the vehicle was 100m from stop 2 and 900m past it
+	distances := []StopDistance{{RouteID: "MTA NYCT_B41", DirectionID: 0, FromID: "2", ToID: "3", Distance: 1100}}
+	tracker := NewStopPassageTracker(DefaultPassageWindow, StopDistancesOption(distances))
+	assert.Empty(t, tracker.Observe([]VehicleJourney{livePosition(0, "2", 100)}))
+	arrivals := tracker.Observe([]VehicleJourney{livePosition(100, "3", 200)})
+	assert.Len(t, arrivals, 1)
+	assert.WithinDuration(t, headwayStart.Add(10*time.Second), arrivals[0].Arrival.Time, time.Second)
+}
@@ -0,0 +1,66 @@
+package bus
+
+import (
+	"database/sql"
+	"log"
+	"transport/lib/database"
+)
+
+// StopEvent records a single visit of a vehicle to a stop on one of its trips
+type StopEvent struct {
+	RouteID      string
+	DirectionID  int
+	TripID       string
+	VehicleRef   string
+	StopID       string
+	Arrival      database.Timestamp
+	Departure    database.Timestamp
+	DwellSeconds int
+	// True if the vehicle didn't report from the stop itself, so the arrival
+	// time was estimated from the reports before and after it
+	Interpolated bool
+}
+
+// StopEventToInterface converts a slice of StopEvent structs into a slice of interface{}
+func StopEventToInterface(events []StopEvent) []interface{} {
+	r := make([]interface{}, len(events))
+	for i, event := range events {
+		r[i] = event
+	}
+	return r
+}
+
+// ExtractEntriesFromStopEvent converts a single StopEvent struct into
+// a slice of interface{} which represents the database row
+func ExtractEntriesFromStopEvent(seEntry interface{}) []interface{} {
+	se, ok := seEntry.(StopEvent)
+	if !ok {
+		log.Panicf("ExtractEntriesFromStopEvent: entry passed in is not a StopEvent struct")
+	}
+	return []interface{}{
+		se.RouteID, se.DirectionID, se.TripID, se.VehicleRef, se.StopID,
+		se.Arrival.Time, se.Departure.Time, se.DwellSeconds, se.Interpolated,
+	}
+}
+
+// ScanStopEventRows scans rows selected from the stop_event table into StopEvent structs
+func ScanStopEventRows(rows *sql.Rows) ([]StopEvent, error) {
+	var events []StopEvent
+	for rows.Next() {
+		var event StopEvent
+		err := rows.Scan(
+			&event.RouteID, &event.DirectionID, &event.TripID, &event.VehicleRef, &event.StopID,
+			&event.Arrival.Time, &event.Departure.Time, &event.DwellSeconds, &event.Interpolated,
+		)
+		if err != nil {
+			log.Printf("ScanStopEventRows: error whilst scanning row from DB into a struct: %s", err)
+			return nil, err
+		}
+		events = append(events, event)
+	}
+	if err := rows.Err(); err != nil {
+		log.Printf("ScanStopEventRows: error whilst scanning rows from DB: %s\n", err)
+		return nil, err
+	}
+	return events, nil
+}
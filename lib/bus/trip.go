@@ -0,0 +1,173 @@
+package bus
+
+import (
+	"sort"
+	"time"
+)
+
+// DefaultMaxTripGap is the longest a vehicle can go without reporting before
+// its movements are treated as belonging to separate trips
+const DefaultMaxTripGap = 10 * time.Minute
+
+// LayoverProgressRate is the ProgressRate of a vehicle waiting at a terminal between trips
+const LayoverProgressRate = "layover"
+
+// Trip is a single run of a vehicle along a route in one direction
+type Trip struct {
+	Route     DirectedRoute
+	TripID    string
+	Movements []VehicleJourney
+}
+
+// Start returns the timestamp of the first movement in the trip
+func (t Trip) Start() time.Time {
+	return t.Movements[0].Timestamp.Time
+}
+
+// End returns the timestamp of the last movement in the trip
+func (t Trip) End() time.Time {
+	return t.Movements[len(t.Movements)-1].Timestamp.Time
+}
+
+// LabelledTrip is a single run of a vehicle along a route in one direction,
+// made up of labelled movements. Labelled movements don't store a trip ID,
+// so these trips are only split on the other boundaries.
+type LabelledTrip struct {
+	Route     DirectedRoute
+	Movements []LabelledJourney
+}
+
+// Segmenter splits the stream of movements sent by each vehicle into trips.
+// A new trip starts whenever the vehicle's trip ID, route or direction changes,
+// after it reports a layover, or if it stops reporting for longer than the maximum gap.
+type Segmenter struct {
+	maxGap         time.Duration
+	splitOnLayover bool
+}
+
+// NewSegmenter creates a Segmenter using the default maximum gap, which splits on layovers
+// Example Usage:
+// trips := bus.NewSegmenter(bus.MaxGapOption(5 * time.Minute)).Trips(movements)
+func NewSegmenter(options ...func(*Segmenter)) *Segmenter {
+	s := &Segmenter{maxGap: DefaultMaxTripGap, splitOnLayover: true}
+	for _, option := range options {
+		option(s)
+	}
+	return s
+}
+
+// MaxGapOption returns a function that can be passed to NewSegmenter to change
+// how long a vehicle can go without reporting before a new trip is started
+func MaxGapOption(maxGap time.Duration) func(*Segmenter) {
+	return func(s *Segmenter) {
+		s.maxGap = maxGap
+	}
+}
+
+// KeepLayoversOption returns a function that can be passed to NewSegmenter so that
+// layover movements are kept as part of the surrounding trip, rather than splitting it
+func KeepLayoversOption() func(*Segmenter) {
+	return func(s *Segmenter) {
+		s.splitOnLayover = false
+	}
+}
+
+// tripKey holds the fields of a movement used to detect trip boundaries
+type tripKey struct {
+	route     DirectedRoute
+	tripID    string
+	layover   bool
+	timestamp time.Time
+}
+
+// Trips splits the movements into trips, ordered by vehicle and then start time.
+// Layover movements are dropped, as they aren't part of either trip.
+func (s *Segmenter) Trips(mvmts []VehicleJourney) []Trip {
+	keys := make([]tripKey, len(mvmts))
+	for i, mvmt := range mvmts {
+		keys[i] = tripKey{
+			route:     DirectedRoute{mvmt.LineRef.String, int(mvmt.DirectionRef.Int64), mvmt.VehicleRef.String},
+			tripID:    mvmt.TripID.String,
+			layover:   mvmt.ProgressRate.String == LayoverProgressRate,
+			timestamp: mvmt.Timestamp.Time,
+		}
+	}
+	var trips []Trip
+	for _, indexes := range s.segment(keys) {
+		trip := Trip{Route: keys[indexes[0]].route, TripID: keys[indexes[0]].tripID}
+		for _, i := range indexes {
+			trip.Movements = append(trip.Movements, mvmts[i])
+		}
+		trips = append(trips, trip)
+	}
+	return trips
+}
+
+// LabelledTrips splits the labelled movements into trips, ordered by vehicle and then start time
+func (s *Segmenter) LabelledTrips(mvmts []LabelledJourney) []LabelledTrip {
+	keys := make([]tripKey, len(mvmts))
+	for i, mvmt := range mvmts {
+		keys[i] = tripKey{
+			route:     DirectedRoute{mvmt.LineRef.String, int(mvmt.DirectionRef.Int64), mvmt.VehicleRef.String},
+			layover:   mvmt.ProgressRate.String == LayoverProgressRate,
+			timestamp: mvmt.Timestamp.Time,
+		}
+	}
+	var trips []LabelledTrip
+	for _, indexes := range s.segment(keys) {
+		trip := LabelledTrip{Route: keys[indexes[0]].route}
+		for _, i := range indexes {
+			trip.Movements = append(trip.Movements, mvmts[i])
+		}
+		trips = append(trips, trip)
+	}
+	return trips
+}
+
+// segment groups the indexes of the keys into trips
+func (s *Segmenter) segment(keys []tripKey) [][]int {
+	// Order every vehicle's movements by time
+	order := make([]int, len(keys))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		ka, kb := keys[order[a]], keys[order[b]]
+		if ka.route.VehicleRef != kb.route.VehicleRef {
+			return ka.route.VehicleRef < kb.route.VehicleRef
+		}
+		return ka.timestamp.Before(kb.timestamp)
+	})
+	var trips [][]int
+	var current []int
+	for _, i := range order {
+		key := keys[i]
+		if s.splitOnLayover && key.layover {
+			// Layovers end the current trip, and aren't part of the next one
+			if len(current) > 0 {
+				trips, current = append(trips, current), nil
+			}
+			continue
+		}
+		if len(current) > 0 && s.isBoundary(keys[current[len(current)-1]], key) {
+			trips, current = append(trips, current), nil
+		}
+		current = append(current, i)
+	}
+	if len(current) > 0 {
+		trips = append(trips, current)
+	}
+	return trips
+}
+
+// isBoundary returns true if `next` can't be part of the same trip as `prev`
+func (s *Segmenter) isBoundary(prev tripKey, next tripKey) bool {
+	return prev.route != next.route ||
+		(prev.tripID != "" && next.tripID != "" && prev.tripID != next.tripID) ||
+		next.timestamp.Sub(prev.timestamp) > s.maxGap
+}
+
+// SegmentTrips splits movements into trips using the default Segmenter
+func SegmentTrips(mvmts []VehicleJourney) []Trip {
+	return NewSegmenter().Trips(mvmts)
+}
@@ -0,0 +1,69 @@
+package bus
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func movementAt(vehicleRef string, direction int, tripID string, progressRate string, minute int) VehicleJourney {
+	return ExampleJourney(
+		VehicleRefOption(vehicleRef), LineRefOption("MTA NYCT_M55"), DirectionRefOption(direction),
+		TripIDOption(tripID), ProgressRateOption(progressRate),
+		TimestampOption(time.Date(2019, 6, 3, 8, minute, 0, 0, time.UTC)),
+	)
+}
+
+func TestSegmentTrips(t *testing.T) {
+	mvmts := []VehicleJourney{
+		// Out of order, to check movements are sorted by time
+		movementAt("A", 0, "T1", "normalProgress", 2),
+		movementAt("A", 0, "T1", "normalProgress", 1),
+		// Layover at the terminal, then back in the other direction
+		movementAt("A", 0, "T1", "layover", 3),
+		movementAt("A", 1, "T2", "normalProgress", 4),
+		movementAt("A", 1, "T2", "normalProgress", 5),
+		// Trip ID changes without a layover
+		movementAt("A", 1, "T3", "normalProgress", 6),
+		// Long gap in reporting on the same trip
+		movementAt("A", 1, "T3", "normalProgress", 30),
+		// A different vehicle
+		movementAt("B", 0, "T9", "normalProgress", 1),
+	}
+	trips := SegmentTrips(mvmts)
+	var summary [][]int
+	for _, trip := range trips {
+		var minutes []int
+		for _, mvmt := range trip.Movements {
+			minutes = append(minutes, mvmt.Timestamp.Minute())
+		}
+		summary = append(summary, minutes)
+	}
+	assert.Equal(t, [][]int{{1, 2}, {4, 5}, {6}, {30}, {1}}, summary)
+	assert.Equal(t, DirectedRoute{"MTA NYCT_M55", 1, "A"}, trips[1].Route)
+	assert.Equal(t, "T2", trips[1].TripID)
+	assert.Equal(t, 4, trips[1].Start().Minute())
+	assert.Equal(t, 5, trips[1].End().Minute())
+}
+
+func TestSegmenter_Options(t *testing.T) {
+	mvmts := []VehicleJourney{
+		movementAt("A", 0, "T1", "normalProgress", 1),
+		movementAt("A", 0, "T1", "layover", 3),
+		movementAt("A", 0, "T1", "normalProgress", 8),
+	}
+	assert.Len(t, NewSegmenter().Trips(mvmts), 2)
+	assert.Len(t, NewSegmenter(KeepLayoversOption()).Trips(mvmts), 1)
+	assert.Len(t, NewSegmenter(KeepLayoversOption(), MaxGapOption(4*time.Minute)).Trips(mvmts), 2)
+}
+
+func TestSegmenter_LabelledTrips(t *testing.T) {
+	var mvmts []LabelledJourney
+	for _, minute := range []int{1, 2, 20, 21} {
+		mvmts = append(mvmts, LabelledJourneyFrom(movementAt("A", 0, "T1", "normalProgress", minute), 0))
+	}
+	trips := NewSegmenter().LabelledTrips(mvmts)
+	assert.Len(t, trips, 2)
+	assert.Equal(t, mvmts[2:], trips[1].Movements)
+}
@@ -0,0 +1,774 @@
+// Schema for the vehicle data passed between livedataloader, detector and the predictor.
+// Regenerate bus.pb.go after editing this file, from the lib directory:
+// protoc --go_out=. --go_opt=paths=source_relative busproto/bus.proto
+
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.12
+// 	protoc        (unknown)
+// source: busproto/bus.proto
+
+package busproto
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+// A single movement report from a vehicle, see bus.VehicleJourney.
+// Fields that the MTA didn't provide are left unset.
+type VehicleJourney struct {
+	state                    protoimpl.MessageState `protogen:"open.v1"`
+	LineRef                  *string                `protobuf:"bytes,1,opt,name=line_ref,json=lineRef,proto3,oneof" json:"line_ref,omitempty"`
+	DirectionRef             *int64                 `protobuf:"varint,2,opt,name=direction_ref,json=directionRef,proto3,oneof" json:"direction_ref,omitempty"`
+	TripId                   *string                `protobuf:"bytes,3,opt,name=trip_id,json=tripId,proto3,oneof" json:"trip_id,omitempty"`
+	PublishedLineName        *string                `protobuf:"bytes,4,opt,name=published_line_name,json=publishedLineName,proto3,oneof" json:"published_line_name,omitempty"`
+	OperatorRef              *string                `protobuf:"bytes,5,opt,name=operator_ref,json=operatorRef,proto3,oneof" json:"operator_ref,omitempty"`
+	OriginRef                *string                `protobuf:"bytes,6,opt,name=origin_ref,json=originRef,proto3,oneof" json:"origin_ref,omitempty"`
+	DestinationRef           *string                `protobuf:"bytes,7,opt,name=destination_ref,json=destinationRef,proto3,oneof" json:"destination_ref,omitempty"`
+	OriginAimedDepartureTime *timestamppb.Timestamp `protobuf:"bytes,8,opt,name=origin_aimed_departure_time,json=originAimedDepartureTime,proto3" json:"origin_aimed_departure_time,omitempty"`
+	SituationRef             []string               `protobuf:"bytes,9,rep,name=situation_ref,json=situationRef,proto3" json:"situation_ref,omitempty"`
+	Longitude                *float64               `protobuf:"fixed64,10,opt,name=longitude,proto3,oneof" json:"longitude,omitempty"`
+	Latitude                 *float64               `protobuf:"fixed64,11,opt,name=latitude,proto3,oneof" json:"latitude,omitempty"`
+	ProgressRate             *string                `protobuf:"bytes,12,opt,name=progress_rate,json=progressRate,proto3,oneof" json:"progress_rate,omitempty"`
+	Occupancy                *string                `protobuf:"bytes,13,opt,name=occupancy,proto3,oneof" json:"occupancy,omitempty"`
+	VehicleRef               *string                `protobuf:"bytes,14,opt,name=vehicle_ref,json=vehicleRef,proto3,oneof" json:"vehicle_ref,omitempty"`
+	ExpectedArrivalTime      *timestamppb.Timestamp `protobuf:"bytes,15,opt,name=expected_arrival_time,json=expectedArrivalTime,proto3" json:"expected_arrival_time,omitempty"`
+	ExpectedDepartureTime    *timestamppb.Timestamp `protobuf:"bytes,16,opt,name=expected_departure_time,json=expectedDepartureTime,proto3" json:"expected_departure_time,omitempty"`
+	DistanceFromStop         *int64                 `protobuf:"varint,17,opt,name=distance_from_stop,json=distanceFromStop,proto3,oneof" json:"distance_from_stop,omitempty"`
+	NumberOfStopsAway        *int64                 `protobuf:"varint,18,opt,name=number_of_stops_away,json=numberOfStopsAway,proto3,oneof" json:"number_of_stops_away,omitempty"`
+	StopPointRef             *string                `protobuf:"bytes,19,opt,name=stop_point_ref,json=stopPointRef,proto3,oneof" json:"stop_point_ref,omitempty"`
+	Timestamp                *timestamppb.Timestamp `protobuf:"bytes,20,opt,name=timestamp,proto3" json:"timestamp,omitempty"`
+	unknownFields            protoimpl.UnknownFields
+	sizeCache                protoimpl.SizeCache
+}
+
+func (x *VehicleJourney) Reset() {
+	*x = VehicleJourney{}
+	mi := &file_busproto_bus_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *VehicleJourney) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*VehicleJourney) ProtoMessage() {}
+
+func (x *VehicleJourney) ProtoReflect() protoreflect.Message {
+	mi := &file_busproto_bus_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use VehicleJourney.ProtoReflect.Descriptor instead.
+func (*VehicleJourney) Descriptor() ([]byte, []int) {
+	return file_busproto_bus_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *VehicleJourney) GetLineRef() string {
+	if x != nil && x.LineRef != nil {
+		return *x.LineRef
+	}
+	return ""
+}
+
+func (x *VehicleJourney) GetDirectionRef() int64 {
+	if x != nil && x.DirectionRef != nil {
+		return *x.DirectionRef
+	}
+	return 0
+}
+
+func (x *VehicleJourney) GetTripId() string {
+	if x != nil && x.TripId != nil {
+		return *x.TripId
+	}
+	return ""
+}
+
+func (x *VehicleJourney) GetPublishedLineName() string {
+	if x != nil && x.PublishedLineName != nil {
+		return *x.PublishedLineName
+	}
+	return ""
+}
+
+func (x *VehicleJourney) GetOperatorRef() string {
+	if x != nil && x.OperatorRef != nil {
+		return *x.OperatorRef
+	}
+	return ""
+}
+
+func (x *VehicleJourney) GetOriginRef() string {
+	if x != nil && x.OriginRef != nil {
+		return *x.OriginRef
+	}
+	return ""
+}
+
+func (x *VehicleJourney) GetDestinationRef() string {
+	if x != nil && x.DestinationRef != nil {
+		return *x.DestinationRef
+	}
+	return ""
+}
+
+func (x *VehicleJourney) GetOriginAimedDepartureTime() *timestamppb.Timestamp {
+	if x != nil {
+		return x.OriginAimedDepartureTime
+	}
+	return nil
+}
+
+func (x *VehicleJourney) GetSituationRef() []string {
+	if x != nil {
+		return x.SituationRef
+	}
+	return nil
+}
+
+func (x *VehicleJourney) GetLongitude() float64 {
+	if x != nil && x.Longitude != nil {
+		return *x.Longitude
+	}
+	return 0
+}
+
+func (x *VehicleJourney) GetLatitude() float64 {
+	if x != nil && x.Latitude != nil {
+		return *x.Latitude
+	}
+	return 0
+}
+
+func (x *VehicleJourney) GetProgressRate() string {
+	if x != nil && x.ProgressRate != nil {
+		return *x.ProgressRate
+	}
+	return ""
+}
+
+func (x *VehicleJourney) GetOccupancy() string {
+	if x != nil && x.Occupancy != nil {
+		return *x.Occupancy
+	}
+	return ""
+}
+
+func (x *VehicleJourney) GetVehicleRef() string {
+	if x != nil && x.VehicleRef != nil {
+		return *x.VehicleRef
+	}
+	return ""
+}
+
+func (x *VehicleJourney) GetExpectedArrivalTime() *timestamppb.Timestamp {
+	if x != nil {
+		return x.ExpectedArrivalTime
+	}
+	return nil
+}
+
+func (x *VehicleJourney) GetExpectedDepartureTime() *timestamppb.Timestamp {
+	if x != nil {
+		return x.ExpectedDepartureTime
+	}
+	return nil
+}
+
+func (x *VehicleJourney) GetDistanceFromStop() int64 {
+	if x != nil && x.DistanceFromStop != nil {
+		return *x.DistanceFromStop
+	}
+	return 0
+}
+
+func (x *VehicleJourney) GetNumberOfStopsAway() int64 {
+	if x != nil && x.NumberOfStopsAway != nil {
+		return *x.NumberOfStopsAway
+	}
+	return 0
+}
+
+func (x *VehicleJourney) GetStopPointRef() string {
+	if x != nil && x.StopPointRef != nil {
+		return *x.StopPointRef
+	}
+	return ""
+}
+
+func (x *VehicleJourney) GetTimestamp() *timestamppb.Timestamp {
+	if x != nil {
+		return x.Timestamp
+	}
+	return nil
+}
+
+type VehicleJourneyList struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Journeys      []*VehicleJourney      `protobuf:"bytes,1,rep,name=journeys,proto3" json:"journeys,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *VehicleJourneyList) Reset() {
+	*x = VehicleJourneyList{}
+	mi := &file_busproto_bus_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *VehicleJourneyList) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*VehicleJourneyList) ProtoMessage() {}
+
+func (x *VehicleJourneyList) ProtoReflect() protoreflect.Message {
+	mi := &file_busproto_bus_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use VehicleJourneyList.ProtoReflect.Descriptor instead.
+func (*VehicleJourneyList) Descriptor() ([]byte, []int) {
+	return file_busproto_bus_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *VehicleJourneyList) GetJourneys() []*VehicleJourney {
+	if x != nil {
+		return x.Journeys
+	}
+	return nil
+}
+
+// A movement labelled with the number of seconds the vehicle took to reach
+// its next stop, see bus.LabelledJourney
+type LabelledJourney struct {
+	state                 protoimpl.MessageState `protogen:"open.v1"`
+	LineRef               *string                `protobuf:"bytes,1,opt,name=line_ref,json=lineRef,proto3,oneof" json:"line_ref,omitempty"`
+	DirectionRef          *int64                 `protobuf:"varint,2,opt,name=direction_ref,json=directionRef,proto3,oneof" json:"direction_ref,omitempty"`
+	OperatorRef           *string                `protobuf:"bytes,3,opt,name=operator_ref,json=operatorRef,proto3,oneof" json:"operator_ref,omitempty"`
+	OriginRef             *string                `protobuf:"bytes,4,opt,name=origin_ref,json=originRef,proto3,oneof" json:"origin_ref,omitempty"`
+	DestinationRef        *string                `protobuf:"bytes,5,opt,name=destination_ref,json=destinationRef,proto3,oneof" json:"destination_ref,omitempty"`
+	Longitude             *float64               `protobuf:"fixed64,6,opt,name=longitude,proto3,oneof" json:"longitude,omitempty"`
+	Latitude              *float64               `protobuf:"fixed64,7,opt,name=latitude,proto3,oneof" json:"latitude,omitempty"`
+	ProgressRate          *string                `protobuf:"bytes,8,opt,name=progress_rate,json=progressRate,proto3,oneof" json:"progress_rate,omitempty"`
+	Occupancy             *string                `protobuf:"bytes,9,opt,name=occupancy,proto3,oneof" json:"occupancy,omitempty"`
+	VehicleRef            *string                `protobuf:"bytes,10,opt,name=vehicle_ref,json=vehicleRef,proto3,oneof" json:"vehicle_ref,omitempty"`
+	ExpectedArrivalTime   *timestamppb.Timestamp `protobuf:"bytes,11,opt,name=expected_arrival_time,json=expectedArrivalTime,proto3" json:"expected_arrival_time,omitempty"`
+	ExpectedDepartureTime *timestamppb.Timestamp `protobuf:"bytes,12,opt,name=expected_departure_time,json=expectedDepartureTime,proto3" json:"expected_departure_time,omitempty"`
+	DistanceFromStop      *int64                 `protobuf:"varint,13,opt,name=distance_from_stop,json=distanceFromStop,proto3,oneof" json:"distance_from_stop,omitempty"`
+	NumberOfStopsAway     *int64                 `protobuf:"varint,14,opt,name=number_of_stops_away,json=numberOfStopsAway,proto3,oneof" json:"number_of_stops_away,omitempty"`
+	StopPointRef          *string                `protobuf:"bytes,15,opt,name=stop_point_ref,json=stopPointRef,proto3,oneof" json:"stop_point_ref,omitempty"`
+	Timestamp             *timestamppb.Timestamp `protobuf:"bytes,16,opt,name=timestamp,proto3" json:"timestamp,omitempty"`
+	TimeToStop            *int64                 `protobuf:"varint,17,opt,name=time_to_stop,json=timeToStop,proto3,oneof" json:"time_to_stop,omitempty"`
+	unknownFields         protoimpl.UnknownFields
+	sizeCache             protoimpl.SizeCache
+}
+
+func (x *LabelledJourney) Reset() {
+	*x = LabelledJourney{}
+	mi := &file_busproto_bus_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *LabelledJourney) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*LabelledJourney) ProtoMessage() {}
+
+func (x *LabelledJourney) ProtoReflect() protoreflect.Message {
+	mi := &file_busproto_bus_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use LabelledJourney.ProtoReflect.Descriptor instead.
+func (*LabelledJourney) Descriptor() ([]byte, []int) {
+	return file_busproto_bus_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *LabelledJourney) GetLineRef() string {
+	if x != nil && x.LineRef != nil {
+		return *x.LineRef
+	}
+	return ""
+}
+
+func (x *LabelledJourney) GetDirectionRef() int64 {
+	if x != nil && x.DirectionRef != nil {
+		return *x.DirectionRef
+	}
+	return 0
+}
+
+func (x *LabelledJourney) GetOperatorRef() string {
+	if x != nil && x.OperatorRef != nil {
+		return *x.OperatorRef
+	}
+	return ""
+}
+
+func (x *LabelledJourney) GetOriginRef() string {
+	if x != nil && x.OriginRef != nil {
+		return *x.OriginRef
+	}
+	return ""
+}
+
+func (x *LabelledJourney) GetDestinationRef() string {
+	if x != nil && x.DestinationRef != nil {
+		return *x.DestinationRef
+	}
+	return ""
+}
+
+func (x *LabelledJourney) GetLongitude() float64 {
+	if x != nil && x.Longitude != nil {
+		return *x.Longitude
+	}
+	return 0
+}
+
+func (x *LabelledJourney) GetLatitude() float64 {
+	if x != nil && x.Latitude != nil {
+		return *x.Latitude
+	}
+	return 0
+}
+
+func (x *LabelledJourney) GetProgressRate() string {
+	if x != nil && x.ProgressRate != nil {
+		return *x.ProgressRate
+	}
+	return ""
+}
+
+func (x *LabelledJourney) GetOccupancy() string {
+	if x != nil && x.Occupancy != nil {
+		return *x.Occupancy
+	}
+	return ""
+}
+
+func (x *LabelledJourney) GetVehicleRef() string {
+	if x != nil && x.VehicleRef != nil {
+		return *x.VehicleRef
+	}
+	return ""
+}
+
+func (x *LabelledJourney) GetExpectedArrivalTime() *timestamppb.Timestamp {
+	if x != nil {
+		return x.ExpectedArrivalTime
+	}
+	return nil
+}
+
+func (x *LabelledJourney) GetExpectedDepartureTime() *timestamppb.Timestamp {
+	if x != nil {
+		return x.ExpectedDepartureTime
+	}
+	return nil
+}
+
+func (x *LabelledJourney) GetDistanceFromStop() int64 {
+	if x != nil && x.DistanceFromStop != nil {
+		return *x.DistanceFromStop
+	}
+	return 0
+}
+
+func (x *LabelledJourney) GetNumberOfStopsAway() int64 {
+	if x != nil && x.NumberOfStopsAway != nil {
+		return *x.NumberOfStopsAway
+	}
+	return 0
+}
+
+func (x *LabelledJourney) GetStopPointRef() string {
+	if x != nil && x.StopPointRef != nil {
+		return *x.StopPointRef
+	}
+	return ""
+}
+
+func (x *LabelledJourney) GetTimestamp() *timestamppb.Timestamp {
+	if x != nil {
+		return x.Timestamp
+	}
+	return nil
+}
+
+func (x *LabelledJourney) GetTimeToStop() int64 {
+	if x != nil && x.TimeToStop != nil {
+		return *x.TimeToStop
+	}
+	return 0
+}
+
+type LabelledJourneyList struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Journeys      []*LabelledJourney     `protobuf:"bytes,1,rep,name=journeys,proto3" json:"journeys,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *LabelledJourneyList) Reset() {
+	*x = LabelledJourneyList{}
+	mi := &file_busproto_bus_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *LabelledJourneyList) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*LabelledJourneyList) ProtoMessage() {}
+
+func (x *LabelledJourneyList) ProtoReflect() protoreflect.Message {
+	mi := &file_busproto_bus_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use LabelledJourneyList.ProtoReflect.Descriptor instead.
+func (*LabelledJourneyList) Descriptor() ([]byte, []int) {
+	return file_busproto_bus_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *LabelledJourneyList) GetJourneys() []*LabelledJourney {
+	if x != nil {
+		return x.Journeys
+	}
+	return nil
+}
+
+// The distance in metres between two consecutive stops on a route, see bus.StopDistance
+type StopDistance struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	RouteId       string                 `protobuf:"bytes,1,opt,name=route_id,json=routeId,proto3" json:"route_id,omitempty"`
+	DirectionId   int32                  `protobuf:"varint,2,opt,name=direction_id,json=directionId,proto3" json:"direction_id,omitempty"`
+	FromId        string                 `protobuf:"bytes,3,opt,name=from_id,json=fromId,proto3" json:"from_id,omitempty"`
+	ToId          string                 `protobuf:"bytes,4,opt,name=to_id,json=toId,proto3" json:"to_id,omitempty"`
+	Distance      float64                `protobuf:"fixed64,5,opt,name=distance,proto3" json:"distance,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *StopDistance) Reset() {
+	*x = StopDistance{}
+	mi := &file_busproto_bus_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *StopDistance) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StopDistance) ProtoMessage() {}
+
+func (x *StopDistance) ProtoReflect() protoreflect.Message {
+	mi := &file_busproto_bus_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StopDistance.ProtoReflect.Descriptor instead.
+func (*StopDistance) Descriptor() ([]byte, []int) {
+	return file_busproto_bus_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *StopDistance) GetRouteId() string {
+	if x != nil {
+		return x.RouteId
+	}
+	return ""
+}
+
+func (x *StopDistance) GetDirectionId() int32 {
+	if x != nil {
+		return x.DirectionId
+	}
+	return 0
+}
+
+func (x *StopDistance) GetFromId() string {
+	if x != nil {
+		return x.FromId
+	}
+	return ""
+}
+
+func (x *StopDistance) GetToId() string {
+	if x != nil {
+		return x.ToId
+	}
+	return ""
+}
+
+func (x *StopDistance) GetDistance() float64 {
+	if x != nil {
+		return x.Distance
+	}
+	return 0
+}
+
+type StopDistanceList struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Distances     []*StopDistance        `protobuf:"bytes,1,rep,name=distances,proto3" json:"distances,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *StopDistanceList) Reset() {
+	*x = StopDistanceList{}
+	mi := &file_busproto_bus_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *StopDistanceList) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StopDistanceList) ProtoMessage() {}
+
+func (x *StopDistanceList) ProtoReflect() protoreflect.Message {
+	mi := &file_busproto_bus_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StopDistanceList.ProtoReflect.Descriptor instead.
+func (*StopDistanceList) Descriptor() ([]byte, []int) {
+	return file_busproto_bus_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *StopDistanceList) GetDistances() []*StopDistance {
+	if x != nil {
+		return x.Distances
+	}
+	return nil
+}
+
+var File_busproto_bus_proto protoreflect.FileDescriptor
+
+const file_busproto_bus_proto_rawDesc = "" +
+	"\n" +
+	"\x12busproto/bus.proto\x12\rtransport.bus\x1a\x1fgoogle/protobuf/timestamp.proto\"\xd5\t\n" +
+	"\x0eVehicleJourney\x12\x1e\n" +
+	"\bline_ref\x18\x01 \x01(\tH\x00R\alineRef\x88\x01\x01\x12(\n" +
+	"\rdirection_ref\x18\x02 \x01(\x03H\x01R\fdirectionRef\x88\x01\x01\x12\x1c\n" +
+	"\atrip_id\x18\x03 \x01(\tH\x02R\x06tripId\x88\x01\x01\x123\n" +
+	"\x13published_line_name\x18\x04 \x01(\tH\x03R\x11publishedLineName\x88\x01\x01\x12&\n" +
+	"\foperator_ref\x18\x05 \x01(\tH\x04R\voperatorRef\x88\x01\x01\x12\"\n" +
+	"\n" +
+	"origin_ref\x18\x06 \x01(\tH\x05R\toriginRef\x88\x01\x01\x12,\n" +
+	"\x0fdestination_ref\x18\a \x01(\tH\x06R\x0edestinationRef\x88\x01\x01\x12Y\n" +
+	"\x1borigin_aimed_departure_time\x18\b \x01(\v2\x1a.google.protobuf.TimestampR\x18originAimedDepartureTime\x12#\n" +
+	"\rsituation_ref\x18\t \x03(\tR\fsituationRef\x12!\n" +
+	"\tlongitude\x18\n" +
+	" \x01(\x01H\aR\tlongitude\x88\x01\x01\x12\x1f\n" +
+	"\blatitude\x18\v \x01(\x01H\bR\blatitude\x88\x01\x01\x12(\n" +
+	"\rprogress_rate\x18\f \x01(\tH\tR\fprogressRate\x88\x01\x01\x12!\n" +
+	"\toccupancy\x18\r \x01(\tH\n" +
+	"R\toccupancy\x88\x01\x01\x12$\n" +
+	"\vvehicle_ref\x18\x0e \x01(\tH\vR\n" +
+	"vehicleRef\x88\x01\x01\x12N\n" +
+	"\x15expected_arrival_time\x18\x0f \x01(\v2\x1a.google.protobuf.TimestampR\x13expectedArrivalTime\x12R\n" +
+	"\x17expected_departure_time\x18\x10 \x01(\v2\x1a.google.protobuf.TimestampR\x15expectedDepartureTime\x121\n" +
+	"\x12distance_from_stop\x18\x11 \x01(\x03H\fR\x10distanceFromStop\x88\x01\x01\x124\n" +
+	"\x14number_of_stops_away\x18\x12 \x01(\x03H\rR\x11numberOfStopsAway\x88\x01\x01\x12)\n" +
+	"\x0estop_point_ref\x18\x13 \x01(\tH\x0eR\fstopPointRef\x88\x01\x01\x128\n" +
+	"\ttimestamp\x18\x14 \x01(\v2\x1a.google.protobuf.TimestampR\ttimestampB\v\n" +
+	"\t_line_refB\x10\n" +
+	"\x0e_direction_refB\n" +
+	"\n" +
+	"\b_trip_idB\x16\n" +
+	"\x14_published_line_nameB\x0f\n" +
+	"\r_operator_refB\r\n" +
+	"\v_origin_refB\x12\n" +
+	"\x10_destination_refB\f\n" +
+	"\n" +
+	"_longitudeB\v\n" +
+	"\t_latitudeB\x10\n" +
+	"\x0e_progress_rateB\f\n" +
+	"\n" +
+	"_occupancyB\x0e\n" +
+	"\f_vehicle_refB\x15\n" +
+	"\x13_distance_from_stopB\x17\n" +
+	"\x15_number_of_stops_awayB\x11\n" +
+	"\x0f_stop_point_ref\"O\n" +
+	"\x12VehicleJourneyList\x129\n" +
+	"\bjourneys\x18\x01 \x03(\v2\x1d.transport.bus.VehicleJourneyR\bjourneys\"\x97\b\n" +
+	"\x0fLabelledJourney\x12\x1e\n" +
+	"\bline_ref\x18\x01 \x01(\tH\x00R\alineRef\x88\x01\x01\x12(\n" +
+	"\rdirection_ref\x18\x02 \x01(\x03H\x01R\fdirectionRef\x88\x01\x01\x12&\n" +
+	"\foperator_ref\x18\x03 \x01(\tH\x02R\voperatorRef\x88\x01\x01\x12\"\n" +
+	"\n" +
+	"origin_ref\x18\x04 \x01(\tH\x03R\toriginRef\x88\x01\x01\x12,\n" +
+	"\x0fdestination_ref\x18\x05 \x01(\tH\x04R\x0edestinationRef\x88\x01\x01\x12!\n" +
+	"\tlongitude\x18\x06 \x01(\x01H\x05R\tlongitude\x88\x01\x01\x12\x1f\n" +
+	"\blatitude\x18\a \x01(\x01H\x06R\blatitude\x88\x01\x01\x12(\n" +
+	"\rprogress_rate\x18\b \x01(\tH\aR\fprogressRate\x88\x01\x01\x12!\n" +
+	"\toccupancy\x18\t \x01(\tH\bR\toccupancy\x88\x01\x01\x12$\n" +
+	"\vvehicle_ref\x18\n" +
+	" \x01(\tH\tR\n" +
+	"vehicleRef\x88\x01\x01\x12N\n" +
+	"\x15expected_arrival_time\x18\v \x01(\v2\x1a.google.protobuf.TimestampR\x13expectedArrivalTime\x12R\n" +
+	"\x17expected_departure_time\x18\f \x01(\v2\x1a.google.protobuf.TimestampR\x15expectedDepartureTime\x121\n" +
+	"\x12distance_from_stop\x18\r \x01(\x03H\n" +
+	"R\x10distanceFromStop\x88\x01\x01\x124\n" +
+	"\x14number_of_stops_away\x18\x0e \x01(\x03H\vR\x11numberOfStopsAway\x88\x01\x01\x12)\n" +
+	"\x0estop_point_ref\x18\x0f \x01(\tH\fR\fstopPointRef\x88\x01\x01\x128\n" +
+	"\ttimestamp\x18\x10 \x01(\v2\x1a.google.protobuf.TimestampR\ttimestamp\x12%\n" +
+	"\ftime_to_stop\x18\x11 \x01(\x03H\rR\n" +
+	"timeToStop\x88\x01\x01B\v\n" +
+	"\t_line_refB\x10\n" +
+	"\x0e_direction_refB\x0f\n" +
+	"\r_operator_refB\r\n" +
+	"\v_origin_refB\x12\n" +
+	"\x10_destination_refB\f\n" +
+	"\n" +
+	"_longitudeB\v\n" +
+	"\t_latitudeB\x10\n" +
+	"\x0e_progress_rateB\f\n" +
+	"\n" +
+	"_occupancyB\x0e\n" +
+	"\f_vehicle_refB\x15\n" +
+	"\x13_distance_from_stopB\x17\n" +
+	"\x15_number_of_stops_awayB\x11\n" +
+	"\x0f_stop_point_refB\x0f\n" +
+	"\r_time_to_stop\"Q\n" +
+	"\x13LabelledJourneyList\x12:\n" +
+	"\bjourneys\x18\x01 \x03(\v2\x1e.transport.bus.LabelledJourneyR\bjourneys\"\x96\x01\n" +
+	"\fStopDistance\x12\x19\n" +
+	"\broute_id\x18\x01 \x01(\tR\arouteId\x12!\n" +
+	"\fdirection_id\x18\x02 \x01(\x05R\vdirectionId\x12\x17\n" +
+	"\afrom_id\x18\x03 \x01(\tR\x06fromId\x12\x13\n" +
+	"\x05to_id\x18\x04 \x01(\tR\x04toId\x12\x1a\n" +
+	"\bdistance\x18\x05 \x01(\x01R\bdistance\"M\n" +
+	"\x10StopDistanceList\x129\n" +
+	"\tdistances\x18\x01 \x03(\v2\x1b.transport.bus.StopDistanceR\tdistancesB\x18Z\x16transport/lib/busprotob\x06proto3"
+
+var (
+	file_busproto_bus_proto_rawDescOnce sync.Once
+	file_busproto_bus_proto_rawDescData []byte
+)
+
+func file_busproto_bus_proto_rawDescGZIP() []byte {
+	file_busproto_bus_proto_rawDescOnce.Do(func() {
+		file_busproto_bus_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_busproto_bus_proto_rawDesc), len(file_busproto_bus_proto_rawDesc)))
+	})
+	return file_busproto_bus_proto_rawDescData
+}
+
+var file_busproto_bus_proto_msgTypes = make([]protoimpl.MessageInfo, 6)
+var file_busproto_bus_proto_goTypes = []any{
+	(*VehicleJourney)(nil),        // 0: transport.bus.VehicleJourney
+	(*VehicleJourneyList)(nil),    // 1: transport.bus.VehicleJourneyList
+	(*LabelledJourney)(nil),       // 2: transport.bus.LabelledJourney
+	(*LabelledJourneyList)(nil),   // 3: transport.bus.LabelledJourneyList
+	(*StopDistance)(nil),          // 4: transport.bus.StopDistance
+	(*StopDistanceList)(nil),      // 5: transport.bus.StopDistanceList
+	(*timestamppb.Timestamp)(nil), // 6: google.protobuf.Timestamp
+}
+var file_busproto_bus_proto_depIdxs = []int32{
+	6,  // 0: transport.bus.VehicleJourney.origin_aimed_departure_time:type_name -> google.protobuf.Timestamp
+	6,  // 1: transport.bus.VehicleJourney.expected_arrival_time:type_name -> google.protobuf.Timestamp
+	6,  // 2: transport.bus.VehicleJourney.expected_departure_time:type_name -> google.protobuf.Timestamp
+	6,  // 3: transport.bus.VehicleJourney.timestamp:type_name -> google.protobuf.Timestamp
+	0,  // 4: transport.bus.VehicleJourneyList.journeys:type_name -> transport.bus.VehicleJourney
+	6,  // 5: transport.bus.LabelledJourney.expected_arrival_time:type_name -> google.protobuf.Timestamp
+	6,  // 6: transport.bus.LabelledJourney.expected_departure_time:type_name -> google.protobuf.Timestamp
+	6,  // 7: transport.bus.LabelledJourney.timestamp:type_name -> google.protobuf.Timestamp
+	2,  // 8: transport.bus.LabelledJourneyList.journeys:type_name -> transport.bus.LabelledJourney
+	4,  // 9: transport.bus.StopDistanceList.distances:type_name -> transport.bus.StopDistance
+	10, // [10:10] is the sub-list for method output_type
+	10, // [10:10] is the sub-list for method input_type
+	10, // [10:10] is the sub-list for extension type_name
+	10, // [10:10] is the sub-list for extension extendee
+	0,  // [0:10] is the sub-list for field type_name
+}
+
+func init() { file_busproto_bus_proto_init() }
+func file_busproto_bus_proto_init() {
+	if File_busproto_bus_proto != nil {
+		return
+	}
+	file_busproto_bus_proto_msgTypes[0].OneofWrappers = []any{}
+	file_busproto_bus_proto_msgTypes[2].OneofWrappers = []any{}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_busproto_bus_proto_rawDesc), len(file_busproto_bus_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   6,
+			NumExtensions: 0,
+			NumServices:   0,
+		},
+		GoTypes:           file_busproto_bus_proto_goTypes,
+		DependencyIndexes: file_busproto_bus_proto_depIdxs,
+		MessageInfos:      file_busproto_bus_proto_msgTypes,
+	}.Build()
+	File_busproto_bus_proto = out.File
+	file_busproto_bus_proto_goTypes = nil
+	file_busproto_bus_proto_depIdxs = nil
+}
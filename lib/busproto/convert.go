@@ -0,0 +1,237 @@
+package busproto
+
+import (
+	"fmt"
+	"transport/lib/bus"
+	"transport/lib/database"
+	"transport/lib/nulltypes"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"gopkg.in/guregu/null.v3"
+)
+
+// ContentType is the MIME type used to request and serve protobuf-encoded data
+const ContentType = "application/x-protobuf"
+
+// FromVehicleJourney converts a bus.VehicleJourney into its protobuf message
+func FromVehicleJourney(vj bus.VehicleJourney) *VehicleJourney {
+	return &VehicleJourney{
+		LineRef:                  vj.LineRef.Ptr(),
+		DirectionRef:             vj.DirectionRef.Ptr(),
+		TripId:                   vj.TripID.Ptr(),
+		PublishedLineName:        vj.PublishedLineName.Ptr(),
+		OperatorRef:              vj.OperatorRef.Ptr(),
+		OriginRef:                vj.OriginRef.Ptr(),
+		DestinationRef:           vj.DestinationRef.Ptr(),
+		OriginAimedDepartureTime: fromTimestamp(vj.OriginAimedDepartureTime),
+		SituationRef:             vj.SituationRef.StringSlice,
+		Longitude:                vj.Longitude.Ptr(),
+		Latitude:                 vj.Latitude.Ptr(),
+		ProgressRate:             vj.ProgressRate.Ptr(),
+		Occupancy:                vj.Occupancy.Ptr(),
+		VehicleRef:               vj.VehicleRef.Ptr(),
+		ExpectedArrivalTime:      fromTimestamp(vj.ExpectedArrivalTime),
+		ExpectedDepartureTime:    fromTimestamp(vj.ExpectedDepartureTime),
+		DistanceFromStop:         vj.DistanceFromStop.Ptr(),
+		NumberOfStopsAway:        vj.NumberOfStopsAway.Ptr(),
+		StopPointRef:             vj.StopPointRef.Ptr(),
+		Timestamp:                fromTimestamp(vj.Timestamp),
+	}
+}
+
+// ToVehicleJourney converts a protobuf message into a bus.VehicleJourney. Unset fields
+// become invalid (null) values, and an empty SituationRef is treated as null.
+func ToVehicleJourney(pb *VehicleJourney) bus.VehicleJourney {
+	return bus.VehicleJourney{
+		LineRef:                  null.StringFromPtr(pb.LineRef),
+		DirectionRef:             null.IntFromPtr(pb.DirectionRef),
+		TripID:                   null.StringFromPtr(pb.TripId),
+		PublishedLineName:        null.StringFromPtr(pb.PublishedLineName),
+		OperatorRef:              null.StringFromPtr(pb.OperatorRef),
+		OriginRef:                null.StringFromPtr(pb.OriginRef),
+		DestinationRef:           null.StringFromPtr(pb.DestinationRef),
+		OriginAimedDepartureTime: toTimestamp(pb.OriginAimedDepartureTime),
+		SituationRef:             toStringSlice(pb.SituationRef),
+		Longitude:                null.FloatFromPtr(pb.Longitude),
+		Latitude:                 null.FloatFromPtr(pb.Latitude),
+		ProgressRate:             null.StringFromPtr(pb.ProgressRate),
+		Occupancy:                null.StringFromPtr(pb.Occupancy),
+		VehicleRef:               null.StringFromPtr(pb.VehicleRef),
+		ExpectedArrivalTime:      toTimestamp(pb.ExpectedArrivalTime),
+		ExpectedDepartureTime:    toTimestamp(pb.ExpectedDepartureTime),
+		DistanceFromStop:         null.IntFromPtr(pb.DistanceFromStop),
+		NumberOfStopsAway:        null.IntFromPtr(pb.NumberOfStopsAway),
+		StopPointRef:             null.StringFromPtr(pb.StopPointRef),
+		Timestamp:                toTimestamp(pb.Timestamp),
+	}
+}
+
+// FromLabelledJourney converts a bus.LabelledJourney into its protobuf message
+func FromLabelledJourney(lj bus.LabelledJourney) *LabelledJourney {
+	return &LabelledJourney{
+		LineRef:               lj.LineRef.Ptr(),
+		DirectionRef:          lj.DirectionRef.Ptr(),
+		OperatorRef:           lj.OperatorRef.Ptr(),
+		OriginRef:             lj.OriginRef.Ptr(),
+		DestinationRef:        lj.DestinationRef.Ptr(),
+		Longitude:             lj.Longitude.Ptr(),
+		Latitude:              lj.Latitude.Ptr(),
+		ProgressRate:          lj.ProgressRate.Ptr(),
+		Occupancy:             lj.Occupancy.Ptr(),
+		VehicleRef:            lj.VehicleRef.Ptr(),
+		ExpectedArrivalTime:   fromTimestamp(lj.ExpectedArrivalTime),
+		ExpectedDepartureTime: fromTimestamp(lj.ExpectedDepartureTime),
+		DistanceFromStop:      lj.DistanceFromStop.Ptr(),
+		NumberOfStopsAway:     lj.NumberOfStopsAway.Ptr(),
+		StopPointRef:          lj.StopPointRef.Ptr(),
+		Timestamp:             fromTimestamp(lj.Timestamp),
+		TimeToStop:            lj.TimeToStop.Ptr(),
+	}
+}
+
+// ToLabelledJourney converts a protobuf message into a bus.LabelledJourney
+func ToLabelledJourney(pb *LabelledJourney) bus.LabelledJourney {
+	return bus.LabelledJourney{
+		LineRef:               null.StringFromPtr(pb.LineRef),
+		DirectionRef:          null.IntFromPtr(pb.DirectionRef),
+		OperatorRef:           null.StringFromPtr(pb.OperatorRef),
+		OriginRef:             null.StringFromPtr(pb.OriginRef),
+		DestinationRef:        null.StringFromPtr(pb.DestinationRef),
+		Longitude:             null.FloatFromPtr(pb.Longitude),
+		Latitude:              null.FloatFromPtr(pb.Latitude),
+		ProgressRate:          null.StringFromPtr(pb.ProgressRate),
+		Occupancy:             null.StringFromPtr(pb.Occupancy),
+		VehicleRef:            null.StringFromPtr(pb.VehicleRef),
+		ExpectedArrivalTime:   toTimestamp(pb.ExpectedArrivalTime),
+		ExpectedDepartureTime: toTimestamp(pb.ExpectedDepartureTime),
+		DistanceFromStop:      null.IntFromPtr(pb.DistanceFromStop),
+		NumberOfStopsAway:     null.IntFromPtr(pb.NumberOfStopsAway),
+		StopPointRef:          null.StringFromPtr(pb.StopPointRef),
+		Timestamp:             toTimestamp(pb.Timestamp),
+		TimeToStop:            null.IntFromPtr(pb.TimeToStop),
+	}
+}
+
+// FromStopDistance converts a bus.StopDistance into its protobuf message
+func FromStopDistance(sd bus.StopDistance) *StopDistance {
+	return &StopDistance{
+		RouteId:     sd.RouteID,
+		DirectionId: int32(sd.DirectionID),
+		FromId:      sd.FromID,
+		ToId:        sd.ToID,
+		Distance:    sd.Distance,
+	}
+}
+
+// ToStopDistance converts a protobuf message into a bus.StopDistance
+func ToStopDistance(pb *StopDistance) bus.StopDistance {
+	return bus.StopDistance{
+		RouteID:     pb.RouteId,
+		DirectionID: int(pb.DirectionId),
+		FromID:      pb.FromId,
+		ToID:        pb.ToId,
+		Distance:    pb.Distance,
+	}
+}
+
+// MarshalVehicleJourneys encodes the journeys as a VehicleJourneyList message
+func MarshalVehicleJourneys(journeys []bus.VehicleJourney) ([]byte, error) {
+	list := &VehicleJourneyList{Journeys: make([]*VehicleJourney, len(journeys))}
+	for i, vj := range journeys {
+		list.Journeys[i] = FromVehicleJourney(vj)
+	}
+	data, err := proto.Marshal(list)
+	if err != nil {
+		return nil, fmt.Errorf("busproto.MarshalVehicleJourneys: failed to encode %d journeys: %s", len(journeys), err)
+	}
+	return data, nil
+}
+
+// UnmarshalVehicleJourneys decodes a VehicleJourneyList message
+func UnmarshalVehicleJourneys(data []byte) ([]bus.VehicleJourney, error) {
+	var list VehicleJourneyList
+	if err := proto.Unmarshal(data, &list); err != nil {
+		return nil, fmt.Errorf("busproto.UnmarshalVehicleJourneys: failed to decode journeys: %s", err)
+	}
+	journeys := make([]bus.VehicleJourney, len(list.Journeys))
+	for i, pb := range list.Journeys {
+		journeys[i] = ToVehicleJourney(pb)
+	}
+	return journeys, nil
+}
+
+// MarshalLabelledJourneys encodes the journeys as a LabelledJourneyList message
+func MarshalLabelledJourneys(journeys []bus.LabelledJourney) ([]byte, error) {
+	list := &LabelledJourneyList{Journeys: make([]*LabelledJourney, len(journeys))}
+	for i, lj := range journeys {
+		list.Journeys[i] = FromLabelledJourney(lj)
+	}
+	data, err := proto.Marshal(list)
+	if err != nil {
+		return nil, fmt.Errorf("busproto.MarshalLabelledJourneys: failed to encode %d journeys: %s", len(journeys), err)
+	}
+	return data, nil
+}
+
+// UnmarshalLabelledJourneys decodes a LabelledJourneyList message
+func UnmarshalLabelledJourneys(data []byte) ([]bus.LabelledJourney, error) {
+	var list LabelledJourneyList
+	if err := proto.Unmarshal(data, &list); err != nil {
+		return nil, fmt.Errorf("busproto.UnmarshalLabelledJourneys: failed to decode journeys: %s", err)
+	}
+	journeys := make([]bus.LabelledJourney, len(list.Journeys))
+	for i, pb := range list.Journeys {
+		journeys[i] = ToLabelledJourney(pb)
+	}
+	return journeys, nil
+}
+
+// MarshalStopDistances encodes the distances as a StopDistanceList message
+func MarshalStopDistances(distances []bus.StopDistance) ([]byte, error) {
+	list := &StopDistanceList{Distances: make([]*StopDistance, len(distances))}
+	for i, sd := range distances {
+		list.Distances[i] = FromStopDistance(sd)
+	}
+	data, err := proto.Marshal(list)
+	if err != nil {
+		return nil, fmt.Errorf("busproto.MarshalStopDistances: failed to encode %d distances: %s", len(distances), err)
+	}
+	return data, nil
+}
+
+// UnmarshalStopDistances decodes a StopDistanceList message
+func UnmarshalStopDistances(data []byte) ([]bus.StopDistance, error) {
+	var list StopDistanceList
+	if err := proto.Unmarshal(data, &list); err != nil {
+		return nil, fmt.Errorf("busproto.UnmarshalStopDistances: failed to decode distances: %s", err)
+	}
+	distances := make([]bus.StopDistance, len(list.Distances))
+	for i, pb := range list.Distances {
+		distances[i] = ToStopDistance(pb)
+	}
+	return distances, nil
+}
+
+// fromTimestamp converts a nullable timestamp, leaving the field unset if it is null
+func fromTimestamp(ts nulltypes.Timestamp) *timestamppb.Timestamp {
+	if !ts.Valid {
+		return nil
+	}
+	return timestamppb.New(ts.Time)
+}
+
+// toTimestamp converts a timestamp field into the DB's timezone, or null if it is unset
+func toTimestamp(pb *timestamppb.Timestamp) nulltypes.Timestamp {
+	if pb == nil {
+		return nulltypes.Timestamp{}
+	}
+	return nulltypes.Timestamp{Timestamp: database.Timestamp{Time: pb.AsTime().In(database.TimeLoc)}, Valid: true}
+}
+
+func toStringSlice(ss []string) nulltypes.StringSlice {
+	if len(ss) == 0 {
+		return nulltypes.StringSlice{}
+	}
+	return nulltypes.StringSliceFrom(ss)
+}
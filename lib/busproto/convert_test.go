@@ -0,0 +1,88 @@
+package busproto
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+	"transport/lib/bus"
+	"transport/lib/database"
+	"transport/lib/nulltypes"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/guregu/null.v3"
+)
+
+func TestVehicleJourneysRoundTrip(t *testing.T) {
+	ts := nulltypes.TimestampFrom(database.Timestamp{Time: time.Date(2019, 6, 3, 8, 0, 0, 0, database.TimeLoc)})
+	journeys := []bus.VehicleJourney{
+		{
+			LineRef:          null.StringFrom("MTA NYCT_B41"),
+			DirectionRef:     null.IntFrom(1),
+			TripID:           null.StringFrom("T1"),
+			SituationRef:     nulltypes.StringSliceFrom([]string{"MTA NYCT_1", "MTA NYCT_2"}),
+			Latitude:         null.FloatFrom(40.7),
+			Longitude:        null.FloatFrom(-73.99),
+			VehicleRef:       null.StringFrom("MTA NYCT_9001"),
+			DistanceFromStop: null.IntFrom(0),
+			StopPointRef:     null.StringFrom("MTA_1"),
+			Timestamp:        ts,
+		},
+		// Every field is null
+		{},
+	}
+	data, err := MarshalVehicleJourneys(journeys)
+	assert.NoError(t, err)
+	decoded, err := UnmarshalVehicleJourneys(data)
+	assert.NoError(t, err)
+	assert.Equal(t, journeys, decoded)
+}
+
+func TestVehicleJourneysRoundTrip_Timestamps(t *testing.T) {
+	// Only valid timestamps are encoded, whether or not a time was left behind in the struct
+	invalid := nulltypes.Timestamp{Timestamp: database.Timestamp{Time: time.Date(2019, 6, 3, 8, 0, 0, 0, database.TimeLoc)}}
+	data, err := MarshalVehicleJourneys([]bus.VehicleJourney{{Timestamp: invalid}})
+	assert.NoError(t, err)
+	decoded, err := UnmarshalVehicleJourneys(data)
+	assert.NoError(t, err)
+	assert.Equal(t, []bus.VehicleJourney{{}}, decoded)
+
+	// Timestamps parsed from JSON are valid, so they survive the round trip
+	var fromJSON bus.VehicleJourney
+	assert.NoError(t, json.Unmarshal([]byte(`{"Timestamp": "2019-06-03 08:00:00"}`), &fromJSON))
+	assert.True(t, fromJSON.Timestamp.Valid)
+	data, err = MarshalVehicleJourneys([]bus.VehicleJourney{fromJSON})
+	assert.NoError(t, err)
+	decoded, err = UnmarshalVehicleJourneys(data)
+	assert.NoError(t, err)
+	assert.Equal(t, []bus.VehicleJourney{fromJSON}, decoded)
+}
+
+func TestLabelledJourneysRoundTrip(t *testing.T) {
+	ts := nulltypes.TimestampFrom(database.Timestamp{Time: time.Date(2019, 6, 3, 8, 0, 0, 0, database.TimeLoc)})
+	journeys := []bus.LabelledJourney{{
+		LineRef:      null.StringFrom("MTA NYCT_B41"),
+		DirectionRef: null.IntFrom(0),
+		StopPointRef: null.StringFrom("MTA_1"),
+		Timestamp:    ts,
+		TimeToStop:   null.IntFrom(95),
+	}}
+	data, err := MarshalLabelledJourneys(journeys)
+	assert.NoError(t, err)
+	decoded, err := UnmarshalLabelledJourneys(data)
+	assert.NoError(t, err)
+	assert.Equal(t, journeys, decoded)
+}
+
+func TestStopDistancesRoundTrip(t *testing.T) {
+	distances := []bus.StopDistance{{RouteID: "MTA NYCT_B41", DirectionID: 1, FromID: "MTA_1", ToID: "MTA_2", Distance: 312.5}}
+	data, err := MarshalStopDistances(distances)
+	assert.NoError(t, err)
+	decoded, err := UnmarshalStopDistances(data)
+	assert.NoError(t, err)
+	assert.Equal(t, distances, decoded)
+}
+
+func TestUnmarshalInvalidData(t *testing.T) {
+	_, err := UnmarshalVehicleJourneys([]byte{0xff, 0xff})
+	assert.Error(t, err)
+}
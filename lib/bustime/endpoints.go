@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"log"
 	"sync"
+	"transport/lib/geo"
 	"transport/lib/jsonhelper"
 	"transport/lib/network"
 
@@ -23,6 +24,11 @@ type BusStop struct {
 	Longitude float64 `json:"longitude"`
 }
 
+// Point returns the position of the stop for use with the geo package
+func (stop BusStop) Point() geo.Point {
+	return geo.Point{Latitude: stop.Latitude, Longitude: stop.Longitude}
+}
+
 ////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
 // Agencies
 ////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
@@ -133,6 +139,47 @@ func getStopDetails(jsonString string) map[string]gjson.Result {
 	return stopIDsMap
 }
 
+////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+// Polylines
+////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+
+// GetPolylines takes a collection of routeIDs and returns a map of
+// the form: routeID -> directionID -> []encodedPolyline
+// Each direction may be made up of several polylines, encoded using
+// Google's polyline algorithm.
+func (client *Client) GetPolylines(routeIDs ...string) map[string]map[int][]string {
+	mapOfPolylines := map[string]map[int][]string{}
+	mux, done := &sync.Mutex{}, make(chan string)
+	for _, routeID := range routeIDs {
+		go client.populatePolylinesForRoute(mapOfPolylines, routeID, mux, done)
+	}
+	for i := 0; i < len(routeIDs); i++ {
+		completedRouteID := <-done
+		log.Printf("Succesfully stored polylines for route ID: %s\n", completedRouteID)
+	}
+	return mapOfPolylines
+}
+
+func (client *Client) populatePolylinesForRoute(mapOfPolylines map[string]map[int][]string, routeID string, mux *sync.Mutex, done chan string) {
+	log.Printf("Fetching polylines for route ID: %s\n", routeID)
+	URLWithKey := fmt.Sprintf(
+		"%s/%s/%s.json?%s&includePolylines=true",
+		client.baseURL, stopsEndpoint, routeID, client.MandatoryParams,
+	)
+	jsonString := network.GetRequestBody(URLWithKey)
+	polylinesByDirection := map[int][]string{}
+	// Each direction of travel has its own set of polylines
+	travelDirections := gjson.Get(jsonString, "data.entry.stopGroupings.0.stopGroups").Array()
+	for _, direction := range travelDirections {
+		directionID := int(direction.Get("id").Int())
+		polylinesByDirection[directionID] = jsonhelper.ResultArrayToStringArray(direction.Get("polylines.#.points").Array())
+	}
+	mux.Lock()
+	mapOfPolylines[routeID] = polylinesByDirection
+	mux.Unlock()
+	done <- routeID
+}
+
 // Takes a list of stops and returns a list of stopIDs containing all the stops after
 // the given stopID (i.e. removing any stops that are before stopID). If `inclusive`
 // is true, then the given stopID is also included in the list (as the first item).
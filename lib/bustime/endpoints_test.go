@@ -267,3 +267,58 @@ var stopsExpectedOutput = map[string]map[int][]bustime.BusStop{
 		},
 	},
 }
+
+////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+// Polylines
+////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+
+func TestClient_GetPolylines(t *testing.T) {
+	ts := testhelper.ServeMultiResponseMock(polylinesResponses, testhelper.ExtractJSONFilepath)
+	defer ts.Close()
+
+	client := bustime.NewClient("TEST", bustime.CustomBaseURLOption(ts.URL))
+
+	expected := map[string]map[int][]string{
+		"MTA NYCT_M1": {
+			0: {"_p~iF~ps|U_ulLnnqC", "_mqNvxq`@"},
+			1: {"_t~fGfzxbW"},
+		},
+	}
+	actual := client.GetPolylines("MTA NYCT_M1")
+
+	if !reflect.DeepEqual(expected, actual) {
+		t.Errorf("bustime.GetPolylines did not return expected map of routeIDs to polylines (expected: %v, received: %v)", expected, actual)
+	}
+}
+
+var polylinesResponses = map[string]string{
+	"MTA NYCT_M1": `
+{
+  "data": {
+    "entry": {
+      "stopGroupings": [
+        {
+          "stopGroups": [
+            {
+              "id": 0,
+              "polylines": [
+                {"points": "_p~iF~ps|U_ulLnnqC"},
+                {"points": "_mqNvxq` + "`" + `@"}
+              ],
+              "stopIds": ["MTA_100001", "MTA_100002"]
+            },
+            {
+              "id": 1,
+              "polylines": [
+                {"points": "_t~fGfzxbW"}
+              ],
+              "stopIds": ["MTA_100003"]
+            }
+          ]
+        }
+      ]
+    }
+  }
+}
+`,
+}
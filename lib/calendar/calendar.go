@@ -0,0 +1,223 @@
+package calendar
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"time"
+	"transport/lib/dates"
+	"transport/lib/iohelper"
+)
+
+// DayType is the kind of service that runs on a given day. Buses run different
+// timetables (and see very different traffic) on each type of day.
+type DayType string
+
+const (
+	Weekday      DayType = "weekday"
+	Saturday     DayType = "saturday"
+	Sunday       DayType = "sunday"
+	Holiday      DayType = "holiday"
+	SpecialEvent DayType = "special_event"
+)
+
+// PathEnvVar is the environment variable holding the path to a calendar config file
+const PathEnvVar = "CALENDAR_PATH"
+
+// Entry is a single named date in a calendar, e.g. {"date": "2019-11-03", "name": "NYC Marathon"}
+type Entry struct {
+	Date string `json:"date"`
+	Name string `json:"name"`
+}
+
+// config is the format of calendar config files, e.g.
+// {
+//     "useMajorHolidays": true,
+//     "holidays": [{"date": "2019-02-05", "name": "Lunar New Year"}],
+//     "events": [{"date": "2019-11-03", "name": "NYC Marathon"}]
+// }
+type config struct {
+	UseMajorHolidays *bool   `json:"useMajorHolidays"`
+	Holidays         []Entry `json:"holidays"`
+	Events           []Entry `json:"events"`
+}
+
+// Calendar classifies service days into DayTypes. Special events take priority over
+// holidays, which take priority over the day of the week.
+type Calendar struct {
+	serviceCalendar  dates.ServiceCalendar
+	holidays         map[string]string
+	events           map[string]string
+	useMajorHolidays bool
+}
+
+// New creates a Calendar that classifies the service days of `sc`. By default it only
+// knows about weekdays and weekends (see the functions suffixed with 'Option' in this file).
+// Example Usage:
+// cal := calendar.New(dates.DefaultServiceCalendar, calendar.MajorHolidaysOption(), calendar.EventsOption(events...))
+func New(sc dates.ServiceCalendar, options ...func(*Calendar)) *Calendar {
+	c := &Calendar{serviceCalendar: sc, holidays: map[string]string{}, events: map[string]string{}}
+	for _, option := range options {
+		option(c)
+	}
+	return c
+}
+
+// MajorHolidaysOption returns a function that can be passed to New so that the
+// MajorHolidays of every year are classified as holidays
+func MajorHolidaysOption() func(*Calendar) {
+	return func(c *Calendar) {
+		c.useMajorHolidays = true
+	}
+}
+
+// HolidaysOption returns a function that can be passed to New to add local holidays
+func HolidaysOption(entries ...Entry) func(*Calendar) {
+	return func(c *Calendar) {
+		for _, e := range entries {
+			c.holidays[e.Date] = e.Name
+		}
+	}
+}
+
+// EventsOption returns a function that can be passed to New to add special events
+func EventsOption(entries ...Entry) func(*Calendar) {
+	return func(c *Calendar) {
+		for _, e := range entries {
+			c.events[e.Date] = e.Name
+		}
+	}
+}
+
+// Load creates a Calendar from the config file at `path`. Major holidays are
+// included unless the file sets "useMajorHolidays" to false.
+func Load(path string, sc dates.ServiceCalendar) (*Calendar, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("calendar.Load: failed to read %s: %s", path, err)
+	}
+	var cfg config
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("calendar.Load: failed to parse %s: %s", path, err)
+	}
+	for _, e := range append(cfg.Holidays, cfg.Events...) {
+		if _, err := sc.ParseDay(e.Date); err != nil {
+			return nil, fmt.Errorf("calendar.Load: entry '%s' in %s: %s", e.Name, path, err)
+		}
+	}
+	options := []func(*Calendar){HolidaysOption(cfg.Holidays...), EventsOption(cfg.Events...)}
+	if cfg.UseMajorHolidays == nil || *cfg.UseMajorHolidays {
+		options = append(options, MajorHolidaysOption())
+	}
+	return New(sc, options...), nil
+}
+
+// FromEnv loads the Calendar from the file at CALENDAR_PATH if it is set. Otherwise,
+// a Calendar containing only the major holidays is returned.
+func FromEnv(sc dates.ServiceCalendar) (*Calendar, error) {
+	path := iohelper.GetEnvOrDefault(PathEnvVar, "")
+	if path == "" {
+		return New(sc, MajorHolidaysOption()), nil
+	}
+	return Load(path, sc)
+}
+
+// Classify returns the DayType of the service day
+func (c *Calendar) Classify(day dates.ServiceDay) DayType {
+	dayType, _ := c.Describe(day)
+	return dayType
+}
+
+// ClassifyTime returns the DayType of the service day that `t` falls within
+func (c *Calendar) ClassifyTime(t time.Time) DayType {
+	return c.Classify(c.serviceCalendar.DayOf(t))
+}
+
+// Describe returns the DayType of the service day, along with the name of the
+// holiday or event for those day types
+func (c *Calendar) Describe(day dates.ServiceDay) (DayType, string) {
+	key := day.String()
+	if name, ok := c.events[key]; ok {
+		return SpecialEvent, name
+	}
+	if name, ok := c.holidays[key]; ok {
+		return Holiday, name
+	}
+	if c.useMajorHolidays {
+		year, _, _ := day.Date()
+		for _, e := range MajorHolidays(year) {
+			if e.Date == key {
+				return Holiday, e.Name
+			}
+		}
+	}
+	switch day.Weekday() {
+	case time.Saturday:
+		return Saturday, ""
+	case time.Sunday:
+		return Sunday, ""
+	default:
+		return Weekday, ""
+	}
+}
+
+// ServiceCalendar returns the service calendar used to convert times into service days
+func (c *Calendar) ServiceCalendar() dates.ServiceCalendar {
+	return c.serviceCalendar
+}
+
+// MajorHolidays returns the holidays in `year` on which the MTA runs a holiday
+// (Sunday) schedule across the network, in date order. Holidays on a fixed date are
+// also observed on the Friday before (if they fall on a Saturday) or the Monday after
+// (if they fall on a Sunday), which can move next year's New Year's Day into `year`.
+func MajorHolidays(year int) []Entry {
+	var entries []Entry
+	add := func(name string, date time.Time) {
+		if date.Year() == year {
+			entries = append(entries, Entry{Date: date.Format("2006-01-02"), Name: name})
+		}
+	}
+	addFixed := func(name string, date time.Time) {
+		add(name, date)
+		if obs := observed(date); !obs.Equal(date) {
+			add(name+" (Observed)", obs)
+		}
+	}
+	date := func(month time.Month, day int) time.Time {
+		return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+	}
+	addFixed("New Year's Day", date(time.January, 1))
+	add("Memorial Day", date(time.May, lastWeekday(year, time.May, time.Monday)))
+	addFixed("Independence Day", date(time.July, 4))
+	add("Labor Day", date(time.September, nthWeekday(year, time.September, time.Monday, 1)))
+	add("Thanksgiving Day", date(time.November, nthWeekday(year, time.November, time.Thursday, 4)))
+	addFixed("Christmas Day", date(time.December, 25))
+	addFixed("New Year's Day", time.Date(year+1, time.January, 1, 0, 0, 0, 0, time.UTC))
+	return entries
+}
+
+// observed returns the weekday on which a holiday falling on `date` is observed
+func observed(date time.Time) time.Time {
+	switch date.Weekday() {
+	case time.Saturday:
+		return date.AddDate(0, 0, -1)
+	case time.Sunday:
+		return date.AddDate(0, 0, 1)
+	default:
+		return date
+	}
+}
+
+// nthWeekday returns the day of the month of the n-th `weekday` in the month
+func nthWeekday(year int, month time.Month, weekday time.Weekday, n int) int {
+	first := time.Date(year, month, 1, 0, 0, 0, 0, time.UTC).Weekday()
+	offset := (int(weekday) - int(first) + 7) % 7
+	return 1 + offset + 7*(n-1)
+}
+
+// lastWeekday returns the day of the month of the last `weekday` in the month
+func lastWeekday(year int, month time.Month, weekday time.Weekday) int {
+	last := time.Date(year, month+1, 0, 0, 0, 0, 0, time.UTC)
+	offset := (int(last.Weekday()) - int(weekday) + 7) % 7
+	return last.Day() - offset
+}
@@ -0,0 +1,85 @@
+package calendar
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+	"transport/lib/database"
+	"transport/lib/dates"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var sc = dates.DefaultServiceCalendar
+
+func TestCalendar_Classify(t *testing.T) {
+	cal := New(sc,
+		MajorHolidaysOption(),
+		HolidaysOption(Entry{Date: "2019-02-05", Name: "Lunar New Year"}),
+		EventsOption(Entry{Date: "2019-11-03", Name: "NYC Marathon"}),
+	)
+	days := []dates.ServiceDay{
+		sc.Day(2019, 6, 3),   // Monday
+		sc.Day(2019, 6, 1),   // Saturday
+		sc.Day(2019, 6, 2),   // Sunday
+		sc.Day(2019, 7, 4),   // Independence Day (Thursday)
+		sc.Day(2019, 2, 5),   // Configured holiday (Tuesday)
+		sc.Day(2019, 11, 3),  // Configured event (Sunday)
+		sc.Day(2019, 11, 28), // Thanksgiving
+	}
+	expected := []DayType{Weekday, Saturday, Sunday, Holiday, Holiday, SpecialEvent, Holiday}
+	for i, day := range days {
+		assert.Equal(t, expected[i], cal.Classify(day), day.String())
+	}
+	_, name := cal.Describe(sc.Day(2019, 11, 3))
+	assert.Equal(t, "NYC Marathon", name)
+}
+
+func TestCalendar_ClassifyTimeUsesServiceDay(t *testing.T) {
+	cal := New(sc)
+	// 2am on a Sunday belongs to Saturday's service
+	assert.Equal(t, Saturday, cal.ClassifyTime(time.Date(2019, 6, 2, 2, 0, 0, 0, database.TimeLoc)))
+	assert.Equal(t, Sunday, cal.ClassifyTime(time.Date(2019, 6, 2, 5, 0, 0, 0, database.TimeLoc)))
+}
+
+func TestMajorHolidays(t *testing.T) {
+	var actual []string
+	for _, e := range MajorHolidays(2019) {
+		actual = append(actual, e.Date)
+	}
+	expected := []string{"2019-01-01", "2019-05-27", "2019-07-04", "2019-09-02", "2019-11-28", "2019-12-25"}
+	assert.Equal(t, expected, actual)
+}
+
+func TestMajorHolidays_Observed(t *testing.T) {
+	cal := New(sc, MajorHolidaysOption())
+	// Independence Day 2021 was on a Sunday, so it was observed on Monday 5th July
+	dayType, name := cal.Describe(sc.Day(2021, 7, 5))
+	assert.Equal(t, Holiday, dayType)
+	assert.Equal(t, "Independence Day (Observed)", name)
+	assert.Equal(t, Holiday, cal.Classify(sc.Day(2021, 7, 4)))
+	// New Year's Day 2022 was on a Saturday, so it was observed on Friday 31st December 2021,
+	// as was Christmas Day on Friday 24th December
+	assert.Equal(t, Holiday, cal.Classify(sc.Day(2021, 12, 24)))
+	assert.Equal(t, Holiday, cal.Classify(sc.Day(2021, 12, 31)))
+	assert.Equal(t, Weekday, cal.Classify(sc.Day(2021, 12, 30)))
+}
+
+func TestLoad(t *testing.T) {
+	dir, err := ioutil.TempDir("", "calendar")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "calendar.json")
+	cfg := `{"useMajorHolidays": false, "events": [{"date": "2019-06-03", "name": "Parade"}]}`
+	assert.NoError(t, ioutil.WriteFile(path, []byte(cfg), 0644))
+	cal, err := Load(path, sc)
+	assert.NoError(t, err)
+	assert.Equal(t, SpecialEvent, cal.Classify(sc.Day(2019, 6, 3)))
+	assert.Equal(t, Weekday, cal.Classify(sc.Day(2019, 7, 4)))
+
+	assert.NoError(t, ioutil.WriteFile(path, []byte(`{"holidays": [{"date": "4th July"}]}`), 0644))
+	_, err = Load(path, sc)
+	assert.Error(t, err)
+}
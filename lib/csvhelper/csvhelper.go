@@ -1,55 +1,204 @@
 package csvhelper
 
 import (
+	"bufio"
 	"fmt"
-	"io/ioutil"
-	"log"
+	"io"
+	"os"
+	"sort"
 	"strings"
 	"transport/lib/progress"
 )
 
-// RemoveNullRows deletes any rows with a "NULL" column value from
-// the given CSV file and returns a byte[] containing only the valid rows
-func RemoveNullRows(path string, columnSeparator string) (rows *[]byte, e error) {
+// DefaultNullValue is the value the MTA archives use for missing columns
+const DefaultNullValue = "NULL"
 
-	// Load file into memory
-	log.Printf("Loading %s into memory to remove null rows...\n", path)
-	data, err := ioutil.ReadFile(path)
-	if err != nil {
-		return nil, fmt.Errorf("csv.RemoveNullRows: opening file '%s' failed due to: %v\n", path, err)
+// Rule determines whether a column is allowed to contain null values
+type Rule int
+
+const (
+	// Required columns cause a row to be rejected if they are null
+	Required Rule = iota
+	// Optional columns may be null
+	Optional
+)
+
+// ReasonColumnCount is the reason given for rejecting rows that don't have
+// the same number of columns as the header
+const ReasonColumnCount = "column count"
+
+// NullReason is the reason given for rejecting a row because `column` was null
+func NullReason(column string) string {
+	return fmt.Sprintf("null %s", column)
+}
+
+// CleanStats counts the rows read, kept and rejected whilst cleaning a file.
+// The header row is not included in any of the counts.
+type CleanStats struct {
+	Read     int
+	Kept     int
+	Rejected map[string]int
+}
+
+// String summarises the stats, listing rejection reasons in alphabetical order
+func (cs CleanStats) String() string {
+	var reasons []string
+	for reason := range cs.Rejected {
+		reasons = append(reasons, reason)
 	}
+	sort.Strings(reasons)
+	summary := fmt.Sprintf("read %d rows, kept %d", cs.Read, cs.Kept)
+	for _, reason := range reasons {
+		summary += fmt.Sprintf(", rejected %d (%s)", cs.Rejected[reason], reason)
+	}
+	return summary
+}
 
-	// Remove any containing null values, in-memory
-	_, validRows := getValidRows(&data, columnSeparator)
+// Cleaner filters the rows of a delimited file one line at a time, so that files
+// far larger than the available memory can be cleaned. The first line is treated
+// as a header, which is always kept and is used to look up the rule for each column.
+type Cleaner struct {
+	separator   string
+	nullValue   string
+	defaultRule Rule
+	rules       map[string]Rule
+}
 
-	// Join all the valid rows into a single byte array, separated by new lines
-	joinedRows := []byte(strings.Join(validRows, "\n"))
+// NewCleaner creates a Cleaner for files with columns separated by `separator`.
+// By default every column is Required and "NULL" is treated as a null value.
+// Example Usage:
+// c := csvhelper.NewCleaner("\t", csvhelper.DefaultRuleOption(csvhelper.Optional), csvhelper.ColumnRuleOption("vehicle_id", csvhelper.Required))
+func NewCleaner(separator string, options ...func(*Cleaner)) *Cleaner {
+	c := &Cleaner{
+		separator:   separator,
+		nullValue:   DefaultNullValue,
+		defaultRule: Required,
+		rules:       map[string]Rule{},
+	}
+	for _, option := range options {
+		option(c)
+	}
+	return c
+}
 
-	return &joinedRows, nil
+// NullValueOption returns a function that can be passed to NewCleaner to
+// change the value that is treated as null
+func NullValueOption(nullValue string) func(*Cleaner) {
+	return func(c *Cleaner) {
+		c.nullValue = nullValue
+	}
 }
 
-// getValidRows returns a new slice containing all the CSV rows in 'data' that
-// contained no NULL values in any column
-func getValidRows(data *[]byte, columnSeparator string) (nullRowCount int, validRowsSlice []string) {
-	rows := strings.Split(string(*data), "\n")
-	nullRows := 0
-	var validRows []string
+// DefaultRuleOption returns a function that can be passed to NewCleaner to set
+// the rule for every column that doesn't have its own rule
+func DefaultRuleOption(rule Rule) func(*Cleaner) {
+	return func(c *Cleaner) {
+		c.defaultRule = rule
+	}
+}
 
-	for i, row := range rows {
-		progress.PrintAtIntervals(i, len(rows), "Removing null rows")
-		validRow := true
-		columns := strings.Split(row, columnSeparator)
-		for _, col := range columns {
-			if col == "NULL" {
-				validRow = false
-				nullRows++
-				break
+// ColumnRuleOption returns a function that can be passed to NewCleaner to set
+// the rule for each of the named columns
+func ColumnRuleOption(rule Rule, columns ...string) func(*Cleaner) {
+	return func(c *Cleaner) {
+		for _, column := range columns {
+			c.rules[column] = rule
+		}
+	}
+}
+
+// Clean reads rows from `r`, writing the header and every valid row to `w`
+func (c *Cleaner) Clean(r io.Reader, w io.Writer) (CleanStats, error) {
+	return c.clean(r, w, nil)
+}
+
+// CleanFile writes the header and valid rows of the file at `src` into a new file at `dst`
+func (c *Cleaner) CleanFile(src string, dst string) (CleanStats, error) {
+	in, err := os.Open(src)
+	if err != nil {
+		return CleanStats{}, fmt.Errorf("csvhelper.CleanFile: opening file '%s' failed due to: %v", src, err)
+	}
+	defer in.Close()
+	info, err := in.Stat()
+	if err != nil {
+		return CleanStats{}, fmt.Errorf("csvhelper.CleanFile: reading size of '%s' failed due to: %v", src, err)
+	}
+	out, err := os.Create(dst)
+	if err != nil {
+		return CleanStats{}, fmt.Errorf("csvhelper.CleanFile: creating file '%s' failed due to: %v", dst, err)
+	}
+	// Progress through the file is tracked in bytes, as the number of rows isn't known upfront
+	tracker := progress.NewTracker(fmt.Sprintf("Cleaning %s (bytes)", src), int(info.Size()))
+	stats, err := c.clean(in, out, tracker)
+	if closeErr := out.Close(); err == nil && closeErr != nil {
+		err = fmt.Errorf("csvhelper.CleanFile: closing file '%s' failed due to: %v", dst, closeErr)
+	}
+	return stats, err
+}
+
+func (c *Cleaner) clean(r io.Reader, w io.Writer, tracker *progress.Tracker) (CleanStats, error) {
+	stats := CleanStats{Rejected: map[string]int{}}
+	reader, writer := bufio.NewReader(r), bufio.NewWriter(w)
+	var header []string
+	for {
+		line, readErr := reader.ReadString('\n')
+		if readErr != nil && readErr != io.EOF {
+			return stats, fmt.Errorf("csvhelper.Clean: error whilst reading row %d: %s", stats.Read+1, readErr)
+		}
+		if tracker != nil {
+			tracker.Add(len(line))
+		}
+		row := strings.TrimRight(line, "\r\n")
+		if row != "" {
+			keep := true
+			if header == nil {
+				header = strings.Split(row, c.separator)
+			} else {
+				stats.Read++
+				if reason, valid := c.validate(header, row); valid {
+					stats.Kept++
+				} else {
+					stats.Rejected[reason]++
+					keep = false
+				}
+			}
+			if keep {
+				if _, err := writer.WriteString(row + "\n"); err != nil {
+					return stats, fmt.Errorf("csvhelper.Clean: error whilst writing row: %s", err)
+				}
 			}
 		}
-		if validRow {
-			validRows = append(validRows, row)
+		if readErr == io.EOF {
+			break
+		}
+	}
+	if tracker != nil {
+		tracker.Done()
+	}
+	if err := writer.Flush(); err != nil {
+		return stats, fmt.Errorf("csvhelper.Clean: error whilst writing rows: %s", err)
+	}
+	return stats, nil
+}
+
+// validate checks a single row against the column rules, returning the reason it
+// should be rejected if it isn't valid
+func (c *Cleaner) validate(header []string, row string) (reason string, valid bool) {
+	columns := strings.Split(row, c.separator)
+	if len(columns) != len(header) {
+		return ReasonColumnCount, false
+	}
+	for i, col := range columns {
+		if col != c.nullValue {
+			continue
+		}
+		rule, found := c.rules[header[i]]
+		if !found {
+			rule = c.defaultRule
+		}
+		if rule == Required {
+			return NullReason(header[i]), false
 		}
 	}
-	log.Printf("Succesfully removed %d null rows...\n", nullRows)
-	return nullRows, validRows
+	return "", true
 }
@@ -0,0 +1,67 @@
+package csvhelper
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const sampleData = "id\tname\tnote\n" +
+	"1\tfirst\tNULL\n" +
+	"2\tNULL\tsecond\n" +
+	"3\tthird\n" +
+	"4\tfourth\tok\r\n" +
+	"\n" +
+	"5\tNULL\tNULL"
+
+func TestCleaner_RejectsNullsInAnyColumnByDefault(t *testing.T) {
+	out := &bytes.Buffer{}
+	stats, err := NewCleaner("\t").Clean(strings.NewReader(sampleData), out)
+	assert.NoError(t, err)
+	assert.Equal(t, "id\tname\tnote\n4\tfourth\tok\n", out.String())
+	assert.Equal(t, 5, stats.Read)
+	assert.Equal(t, 1, stats.Kept)
+	assert.Equal(t, map[string]int{
+		NullReason("note"): 1,
+		NullReason("name"): 2,
+		ReasonColumnCount:  1,
+	}, stats.Rejected)
+}
+
+func TestCleaner_ColumnRules(t *testing.T) {
+	out := &bytes.Buffer{}
+	cleaner := NewCleaner("\t", ColumnRuleOption(Optional, "note"))
+	stats, err := cleaner.Clean(strings.NewReader(sampleData), out)
+	assert.NoError(t, err)
+	assert.Equal(t, "id\tname\tnote\n1\tfirst\tNULL\n4\tfourth\tok\n", out.String())
+	assert.Equal(t, 2, stats.Kept)
+	assert.Equal(t, "read 5 rows, kept 2, rejected 1 (column count), rejected 2 (null name)", stats.String())
+}
+
+func TestCleaner_NullValue(t *testing.T) {
+	out := &bytes.Buffer{}
+	data := "a,b\n1,\n2,NULL\n"
+	stats, err := NewCleaner(",", NullValueOption("")).Clean(strings.NewReader(data), out)
+	assert.NoError(t, err)
+	assert.Equal(t, "a,b\n2,NULL\n", out.String())
+	assert.Equal(t, map[string]int{NullReason("b"): 1}, stats.Rejected)
+}
+
+func TestCleaner_CleanFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "csvhelper")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	src, dst := filepath.Join(dir, "in.tsv"), filepath.Join(dir, "out.tsv")
+	assert.NoError(t, ioutil.WriteFile(src, []byte(sampleData), 0644))
+	stats, err := NewCleaner("\t", DefaultRuleOption(Optional)).CleanFile(src, dst)
+	assert.NoError(t, err)
+	assert.Equal(t, 4, stats.Kept)
+	cleaned, err := ioutil.ReadFile(dst)
+	assert.NoError(t, err)
+	assert.Equal(t, "id\tname\tnote\n1\tfirst\tNULL\n2\tNULL\tsecond\n4\tfourth\tok\n5\tNULL\tNULL\n", string(cleaned))
+}
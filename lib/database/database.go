@@ -7,6 +7,7 @@ import (
 	"strings"
 	"time"
 	"transport/lib/iohelper"
+	"transport/lib/metrics"
 	"transport/lib/progress"
 
 	"github.com/lib/pq"
@@ -25,6 +26,13 @@ const (
 
 var TimeLoc, _ = time.LoadLocation("America/New_York")
 
+// Metrics recorded whilst inserting into the DB, labelled with the name of the table
+var (
+	RowsInserted   = metrics.NewCounter("db_rows_inserted_total", "Rows committed to the DB, by table.", "table")
+	RowsRejected   = metrics.NewCounter("db_rows_rejected_total", "Rows that failed to be inserted into the DB, by table.", "table")
+	commitDuration = metrics.NewHistogram("db_commit_duration_seconds", "Time taken to flush and commit insertion transactions.", nil)
+)
+
 // DBTable type holds name and column list for each table in the DB
 type DBTable struct {
 	Name    string
@@ -118,6 +126,50 @@ var (
 	}
 )
 
+// StopEventTable contains the times that vehicles arrived at and departed from each stop
+// on their trips. Interpolated events were estimated from the reports either side of
+// the stop, as the vehicle didn't report while it was at the stop.
+var StopEventTable = DBTable{
+	Name: "stop_event",
+	Columns: []string{
+		"route_id", "direction_id",
+		"trip_id", "vehicle_ref",
+		"stop_id",
+		"arrival_time", "departure_time",
+		"dwell_seconds", "interpolated",
+	},
+}
+
+// HeadwayTable contains the time between consecutive vehicles on a route arriving at each
+// stop, and whether the vehicles were bunched or there was a gap in service. livedataloader
+// stores headways as they happen, and the labeller replaces them with headways computed from
+// the stop_event table once each service day has been labelled.
+var HeadwayTable = DBTable{
+	Name: "headway",
+	Columns: []string{
+		"route_id", "direction_id",
+		"stop_id",
+		"vehicle_ref", "lead_vehicle_ref",
+		"arrival_time", "lead_arrival_time",
+		"headway_seconds", "reference_seconds",
+		"status",
+	},
+}
+
+// ServiceAlertTable contains the disruptions published by the MTA, along with the routes
+// and directions they affect. The n-th entry of affected_direction_refs is the direction
+// of the n-th entry of affected_line_refs.
+var ServiceAlertTable = DBTable{
+	Name: "service_alert",
+	Columns: []string{
+		"situation_number",
+		"severity", "summary", "description",
+		"creation_time",
+		"publication_start", "publication_end",
+		"affected_line_refs", "affected_direction_refs",
+	},
+}
+
 var NotificationEvalTable = DBTable{
 	Name: "notification_eval",
 	Columns: []string{
@@ -170,18 +222,39 @@ func CreateTransaction(db *sql.DB) *sql.Tx {
 // CommitTransaction runs a blank .Exec() call to flush the given statement,
 // before closing the statement and committing the transaction.
 func CommitTransaction(stmt *sql.Stmt, transaction *sql.Tx) {
+	for _, err := range flushAndCommit(stmt, transaction) {
+		log.Println(err)
+	}
+}
+
+// FlushAndCommit does the same as CommitTransaction, but returns the first error
+// that occurred instead of only logging it
+func FlushAndCommit(stmt *sql.Stmt, transaction *sql.Tx) error {
+	errs := flushAndCommit(stmt, transaction)
+	if len(errs) > 0 {
+		return errs[0]
+	}
+	return nil
+}
+
+// flushAndCommit flushes and closes the statement, then commits the transaction. Every
+// step is attempted even if an earlier one fails, and all of the errors are returned.
+func flushAndCommit(stmt *sql.Stmt, transaction *sql.Tx) []error {
+	defer commitDuration.ObserveSince(time.Now())
+	var errs []error
 	_, err := stmt.Exec()
 	if err != nil {
-		log.Printf("error whilst flushing statement: %v\n", err)
+		errs = append(errs, fmt.Errorf("error whilst flushing statement: %v", err))
 	}
 	err = stmt.Close()
 	if err != nil {
-		log.Printf("error whilst closing insertion statement: %v\n", err)
+		errs = append(errs, fmt.Errorf("error whilst closing insertion statement: %v", err))
 	}
 	err = transaction.Commit()
 	if err != nil {
-		log.Printf("error whilst committing insertion transaction to db: %v\n", err)
+		errs = append(errs, fmt.Errorf("error whilst committing insertion transaction to db: %v", err))
 	}
+	return errs
 }
 
 // Store sends an array of entries to the specified database table.
@@ -200,16 +273,32 @@ func Store(table DBTable, columnExtractor func(interface{}) []interface{}, entri
 	}
 
 	// Copy all entries into the DB (as part of the transaction)
-	CopyIntoDB(table, columnExtractor, transaction, entriesSlice)
+	rows := CopyIntoDB(table, columnExtractor, transaction, entriesSlice)
 
 	// Commit transaction
+	start := time.Now()
 	err = transaction.Commit()
+	RecordCommit(table, rows, err)
 	if err != nil {
 		log.Fatal(err)
 	}
+	commitDuration.ObserveSince(start)
 }
 
-func CopyIntoDB(table DBTable, columnExtractor func(interface{}) []interface{}, transaction *sql.Tx, entries []interface{}) {
+// RecordCommit updates the row metrics for the table once the transaction holding `rows`
+// rows has been committed, or has failed to commit with err
+func RecordCommit(table DBTable, rows int, err error) {
+	if err != nil {
+		RowsRejected.Add(float64(rows), table.Name)
+	} else {
+		RowsInserted.Add(float64(rows), table.Name)
+	}
+}
+
+// CopyIntoDB adds the entries to a copy statement for the table within the transaction,
+// and returns how many rows were added. Rows the statement refuses are counted as rejected;
+// the rest should be passed to RecordCommit once the transaction has been committed.
+func CopyIntoDB(table DBTable, columnExtractor func(interface{}) []interface{}, transaction *sql.Tx, entries []interface{}) int {
 	// Create Copy statement for all columns of the table
 	statement, err := transaction.Prepare(pq.CopyIn(table.Name, table.Columns...))
 	if err != nil {
@@ -217,19 +306,26 @@ func CopyIntoDB(table DBTable, columnExtractor func(interface{}) []interface{},
 	}
 
 	// Execute Copy statement for each ArrivalEntry
-	for i, entry := range entries {
-		progress.PrintAtIntervals(i, len(entries), "Inserting into DB:")
+	tracker := progress.NewTracker(fmt.Sprintf("Inserting into %s", table.Name), len(entries))
+	rows := 0
+	for _, entry := range entries {
 		_, err := statement.Exec(columnExtractor(entry)...)
 		if err != nil {
 			log.Printf("database.CopyIntoDB: error whilst executing copy statement: %s\n", err)
+			RowsRejected.Inc(table.Name)
+		} else {
+			rows++
 		}
+		tracker.Increment()
 	}
+	tracker.Done()
 
 	// Close statement
 	err = statement.Close()
 	if err != nil {
 		log.Fatal(err)
 	}
+	return rows
 }
 
 // Fetch all raw rows from given table
@@ -19,8 +19,10 @@ func Equal(a time.Time, b time.Time) bool {
 // `a` and `b`. `b` should be the date that occurs *after* `a`.
 // This function is *inclusive* of `a` and `b`:
 // e.g. DaysBetween(1st April, 4th April) = 4 days (1st April, 2nd April, 3rd April, 4th April)
+// Only the calendar dates of `a` and `b` are used, so days that are shorter or longer
+// than 24 hours due to DST changes are still counted correctly.
 func DaysBetween(a time.Time, b time.Time) int {
-	return int(b.Sub(a).Hours()/HoursInDay) + 1
+	return DefaultServiceCalendar.DayOfDate(a).DaysUntil(DefaultServiceCalendar.DayOfDate(b)) + 1
 }
 
 // SetHour returns a Time with the same year, month and date,
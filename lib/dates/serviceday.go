@@ -0,0 +1,180 @@
+package dates
+
+import (
+	"fmt"
+	"time"
+	"transport/lib/database"
+	"transport/lib/iohelper"
+)
+
+// DefaultServiceDayBoundary is the time of day at which one service day ends and
+// the next begins. Buses running after midnight belong to the previous day's service.
+const DefaultServiceDayBoundary = 4 * time.Hour
+
+// Environment variables that can be used to override the default ServiceCalendar,
+// e.g. SERVICE_DAY_BOUNDARY=3h30m SERVICE_DAY_TIMEZONE=America/Chicago
+const (
+	BoundaryEnvVar = "SERVICE_DAY_BOUNDARY"
+	TimezoneEnvVar = "SERVICE_DAY_TIMEZONE"
+)
+
+// ServiceCalendar defines when service days start and end: every service day
+// begins at `Boundary` after midnight (local time) in `Location`
+type ServiceCalendar struct {
+	Boundary time.Duration
+	Location *time.Location
+}
+
+// DefaultServiceCalendar starts each service day at 4am New York time
+var DefaultServiceCalendar = ServiceCalendar{Boundary: DefaultServiceDayBoundary, Location: database.TimeLoc}
+
+// ServiceCalendarFromEnv returns the DefaultServiceCalendar, with its boundary and timezone
+// overridden by the SERVICE_DAY_BOUNDARY and SERVICE_DAY_TIMEZONE environment variables (if set)
+func ServiceCalendarFromEnv() (ServiceCalendar, error) {
+	boundary, err := time.ParseDuration(iohelper.GetEnvOrDefault(BoundaryEnvVar, DefaultServiceDayBoundary.String()))
+	if err != nil {
+		return ServiceCalendar{}, fmt.Errorf("dates.ServiceCalendarFromEnv: invalid %s: %s", BoundaryEnvVar, err)
+	}
+	loc, err := time.LoadLocation(iohelper.GetEnvOrDefault(TimezoneEnvVar, DefaultServiceCalendar.Location.String()))
+	if err != nil {
+		return ServiceCalendar{}, fmt.Errorf("dates.ServiceCalendarFromEnv: invalid %s: %s", TimezoneEnvVar, err)
+	}
+	if boundary < 0 || boundary >= HoursInDay*time.Hour {
+		return ServiceCalendar{}, fmt.Errorf("dates.ServiceCalendarFromEnv: %s must be between 0 and 24h, got %s", BoundaryEnvVar, boundary)
+	}
+	return ServiceCalendar{Boundary: boundary, Location: loc}, nil
+}
+
+// Day returns the service day for the given calendar date
+func (sc ServiceCalendar) Day(year int, month time.Month, day int) ServiceDay {
+	// Normalise dates such as 32nd January into 1st February
+	d := time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+	return ServiceDay{year: d.Year(), month: d.Month(), day: d.Day(), calendar: sc}
+}
+
+// DayOf returns the service day that the instant `t` belongs to, e.g. with a 4am
+// boundary, 2am on the 2nd of June belongs to the service day of the 1st of June
+func (sc ServiceCalendar) DayOf(t time.Time) ServiceDay {
+	local := t.In(sc.Location)
+	// Any time before the boundary (on the wall clock) belongs to the previous calendar date
+	if wallClock(local) < sc.Boundary {
+		local = local.AddDate(0, 0, -1)
+	}
+	return sc.Day(local.Year(), local.Month(), local.Day())
+}
+
+// DayOfDate returns the service day with the same calendar date as `t`, ignoring its time
+// of day. Useful for dates parsed from input such as CLI arguments (e.g. "2019-06-01").
+func (sc ServiceCalendar) DayOfDate(t time.Time) ServiceDay {
+	return sc.Day(t.Year(), t.Month(), t.Day())
+}
+
+// ParseDay parses a date in the database.DateFormat (YYYY-MM-DD) into a service day
+func (sc ServiceCalendar) ParseDay(s string) (ServiceDay, error) {
+	t, err := time.Parse(database.DateFormat, s)
+	if err != nil {
+		return ServiceDay{}, fmt.Errorf("dates.ParseDay: %s is not a valid date, make sure you use the format YYYY-MM-DD", s)
+	}
+	return sc.DayOfDate(t), nil
+}
+
+// ServiceDay is a single day of service, running from the calendar's boundary time on
+// its date, up to (but not including) the boundary time on the following date
+type ServiceDay struct {
+	year     int
+	month    time.Month
+	day      int
+	calendar ServiceCalendar
+}
+
+// Date returns the year, month and day of the service day
+func (sd ServiceDay) Date() (year int, month time.Month, day int) {
+	return sd.year, sd.month, sd.day
+}
+
+// Weekday returns the day of the week the service day starts on
+func (sd ServiceDay) Weekday() time.Weekday {
+	return sd.civil().Weekday()
+}
+
+// Start returns the first instant of the service day
+func (sd ServiceDay) Start() time.Time {
+	// Build the boundary from wall clock fields, so that the service day still starts at
+	// (e.g.) 4am local time on days where the clocks change between midnight and 4am
+	b := sd.calendar.Boundary
+	hours, minutes, seconds := int(b/time.Hour), int(b%time.Hour/time.Minute), int(b%time.Minute/time.Second)
+	return time.Date(sd.year, sd.month, sd.day, hours, minutes, seconds, 0, sd.calendar.Location)
+}
+
+// End returns the first instant *after* the service day, which is the start of the next
+// service day. Together, Start and End form the half-open interval [Start, End).
+func (sd ServiceDay) End() time.Time {
+	return sd.Next().Start()
+}
+
+// Bounds returns the half-open interval [start, end) covered by the service day
+func (sd ServiceDay) Bounds() (start time.Time, end time.Time) {
+	return sd.Start(), sd.End()
+}
+
+// Contains returns true if the instant `t` falls within the service day
+func (sd ServiceDay) Contains(t time.Time) bool {
+	return !t.Before(sd.Start()) && t.Before(sd.End())
+}
+
+// AddDays returns the service day `n` days after this one (or before, if n is negative)
+func (sd ServiceDay) AddDays(n int) ServiceDay {
+	return sd.calendar.Day(sd.year, sd.month, sd.day+n)
+}
+
+// Next returns the following service day
+func (sd ServiceDay) Next() ServiceDay {
+	return sd.AddDays(1)
+}
+
+// Prev returns the previous service day
+func (sd ServiceDay) Prev() ServiceDay {
+	return sd.AddDays(-1)
+}
+
+// Equal returns true if both service days are on the same date
+func (sd ServiceDay) Equal(other ServiceDay) bool {
+	return sd.year == other.year && sd.month == other.month && sd.day == other.day
+}
+
+// Before returns true if the service day is on an earlier date than `other`
+func (sd ServiceDay) Before(other ServiceDay) bool {
+	return sd.civil().Before(other.civil())
+}
+
+// DaysUntil returns the number of days from this service day to `other`, e.g.
+// the 1st of June is 3 days until the 4th of June
+func (sd ServiceDay) DaysUntil(other ServiceDay) int {
+	return int(other.civil().Sub(sd.civil()).Hours() / HoursInDay)
+}
+
+// String formats the service day using the database.DateFormat (YYYY-MM-DD)
+func (sd ServiceDay) String() string {
+	return sd.civil().Format(database.DateFormat)
+}
+
+// civil returns the date of the service day at midnight UTC, which is free of DST
+// changes and so can be used for date arithmetic
+func (sd ServiceDay) civil() time.Time {
+	return time.Date(sd.year, sd.month, sd.day, 0, 0, 0, 0, time.UTC)
+}
+
+// Range returns every service day from `first` to `last`, inclusive of both.
+// If `last` is before `first`, the range is empty.
+func Range(first ServiceDay, last ServiceDay) []ServiceDay {
+	var days []ServiceDay
+	for d := first; !last.Before(d); d = d.Next() {
+		days = append(days, d)
+	}
+	return days
+}
+
+// wallClock returns the local time of day shown on a clock at `t`, as a duration since midnight
+func wallClock(t time.Time) time.Duration {
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute + time.Duration(t.Second())*time.Second
+}
@@ -0,0 +1,78 @@
+package dates
+
+import (
+	"testing"
+	"time"
+	"transport/lib/database"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var ny = database.TimeLoc
+
+func TestServiceCalendar_DayOf(t *testing.T) {
+	cal := DefaultServiceCalendar
+	inputs := []time.Time{
+		time.Date(2019, 6, 2, 3, 59, 59, 0, ny),
+		time.Date(2019, 6, 2, 4, 0, 0, 0, ny),
+		time.Date(2019, 6, 2, 23, 0, 0, 0, ny),
+		// 02:30 in New York, expressed in UTC
+		time.Date(2019, 6, 2, 6, 30, 0, 0, time.UTC),
+	}
+	expected := []string{"2019-06-01", "2019-06-02", "2019-06-02", "2019-06-01"}
+	for i, input := range inputs {
+		assert.Equal(t, expected[i], cal.DayOf(input).String())
+	}
+}
+
+func TestServiceDay_Bounds(t *testing.T) {
+	cal := ServiceCalendar{Boundary: 3*time.Hour + 30*time.Minute, Location: ny}
+	start, end := cal.Day(2019, 6, 1).Bounds()
+	assert.Equal(t, time.Date(2019, 6, 1, 3, 30, 0, 0, ny), start)
+	assert.Equal(t, time.Date(2019, 6, 2, 3, 30, 0, 0, ny), end)
+	day := cal.Day(2019, 6, 1)
+	assert.True(t, day.Contains(start))
+	assert.False(t, day.Contains(end))
+	assert.True(t, day.Contains(end.Add(-time.Nanosecond)))
+}
+
+func TestServiceDay_BoundsAcrossDST(t *testing.T) {
+	cal := DefaultServiceCalendar
+	// The clocks go forward at 2am on the 10th of March 2019, so the 9th is only 23 hours long
+	spring := cal.Day(2019, 3, 9)
+	start, end := spring.Bounds()
+	assert.Equal(t, 4, start.Hour())
+	assert.Equal(t, 4, end.Hour())
+	assert.Equal(t, 23*time.Hour, end.Sub(start))
+	// The clocks go back at 2am on the 3rd of November 2019, so the 2nd is 25 hours long
+	autumn := cal.Day(2019, 11, 2)
+	start, end = autumn.Bounds()
+	assert.Equal(t, 25*time.Hour, end.Sub(start))
+}
+
+func TestRange(t *testing.T) {
+	cal := DefaultServiceCalendar
+	days := Range(cal.Day(2019, 2, 27), cal.Day(2019, 3, 2))
+	var actual []string
+	for _, d := range days {
+		actual = append(actual, d.String())
+	}
+	assert.Equal(t, []string{"2019-02-27", "2019-02-28", "2019-03-01", "2019-03-02"}, actual)
+	assert.Empty(t, Range(cal.Day(2019, 3, 2), cal.Day(2019, 3, 1)))
+}
+
+func TestDaysBetween(t *testing.T) {
+	// Spans the start of DST, where one of the days is only 23 hours long
+	a := time.Date(2019, 3, 9, 0, 0, 0, 0, ny)
+	b := time.Date(2019, 3, 12, 0, 0, 0, 0, ny)
+	assert.Equal(t, 4, DaysBetween(a, b))
+	assert.Equal(t, 1, DaysBetween(a, a))
+}
+
+func TestServiceCalendar_ParseDay(t *testing.T) {
+	day, err := DefaultServiceCalendar.ParseDay("2019-06-01")
+	assert.NoError(t, err)
+	assert.Equal(t, time.Saturday, day.Weekday())
+	_, err = DefaultServiceCalendar.ParseDay("01/06/2019")
+	assert.Error(t, err)
+}
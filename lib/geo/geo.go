@@ -0,0 +1,63 @@
+package geo
+
+import "math"
+
+// EarthRadius is the mean radius of the Earth in metres
+const EarthRadius = 6371000
+
+// Point is a position on the Earth's surface, in degrees
+type Point struct {
+	Latitude  float64
+	Longitude float64
+}
+
+// Haversine returns the great-circle distance in metres between `a` and `b`
+func Haversine(a, b Point) float64 {
+	dLat, dLon := toRadians(b.Latitude-a.Latitude), toRadians(b.Longitude-a.Longitude)
+	h := math.Pow(math.Sin(dLat/2), 2) +
+		math.Cos(toRadians(a.Latitude))*math.Cos(toRadians(b.Latitude))*math.Pow(math.Sin(dLon/2), 2)
+	return 2 * EarthRadius * math.Asin(math.Min(1, math.Sqrt(h)))
+}
+
+// Bearing returns the initial bearing in degrees (in the range [0, 360)) that
+// must be followed from `a` to reach `b` along a great circle, where 0 is north
+// and 90 is east.
+func Bearing(a, b Point) float64 {
+	latA, latB := toRadians(a.Latitude), toRadians(b.Latitude)
+	dLon := toRadians(b.Longitude - a.Longitude)
+	y := math.Sin(dLon) * math.Cos(latB)
+	x := math.Cos(latA)*math.Sin(latB) - math.Sin(latA)*math.Cos(latB)*math.Cos(dLon)
+	return math.Mod(toDegrees(math.Atan2(y, x))+360, 360)
+}
+
+// Interpolate returns the point that is `fraction` of the way along the straight
+// line from `a` to `b`. Only intended for short distances, such as a single
+// segment of a Polyline.
+func Interpolate(a, b Point, fraction float64) Point {
+	return Point{
+		Latitude:  a.Latitude + fraction*(b.Latitude-a.Latitude),
+		Longitude: a.Longitude + fraction*(b.Longitude-a.Longitude),
+	}
+}
+
+// segmentFraction returns how far along the segment a->b (in the range [0, 1])
+// the projection of p lies. Over the length of a single segment, an
+// equirectangular approximation is accurate enough.
+func segmentFraction(a, b, p Point) float64 {
+	cosLat := math.Cos(toRadians(a.Latitude))
+	bx, by := (b.Longitude-a.Longitude)*cosLat, b.Latitude-a.Latitude
+	px, py := (p.Longitude-a.Longitude)*cosLat, p.Latitude-a.Latitude
+	lengthSquared := bx*bx + by*by
+	if lengthSquared == 0 {
+		return 0
+	}
+	return math.Max(0, math.Min(1, (px*bx+py*by)/lengthSquared))
+}
+
+func toRadians(degrees float64) float64 {
+	return degrees * math.Pi / 180
+}
+
+func toDegrees(radians float64) float64 {
+	return radians * 180 / math.Pi
+}
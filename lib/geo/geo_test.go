@@ -0,0 +1,73 @@
+package geo
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	timesSquare  = Point{Latitude: 40.7580, Longitude: -73.9855}
+	empireState  = Point{Latitude: 40.7484, Longitude: -73.9857}
+	statueOfLib  = Point{Latitude: 40.6892, Longitude: -74.0445}
+	northOfStart = Point{Latitude: 40.7680, Longitude: -73.9855}
+)
+
+func TestHaversine(t *testing.T) {
+	assert.Equal(t, 0.0, Haversine(timesSquare, timesSquare))
+	assert.InDelta(t, 1067, Haversine(timesSquare, empireState), 5)
+	assert.InDelta(t, 8239, Haversine(empireState, statueOfLib), 5)
+	// Distance is symmetric
+	assert.InDelta(t, Haversine(empireState, statueOfLib), Haversine(statueOfLib, empireState), 1e-9)
+}
+
+func TestBearing(t *testing.T) {
+	assert.InDelta(t, 0, Bearing(timesSquare, northOfStart), 0.01)
+	assert.InDelta(t, 180, Bearing(northOfStart, timesSquare), 0.01)
+	east := Point{Latitude: 0, Longitude: 1}
+	assert.InDelta(t, 90, Bearing(Point{}, east), 0.01)
+	assert.InDelta(t, 270, Bearing(east, Point{}), 0.01)
+}
+
+func TestPolyline_Project(t *testing.T) {
+	// An L-shaped route: north for ~1112m, then east for ~843m
+	pl := NewPolyline([]Point{{40.70, -73.99}, {40.71, -73.99}, {40.71, -73.98}})
+	assert.InDelta(t, 1955, pl.Length(), 2)
+
+	// Slightly west of the first segment, halfway along it
+	proj := pl.Project(Point{40.705, -73.9901})
+	assert.Equal(t, 0, proj.Segment)
+	assert.InDelta(t, 556, proj.DistanceAlong, 1)
+	assert.InDelta(t, 8.4, proj.Offset, 0.5)
+
+	// Just north of the second segment
+	proj = pl.Project(Point{40.7101, -73.985})
+	assert.Equal(t, 1, proj.Segment)
+	assert.InDelta(t, 1112+421, proj.DistanceAlong, 2)
+
+	// Points beyond the end are clamped to the final point
+	proj = pl.Project(Point{40.71, -73.97})
+	assert.InDelta(t, pl.Length(), proj.DistanceAlong, 1e-6)
+}
+
+func TestPolyline_StopDistances(t *testing.T) {
+	// A route that runs north and then returns south along the same road
+	pl := NewPolyline([]Point{{40.70, -73.99}, {40.71, -73.99}, {40.70, -73.99}})
+	stops := []Point{{40.701, -73.99}, {40.709, -73.99}, {40.709, -73.99}, {40.701, -73.99}}
+	projections := pl.StopDistances(stops)
+	distances := make([]float64, len(projections))
+	for i, p := range projections {
+		distances[i] = p.DistanceAlong
+	}
+	// The stops on the return leg should be placed on the return leg, not the outbound one
+	expected := []float64{111.2, 1000.7, 1000.7, 2112.8}
+	assert.InDeltaSlice(t, expected, distances, 1)
+}
+
+func TestPolyline_PointAt(t *testing.T) {
+	pl := NewPolyline([]Point{{40.70, -73.99}, {40.71, -73.99}})
+	mid := pl.PointAt(pl.Length() / 2)
+	assert.InDelta(t, 40.705, mid.Latitude, 1e-9)
+	assert.Equal(t, pl.Points[0], pl.PointAt(-10))
+	assert.Equal(t, pl.Points[1], pl.PointAt(pl.Length()+10))
+}
@@ -0,0 +1,117 @@
+package geo
+
+import "math"
+
+// Polyline is an ordered sequence of points describing a path, such as
+// the shape of a bus route in a single direction.
+type Polyline struct {
+	Points []Point
+	// Distance in metres from the first point to each point in Points
+	cumulative []float64
+}
+
+// Projection describes the closest position on a Polyline to some other point
+type Projection struct {
+	// The closest position on the polyline
+	Point Point
+	// Distance in metres along the polyline from its first point to Point
+	DistanceAlong float64
+	// Distance in metres between the original point and Point
+	Offset float64
+	// Index of the segment (Points[Segment] -> Points[Segment+1]) that Point lies on
+	Segment int
+}
+
+// NewPolyline creates a Polyline from the given points
+func NewPolyline(points []Point) Polyline {
+	cumulative := make([]float64, len(points))
+	for i := 1; i < len(points); i++ {
+		cumulative[i] = cumulative[i-1] + Haversine(points[i-1], points[i])
+	}
+	return Polyline{Points: points, cumulative: cumulative}
+}
+
+// Length returns the total length of the polyline in metres
+func (pl Polyline) Length() float64 {
+	if len(pl.cumulative) == 0 {
+		return 0
+	}
+	return pl.cumulative[len(pl.cumulative)-1]
+}
+
+// Project returns the closest position on the polyline to `p`
+func (pl Polyline) Project(p Point) Projection {
+	return pl.ProjectAfter(p, 0)
+}
+
+// ProjectAfter returns the closest position on the polyline to `p` that is at least
+// `minDistanceAlong` metres along the polyline. This prevents points on routes that
+// loop back on themselves from being matched to an earlier part of the route.
+func (pl Polyline) ProjectAfter(p Point, minDistanceAlong float64) Projection {
+	best := Projection{Offset: math.Inf(1)}
+	if len(pl.Points) == 0 {
+		return best
+	}
+	if len(pl.Points) == 1 {
+		return Projection{Point: pl.Points[0], Offset: Haversine(p, pl.Points[0])}
+	}
+	for i := 1; i < len(pl.Points); i++ {
+		segStart, segEnd := pl.cumulative[i-1], pl.cumulative[i]
+		if segEnd < minDistanceAlong {
+			continue
+		}
+		a, b := pl.Points[i-1], pl.Points[i]
+		t := segmentFraction(a, b, p)
+		// Don't allow the projection to fall before minDistanceAlong on the segment that contains it
+		if segEnd > segStart {
+			t = math.Max(t, (minDistanceAlong-segStart)/(segEnd-segStart))
+		}
+		closest := Interpolate(a, b, t)
+		if offset := Haversine(p, closest); offset < best.Offset {
+			best = Projection{
+				Point:         closest,
+				DistanceAlong: segStart + t*(segEnd-segStart),
+				Offset:        offset,
+				Segment:       i - 1,
+			}
+		}
+	}
+	// minDistanceAlong is past the end of the polyline
+	if math.IsInf(best.Offset, 1) {
+		last := pl.Points[len(pl.Points)-1]
+		return Projection{Point: last, DistanceAlong: pl.Length(), Offset: Haversine(p, last), Segment: len(pl.Points) - 2}
+	}
+	return best
+}
+
+// PointAt returns the point `distanceAlong` metres along the polyline. Distances
+// outside the polyline are clamped to its first or last point.
+func (pl Polyline) PointAt(distanceAlong float64) Point {
+	if len(pl.Points) == 0 {
+		return Point{}
+	}
+	for i := 1; i < len(pl.Points); i++ {
+		if pl.cumulative[i] >= distanceAlong {
+			segLength := pl.cumulative[i] - pl.cumulative[i-1]
+			if segLength == 0 {
+				return pl.Points[i]
+			}
+			t := math.Max(0, (distanceAlong-pl.cumulative[i-1])/segLength)
+			return Interpolate(pl.Points[i-1], pl.Points[i], t)
+		}
+	}
+	return pl.Points[len(pl.Points)-1]
+}
+
+// StopDistances projects each of the stops (which must be in the order they are
+// served) onto the polyline and returns the distance along the polyline to each one.
+// Each stop is placed at or after the previous stop, so the result never decreases.
+func (pl Polyline) StopDistances(stops []Point) []Projection {
+	projections := make([]Projection, len(stops))
+	prev := 0.0
+	for i, stop := range stops {
+		projections[i] = pl.ProjectAfter(stop, prev)
+		prev = projections[i].DistanceAlong
+	}
+	return projections
+}
@@ -1,17 +1,25 @@
 module transport/lib
 
+go 1.23
+
 require (
 	github.com/DATA-DOG/go-sqlmock v1.3.3
 	github.com/avast/retry-go v2.2.0+incompatible
-	github.com/google/go-cmp v0.2.0
-	github.com/google/uuid v1.1.1 // indirect
+	github.com/google/go-cmp v0.7.0
 	github.com/lib/pq v1.0.0
-	github.com/orcaman/concurrent-map v0.0.0-20190314100340-2693aad1ed75 // indirect
 	github.com/stretchr/testify v1.3.0
 	github.com/tidwall/gjson v1.2.1
+	google.golang.org/protobuf v1.36.12
+	googlemaps.github.io/maps v0.0.0-20190311183511-743053230cec
+	gopkg.in/guregu/null.v3 v3.4.0
+)
+
+require (
+	github.com/davecgh/go-spew v1.1.0 // indirect
+	github.com/google/uuid v1.1.1 // indirect
+	github.com/orcaman/concurrent-map v0.0.0-20190314100340-2693aad1ed75 // indirect
+	github.com/pmezard/go-difflib v1.0.0 // indirect
 	github.com/tidwall/match v1.0.1 // indirect
 	github.com/tidwall/pretty v0.0.0-20190325153808-1166b9ac2b65 // indirect
 	golang.org/x/time v0.0.0-20190308202827-9d24e82272b4 // indirect
-	googlemaps.github.io/maps v0.0.0-20190311183511-743053230cec
-	gopkg.in/guregu/null.v3 v3.4.0
 )
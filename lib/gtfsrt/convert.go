@@ -0,0 +1,108 @@
+package gtfsrt
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"transport/lib/bus"
+
+	"google.golang.org/protobuf/proto"
+)
+
+// ContentType is the MIME type used to serve GTFS-Realtime feeds. GTFS-Realtime has no
+// registered media type, so (like most producers) feeds are served as generic protobuf.
+// This is the same as busproto.ContentType, so servers can't tell the two apart from an
+// Accept header, and clients need another way (e.g. a format parameter) to ask for GTFS-Realtime.
+const ContentType = "application/x-protobuf"
+
+// Version is the version of the GTFS-Realtime specification that feeds conform to
+const Version = "2.0"
+
+// Occupancy values sent by the MTA (SIRI's OccupancyEnumeration) and their GTFS-Realtime equivalents
+var occupancyStatuses = map[string]VehiclePosition_OccupancyStatus{
+	"seatsAvailable":    VehiclePosition_MANY_SEATS_AVAILABLE,
+	"standingAvailable": VehiclePosition_STANDING_ROOM_ONLY,
+	"full":              VehiclePosition_FULL,
+}
+
+// FromVehicleJourneys converts the journeys into a full VehiclePositions feed, created at `generated`.
+// Journeys without a VehicleRef are given an entity ID based on their position in the feed.
+func FromVehicleJourneys(journeys []bus.VehicleJourney, generated time.Time) *FeedMessage {
+	feed := &FeedMessage{
+		Header: &FeedHeader{
+			GtfsRealtimeVersion: proto.String(Version),
+			Incrementality:      FeedHeader_FULL_DATASET.Enum(),
+		},
+		Entity: make([]*FeedEntity, len(journeys)),
+	}
+	if !generated.IsZero() {
+		feed.Header.Timestamp = proto.Uint64(uint64(generated.Unix()))
+	}
+	for i, vj := range journeys {
+		id := vj.VehicleRef.String
+		if !vj.VehicleRef.Valid {
+			id = strconv.Itoa(i)
+		}
+		feed.Entity[i] = &FeedEntity{Id: proto.String(id), Vehicle: FromVehicleJourney(vj)}
+	}
+	return feed
+}
+
+// FromVehicleJourney converts a bus.VehicleJourney into a VehiclePosition. The MTA prefixes route,
+// trip and stop IDs with their agency (e.g. "MTA NYCT_B41"), which is removed so that the IDs
+// match the MTA's GTFS schedules. Fields that the MTA didn't provide are left unset.
+func FromVehicleJourney(vj bus.VehicleJourney) *VehiclePosition {
+	vp := &VehiclePosition{}
+	trip := &TripDescriptor{}
+	if vj.TripID.Valid {
+		trip.TripId = proto.String(withoutAgency(vj.TripID.String))
+	}
+	if vj.LineRef.Valid {
+		trip.RouteId = proto.String(withoutAgency(vj.LineRef.String))
+	}
+	if vj.DirectionRef.Valid {
+		trip.DirectionId = proto.Uint32(uint32(vj.DirectionRef.Int64))
+	}
+	if trip.TripId != nil || trip.RouteId != nil {
+		vp.Trip = trip
+	}
+	if vj.VehicleRef.Valid {
+		vp.Vehicle = &VehicleDescriptor{Id: proto.String(vj.VehicleRef.String)}
+	}
+	if vj.Latitude.Valid && vj.Longitude.Valid {
+		vp.Position = &Position{Latitude: proto.Float32(float32(vj.Latitude.Float64)), Longitude: proto.Float32(float32(vj.Longitude.Float64))}
+	}
+	if vj.StopPointRef.Valid {
+		vp.StopId = proto.String(withoutAgency(vj.StopPointRef.String))
+		status := VehiclePosition_IN_TRANSIT_TO
+		if vj.DistanceFromStop.Valid && vj.DistanceFromStop.Int64 == 0 {
+			status = VehiclePosition_STOPPED_AT
+		}
+		vp.CurrentStatus = status.Enum()
+	}
+	if vj.Timestamp.Valid {
+		vp.Timestamp = proto.Uint64(uint64(vj.Timestamp.Time.Unix()))
+	}
+	if status, found := occupancyStatuses[vj.Occupancy.String]; found {
+		vp.OccupancyStatus = status.Enum()
+	}
+	return vp
+}
+
+// MarshalVehiclePositions encodes the journeys into a VehiclePositions feed, created at `generated`
+func MarshalVehiclePositions(journeys []bus.VehicleJourney, generated time.Time) ([]byte, error) {
+	data, err := proto.Marshal(FromVehicleJourneys(journeys, generated))
+	if err != nil {
+		return nil, fmt.Errorf("gtfsrt.MarshalVehiclePositions: %s", err)
+	}
+	return data, nil
+}
+
+// withoutAgency removes the agency prefix from an MTA ID, e.g. "MTA NYCT_B41" becomes "B41"
+func withoutAgency(id string) string {
+	if i := strings.Index(id, "_"); i >= 0 {
+		return id[i+1:]
+	}
+	return id
+}
@@ -0,0 +1,68 @@
+package gtfsrt
+
+import (
+	"testing"
+	"time"
+	"transport/lib/bus"
+	"transport/lib/database"
+	"transport/lib/nulltypes"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/proto"
+	"gopkg.in/guregu/null.v3"
+)
+
+func TestMarshalVehiclePositions(t *testing.T) {
+	ts := time.Date(2019, 6, 3, 8, 0, 0, 0, database.TimeLoc)
+	journeys := []bus.VehicleJourney{
+		{
+			LineRef:          null.StringFrom("MTA NYCT_B41"),
+			DirectionRef:     null.IntFrom(1),
+			TripID:           null.StringFrom("MTA NYCT_FB_B9-Weekday-038000_B41_1"),
+			Latitude:         null.FloatFrom(40.7),
+			Longitude:        null.FloatFrom(-73.99),
+			Occupancy:        null.StringFrom("standingAvailable"),
+			VehicleRef:       null.StringFrom("MTA NYCT_9001"),
+			DistanceFromStop: null.IntFrom(0),
+			StopPointRef:     null.StringFrom("MTA_308214"),
+			Timestamp:        nulltypes.TimestampFrom(database.Timestamp{Time: ts}),
+		},
+		// Every field is null
+		{},
+	}
+	data, err := MarshalVehiclePositions(journeys, ts.Add(time.Minute))
+	assert.NoError(t, err)
+	feed := &FeedMessage{}
+	assert.NoError(t, proto.Unmarshal(data, feed))
+
+	assert.Equal(t, Version, feed.GetHeader().GetGtfsRealtimeVersion())
+	assert.Equal(t, FeedHeader_FULL_DATASET, feed.GetHeader().GetIncrementality())
+	assert.Equal(t, uint64(ts.Add(time.Minute).Unix()), feed.GetHeader().GetTimestamp())
+	assert.Len(t, feed.GetEntity(), 2)
+
+	entity := feed.GetEntity()[0]
+	assert.Equal(t, "MTA NYCT_9001", entity.GetId())
+	vp := entity.GetVehicle()
+	assert.Equal(t, "B41", vp.GetTrip().GetRouteId())
+	assert.Equal(t, "FB_B9-Weekday-038000_B41_1", vp.GetTrip().GetTripId())
+	assert.Equal(t, uint32(1), vp.GetTrip().GetDirectionId())
+	assert.Equal(t, "MTA NYCT_9001", vp.GetVehicle().GetId())
+	assert.InDelta(t, 40.7, vp.GetPosition().GetLatitude(), 1e-5)
+	assert.InDelta(t, -73.99, vp.GetPosition().GetLongitude(), 1e-5)
+	assert.Equal(t, "308214", vp.GetStopId())
+	assert.Equal(t, VehiclePosition_STOPPED_AT, vp.GetCurrentStatus())
+	assert.Equal(t, uint64(ts.Unix()), vp.GetTimestamp())
+	assert.Equal(t, VehiclePosition_STANDING_ROOM_ONLY, vp.GetOccupancyStatus())
+
+	empty := feed.GetEntity()[1]
+	assert.Equal(t, "1", empty.GetId())
+	assert.Nil(t, empty.GetVehicle().GetTrip())
+	assert.Nil(t, empty.GetVehicle().GetPosition())
+	assert.Nil(t, empty.GetVehicle().OccupancyStatus)
+}
+
+func TestWithoutAgency(t *testing.T) {
+	assert.Equal(t, "B41", withoutAgency("MTA NYCT_B41"))
+	assert.Equal(t, "308214", withoutAgency("MTA_308214"))
+	assert.Equal(t, "B41", withoutAgency("B41"))
+}
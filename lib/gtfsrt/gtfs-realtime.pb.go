@@ -0,0 +1,869 @@
+// The parts of the GTFS-Realtime schema (https://gtfs.org/realtime/reference/) needed to publish
+// a VehiclePositions feed. Field numbers and defaults match the official gtfs-realtime.proto,
+// so any GTFS-Realtime consumer can decode the messages. Regenerate gtfs-realtime.pb.go after
+// editing this file, from the lib directory:
+// protoc --go_out=. --go_opt=paths=source_relative gtfsrt/gtfs-realtime.proto
+
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.12
+// 	protoc        (unknown)
+// source: gtfsrt/gtfs-realtime.proto
+
+package gtfsrt
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type FeedHeader_Incrementality int32
+
+const (
+	FeedHeader_FULL_DATASET FeedHeader_Incrementality = 0
+	FeedHeader_DIFFERENTIAL FeedHeader_Incrementality = 1
+)
+
+// Enum value maps for FeedHeader_Incrementality.
+var (
+	FeedHeader_Incrementality_name = map[int32]string{
+		0: "FULL_DATASET",
+		1: "DIFFERENTIAL",
+	}
+	FeedHeader_Incrementality_value = map[string]int32{
+		"FULL_DATASET": 0,
+		"DIFFERENTIAL": 1,
+	}
+)
+
+func (x FeedHeader_Incrementality) Enum() *FeedHeader_Incrementality {
+	p := new(FeedHeader_Incrementality)
+	*p = x
+	return p
+}
+
+func (x FeedHeader_Incrementality) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (FeedHeader_Incrementality) Descriptor() protoreflect.EnumDescriptor {
+	return file_gtfsrt_gtfs_realtime_proto_enumTypes[0].Descriptor()
+}
+
+func (FeedHeader_Incrementality) Type() protoreflect.EnumType {
+	return &file_gtfsrt_gtfs_realtime_proto_enumTypes[0]
+}
+
+func (x FeedHeader_Incrementality) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Do not use.
+func (x *FeedHeader_Incrementality) UnmarshalJSON(b []byte) error {
+	num, err := protoimpl.X.UnmarshalJSONEnum(x.Descriptor(), b)
+	if err != nil {
+		return err
+	}
+	*x = FeedHeader_Incrementality(num)
+	return nil
+}
+
+// Deprecated: Use FeedHeader_Incrementality.Descriptor instead.
+func (FeedHeader_Incrementality) EnumDescriptor() ([]byte, []int) {
+	return file_gtfsrt_gtfs_realtime_proto_rawDescGZIP(), []int{1, 0}
+}
+
+type VehiclePosition_VehicleStopStatus int32
+
+const (
+	VehiclePosition_INCOMING_AT   VehiclePosition_VehicleStopStatus = 0
+	VehiclePosition_STOPPED_AT    VehiclePosition_VehicleStopStatus = 1
+	VehiclePosition_IN_TRANSIT_TO VehiclePosition_VehicleStopStatus = 2
+)
+
+// Enum value maps for VehiclePosition_VehicleStopStatus.
+var (
+	VehiclePosition_VehicleStopStatus_name = map[int32]string{
+		0: "INCOMING_AT",
+		1: "STOPPED_AT",
+		2: "IN_TRANSIT_TO",
+	}
+	VehiclePosition_VehicleStopStatus_value = map[string]int32{
+		"INCOMING_AT":   0,
+		"STOPPED_AT":    1,
+		"IN_TRANSIT_TO": 2,
+	}
+)
+
+func (x VehiclePosition_VehicleStopStatus) Enum() *VehiclePosition_VehicleStopStatus {
+	p := new(VehiclePosition_VehicleStopStatus)
+	*p = x
+	return p
+}
+
+func (x VehiclePosition_VehicleStopStatus) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (VehiclePosition_VehicleStopStatus) Descriptor() protoreflect.EnumDescriptor {
+	return file_gtfsrt_gtfs_realtime_proto_enumTypes[1].Descriptor()
+}
+
+func (VehiclePosition_VehicleStopStatus) Type() protoreflect.EnumType {
+	return &file_gtfsrt_gtfs_realtime_proto_enumTypes[1]
+}
+
+func (x VehiclePosition_VehicleStopStatus) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Do not use.
+func (x *VehiclePosition_VehicleStopStatus) UnmarshalJSON(b []byte) error {
+	num, err := protoimpl.X.UnmarshalJSONEnum(x.Descriptor(), b)
+	if err != nil {
+		return err
+	}
+	*x = VehiclePosition_VehicleStopStatus(num)
+	return nil
+}
+
+// Deprecated: Use VehiclePosition_VehicleStopStatus.Descriptor instead.
+func (VehiclePosition_VehicleStopStatus) EnumDescriptor() ([]byte, []int) {
+	return file_gtfsrt_gtfs_realtime_proto_rawDescGZIP(), []int{3, 0}
+}
+
+type VehiclePosition_OccupancyStatus int32
+
+const (
+	VehiclePosition_EMPTY                      VehiclePosition_OccupancyStatus = 0
+	VehiclePosition_MANY_SEATS_AVAILABLE       VehiclePosition_OccupancyStatus = 1
+	VehiclePosition_FEW_SEATS_AVAILABLE        VehiclePosition_OccupancyStatus = 2
+	VehiclePosition_STANDING_ROOM_ONLY         VehiclePosition_OccupancyStatus = 3
+	VehiclePosition_CRUSHED_STANDING_ROOM_ONLY VehiclePosition_OccupancyStatus = 4
+	VehiclePosition_FULL                       VehiclePosition_OccupancyStatus = 5
+	VehiclePosition_NOT_ACCEPTING_PASSENGERS   VehiclePosition_OccupancyStatus = 6
+	VehiclePosition_NO_DATA_AVAILABLE          VehiclePosition_OccupancyStatus = 7
+	VehiclePosition_NOT_BOARDABLE              VehiclePosition_OccupancyStatus = 8
+)
+
+// Enum value maps for VehiclePosition_OccupancyStatus.
+var (
+	VehiclePosition_OccupancyStatus_name = map[int32]string{
+		0: "EMPTY",
+		1: "MANY_SEATS_AVAILABLE",
+		2: "FEW_SEATS_AVAILABLE",
+		3: "STANDING_ROOM_ONLY",
+		4: "CRUSHED_STANDING_ROOM_ONLY",
+		5: "FULL",
+		6: "NOT_ACCEPTING_PASSENGERS",
+		7: "NO_DATA_AVAILABLE",
+		8: "NOT_BOARDABLE",
+	}
+	VehiclePosition_OccupancyStatus_value = map[string]int32{
+		"EMPTY":                      0,
+		"MANY_SEATS_AVAILABLE":       1,
+		"FEW_SEATS_AVAILABLE":        2,
+		"STANDING_ROOM_ONLY":         3,
+		"CRUSHED_STANDING_ROOM_ONLY": 4,
+		"FULL":                       5,
+		"NOT_ACCEPTING_PASSENGERS":   6,
+		"NO_DATA_AVAILABLE":          7,
+		"NOT_BOARDABLE":              8,
+	}
+)
+
+func (x VehiclePosition_OccupancyStatus) Enum() *VehiclePosition_OccupancyStatus {
+	p := new(VehiclePosition_OccupancyStatus)
+	*p = x
+	return p
+}
+
+func (x VehiclePosition_OccupancyStatus) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (VehiclePosition_OccupancyStatus) Descriptor() protoreflect.EnumDescriptor {
+	return file_gtfsrt_gtfs_realtime_proto_enumTypes[2].Descriptor()
+}
+
+func (VehiclePosition_OccupancyStatus) Type() protoreflect.EnumType {
+	return &file_gtfsrt_gtfs_realtime_proto_enumTypes[2]
+}
+
+func (x VehiclePosition_OccupancyStatus) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Do not use.
+func (x *VehiclePosition_OccupancyStatus) UnmarshalJSON(b []byte) error {
+	num, err := protoimpl.X.UnmarshalJSONEnum(x.Descriptor(), b)
+	if err != nil {
+		return err
+	}
+	*x = VehiclePosition_OccupancyStatus(num)
+	return nil
+}
+
+// Deprecated: Use VehiclePosition_OccupancyStatus.Descriptor instead.
+func (VehiclePosition_OccupancyStatus) EnumDescriptor() ([]byte, []int) {
+	return file_gtfsrt_gtfs_realtime_proto_rawDescGZIP(), []int{3, 1}
+}
+
+// The contents of a feed message
+type FeedMessage struct {
+	state           protoimpl.MessageState `protogen:"open.v1"`
+	Header          *FeedHeader            `protobuf:"bytes,1,req,name=header" json:"header,omitempty"`
+	Entity          []*FeedEntity          `protobuf:"bytes,2,rep,name=entity" json:"entity,omitempty"`
+	extensionFields protoimpl.ExtensionFields
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
+}
+
+func (x *FeedMessage) Reset() {
+	*x = FeedMessage{}
+	mi := &file_gtfsrt_gtfs_realtime_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *FeedMessage) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*FeedMessage) ProtoMessage() {}
+
+func (x *FeedMessage) ProtoReflect() protoreflect.Message {
+	mi := &file_gtfsrt_gtfs_realtime_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use FeedMessage.ProtoReflect.Descriptor instead.
+func (*FeedMessage) Descriptor() ([]byte, []int) {
+	return file_gtfsrt_gtfs_realtime_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *FeedMessage) GetHeader() *FeedHeader {
+	if x != nil {
+		return x.Header
+	}
+	return nil
+}
+
+func (x *FeedMessage) GetEntity() []*FeedEntity {
+	if x != nil {
+		return x.Entity
+	}
+	return nil
+}
+
+// Metadata about a feed, included in feed messages
+type FeedHeader struct {
+	state               protoimpl.MessageState     `protogen:"open.v1"`
+	GtfsRealtimeVersion *string                    `protobuf:"bytes,1,req,name=gtfs_realtime_version,json=gtfsRealtimeVersion" json:"gtfs_realtime_version,omitempty"`
+	Incrementality      *FeedHeader_Incrementality `protobuf:"varint,2,opt,name=incrementality,enum=transit_realtime.FeedHeader_Incrementality,def=0" json:"incrementality,omitempty"`
+	// When the content of the feed was created, in seconds since the Unix epoch
+	Timestamp       *uint64 `protobuf:"varint,3,opt,name=timestamp" json:"timestamp,omitempty"`
+	extensionFields protoimpl.ExtensionFields
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
+}
+
+// Default values for FeedHeader fields.
+const (
+	Default_FeedHeader_Incrementality = FeedHeader_FULL_DATASET
+)
+
+func (x *FeedHeader) Reset() {
+	*x = FeedHeader{}
+	mi := &file_gtfsrt_gtfs_realtime_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *FeedHeader) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*FeedHeader) ProtoMessage() {}
+
+func (x *FeedHeader) ProtoReflect() protoreflect.Message {
+	mi := &file_gtfsrt_gtfs_realtime_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use FeedHeader.ProtoReflect.Descriptor instead.
+func (*FeedHeader) Descriptor() ([]byte, []int) {
+	return file_gtfsrt_gtfs_realtime_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *FeedHeader) GetGtfsRealtimeVersion() string {
+	if x != nil && x.GtfsRealtimeVersion != nil {
+		return *x.GtfsRealtimeVersion
+	}
+	return ""
+}
+
+func (x *FeedHeader) GetIncrementality() FeedHeader_Incrementality {
+	if x != nil && x.Incrementality != nil {
+		return *x.Incrementality
+	}
+	return Default_FeedHeader_Incrementality
+}
+
+func (x *FeedHeader) GetTimestamp() uint64 {
+	if x != nil && x.Timestamp != nil {
+		return *x.Timestamp
+	}
+	return 0
+}
+
+// A definition (or update) of an entity in the transit feed. Only vehicle
+// positions are published, so trip_update (3) and alert (5) are left out.
+type FeedEntity struct {
+	state           protoimpl.MessageState `protogen:"open.v1"`
+	Id              *string                `protobuf:"bytes,1,req,name=id" json:"id,omitempty"`
+	IsDeleted       *bool                  `protobuf:"varint,2,opt,name=is_deleted,json=isDeleted,def=0" json:"is_deleted,omitempty"`
+	Vehicle         *VehiclePosition       `protobuf:"bytes,4,opt,name=vehicle" json:"vehicle,omitempty"`
+	extensionFields protoimpl.ExtensionFields
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
+}
+
+// Default values for FeedEntity fields.
+const (
+	Default_FeedEntity_IsDeleted = bool(false)
+)
+
+func (x *FeedEntity) Reset() {
+	*x = FeedEntity{}
+	mi := &file_gtfsrt_gtfs_realtime_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *FeedEntity) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*FeedEntity) ProtoMessage() {}
+
+func (x *FeedEntity) ProtoReflect() protoreflect.Message {
+	mi := &file_gtfsrt_gtfs_realtime_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use FeedEntity.ProtoReflect.Descriptor instead.
+func (*FeedEntity) Descriptor() ([]byte, []int) {
+	return file_gtfsrt_gtfs_realtime_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *FeedEntity) GetId() string {
+	if x != nil && x.Id != nil {
+		return *x.Id
+	}
+	return ""
+}
+
+func (x *FeedEntity) GetIsDeleted() bool {
+	if x != nil && x.IsDeleted != nil {
+		return *x.IsDeleted
+	}
+	return Default_FeedEntity_IsDeleted
+}
+
+func (x *FeedEntity) GetVehicle() *VehiclePosition {
+	if x != nil {
+		return x.Vehicle
+	}
+	return nil
+}
+
+// Realtime positioning information for a given vehicle
+type VehiclePosition struct {
+	state               protoimpl.MessageState             `protogen:"open.v1"`
+	Trip                *TripDescriptor                    `protobuf:"bytes,1,opt,name=trip" json:"trip,omitempty"`
+	Vehicle             *VehicleDescriptor                 `protobuf:"bytes,8,opt,name=vehicle" json:"vehicle,omitempty"`
+	Position            *Position                          `protobuf:"bytes,2,opt,name=position" json:"position,omitempty"`
+	CurrentStopSequence *uint32                            `protobuf:"varint,3,opt,name=current_stop_sequence,json=currentStopSequence" json:"current_stop_sequence,omitempty"`
+	StopId              *string                            `protobuf:"bytes,7,opt,name=stop_id,json=stopId" json:"stop_id,omitempty"`
+	CurrentStatus       *VehiclePosition_VehicleStopStatus `protobuf:"varint,4,opt,name=current_status,json=currentStatus,enum=transit_realtime.VehiclePosition_VehicleStopStatus,def=2" json:"current_status,omitempty"`
+	// When the position was measured, in seconds since the Unix epoch
+	Timestamp       *uint64                          `protobuf:"varint,5,opt,name=timestamp" json:"timestamp,omitempty"`
+	OccupancyStatus *VehiclePosition_OccupancyStatus `protobuf:"varint,9,opt,name=occupancy_status,json=occupancyStatus,enum=transit_realtime.VehiclePosition_OccupancyStatus" json:"occupancy_status,omitempty"`
+	extensionFields protoimpl.ExtensionFields
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
+}
+
+// Default values for VehiclePosition fields.
+const (
+	Default_VehiclePosition_CurrentStatus = VehiclePosition_IN_TRANSIT_TO
+)
+
+func (x *VehiclePosition) Reset() {
+	*x = VehiclePosition{}
+	mi := &file_gtfsrt_gtfs_realtime_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *VehiclePosition) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*VehiclePosition) ProtoMessage() {}
+
+func (x *VehiclePosition) ProtoReflect() protoreflect.Message {
+	mi := &file_gtfsrt_gtfs_realtime_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use VehiclePosition.ProtoReflect.Descriptor instead.
+func (*VehiclePosition) Descriptor() ([]byte, []int) {
+	return file_gtfsrt_gtfs_realtime_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *VehiclePosition) GetTrip() *TripDescriptor {
+	if x != nil {
+		return x.Trip
+	}
+	return nil
+}
+
+func (x *VehiclePosition) GetVehicle() *VehicleDescriptor {
+	if x != nil {
+		return x.Vehicle
+	}
+	return nil
+}
+
+func (x *VehiclePosition) GetPosition() *Position {
+	if x != nil {
+		return x.Position
+	}
+	return nil
+}
+
+func (x *VehiclePosition) GetCurrentStopSequence() uint32 {
+	if x != nil && x.CurrentStopSequence != nil {
+		return *x.CurrentStopSequence
+	}
+	return 0
+}
+
+func (x *VehiclePosition) GetStopId() string {
+	if x != nil && x.StopId != nil {
+		return *x.StopId
+	}
+	return ""
+}
+
+func (x *VehiclePosition) GetCurrentStatus() VehiclePosition_VehicleStopStatus {
+	if x != nil && x.CurrentStatus != nil {
+		return *x.CurrentStatus
+	}
+	return Default_VehiclePosition_CurrentStatus
+}
+
+func (x *VehiclePosition) GetTimestamp() uint64 {
+	if x != nil && x.Timestamp != nil {
+		return *x.Timestamp
+	}
+	return 0
+}
+
+func (x *VehiclePosition) GetOccupancyStatus() VehiclePosition_OccupancyStatus {
+	if x != nil && x.OccupancyStatus != nil {
+		return *x.OccupancyStatus
+	}
+	return VehiclePosition_EMPTY
+}
+
+// A geographic position of a vehicle
+type Position struct {
+	state           protoimpl.MessageState `protogen:"open.v1"`
+	Latitude        *float32               `protobuf:"fixed32,1,req,name=latitude" json:"latitude,omitempty"`
+	Longitude       *float32               `protobuf:"fixed32,2,req,name=longitude" json:"longitude,omitempty"`
+	Bearing         *float32               `protobuf:"fixed32,3,opt,name=bearing" json:"bearing,omitempty"`
+	Odometer        *float64               `protobuf:"fixed64,4,opt,name=odometer" json:"odometer,omitempty"`
+	Speed           *float32               `protobuf:"fixed32,5,opt,name=speed" json:"speed,omitempty"`
+	extensionFields protoimpl.ExtensionFields
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
+}
+
+func (x *Position) Reset() {
+	*x = Position{}
+	mi := &file_gtfsrt_gtfs_realtime_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Position) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Position) ProtoMessage() {}
+
+func (x *Position) ProtoReflect() protoreflect.Message {
+	mi := &file_gtfsrt_gtfs_realtime_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Position.ProtoReflect.Descriptor instead.
+func (*Position) Descriptor() ([]byte, []int) {
+	return file_gtfsrt_gtfs_realtime_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *Position) GetLatitude() float32 {
+	if x != nil && x.Latitude != nil {
+		return *x.Latitude
+	}
+	return 0
+}
+
+func (x *Position) GetLongitude() float32 {
+	if x != nil && x.Longitude != nil {
+		return *x.Longitude
+	}
+	return 0
+}
+
+func (x *Position) GetBearing() float32 {
+	if x != nil && x.Bearing != nil {
+		return *x.Bearing
+	}
+	return 0
+}
+
+func (x *Position) GetOdometer() float64 {
+	if x != nil && x.Odometer != nil {
+		return *x.Odometer
+	}
+	return 0
+}
+
+func (x *Position) GetSpeed() float32 {
+	if x != nil && x.Speed != nil {
+		return *x.Speed
+	}
+	return 0
+}
+
+// Identifies an instance of a GTFS trip
+type TripDescriptor struct {
+	state           protoimpl.MessageState `protogen:"open.v1"`
+	TripId          *string                `protobuf:"bytes,1,opt,name=trip_id,json=tripId" json:"trip_id,omitempty"`
+	RouteId         *string                `protobuf:"bytes,5,opt,name=route_id,json=routeId" json:"route_id,omitempty"`
+	DirectionId     *uint32                `protobuf:"varint,6,opt,name=direction_id,json=directionId" json:"direction_id,omitempty"`
+	StartTime       *string                `protobuf:"bytes,2,opt,name=start_time,json=startTime" json:"start_time,omitempty"`
+	StartDate       *string                `protobuf:"bytes,3,opt,name=start_date,json=startDate" json:"start_date,omitempty"`
+	extensionFields protoimpl.ExtensionFields
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
+}
+
+func (x *TripDescriptor) Reset() {
+	*x = TripDescriptor{}
+	mi := &file_gtfsrt_gtfs_realtime_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *TripDescriptor) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TripDescriptor) ProtoMessage() {}
+
+func (x *TripDescriptor) ProtoReflect() protoreflect.Message {
+	mi := &file_gtfsrt_gtfs_realtime_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TripDescriptor.ProtoReflect.Descriptor instead.
+func (*TripDescriptor) Descriptor() ([]byte, []int) {
+	return file_gtfsrt_gtfs_realtime_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *TripDescriptor) GetTripId() string {
+	if x != nil && x.TripId != nil {
+		return *x.TripId
+	}
+	return ""
+}
+
+func (x *TripDescriptor) GetRouteId() string {
+	if x != nil && x.RouteId != nil {
+		return *x.RouteId
+	}
+	return ""
+}
+
+func (x *TripDescriptor) GetDirectionId() uint32 {
+	if x != nil && x.DirectionId != nil {
+		return *x.DirectionId
+	}
+	return 0
+}
+
+func (x *TripDescriptor) GetStartTime() string {
+	if x != nil && x.StartTime != nil {
+		return *x.StartTime
+	}
+	return ""
+}
+
+func (x *TripDescriptor) GetStartDate() string {
+	if x != nil && x.StartDate != nil {
+		return *x.StartDate
+	}
+	return ""
+}
+
+// Identification information for the vehicle performing the trip
+type VehicleDescriptor struct {
+	state           protoimpl.MessageState `protogen:"open.v1"`
+	Id              *string                `protobuf:"bytes,1,opt,name=id" json:"id,omitempty"`
+	Label           *string                `protobuf:"bytes,2,opt,name=label" json:"label,omitempty"`
+	LicensePlate    *string                `protobuf:"bytes,3,opt,name=license_plate,json=licensePlate" json:"license_plate,omitempty"`
+	extensionFields protoimpl.ExtensionFields
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
+}
+
+func (x *VehicleDescriptor) Reset() {
+	*x = VehicleDescriptor{}
+	mi := &file_gtfsrt_gtfs_realtime_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *VehicleDescriptor) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*VehicleDescriptor) ProtoMessage() {}
+
+func (x *VehicleDescriptor) ProtoReflect() protoreflect.Message {
+	mi := &file_gtfsrt_gtfs_realtime_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use VehicleDescriptor.ProtoReflect.Descriptor instead.
+func (*VehicleDescriptor) Descriptor() ([]byte, []int) {
+	return file_gtfsrt_gtfs_realtime_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *VehicleDescriptor) GetId() string {
+	if x != nil && x.Id != nil {
+		return *x.Id
+	}
+	return ""
+}
+
+func (x *VehicleDescriptor) GetLabel() string {
+	if x != nil && x.Label != nil {
+		return *x.Label
+	}
+	return ""
+}
+
+func (x *VehicleDescriptor) GetLicensePlate() string {
+	if x != nil && x.LicensePlate != nil {
+		return *x.LicensePlate
+	}
+	return ""
+}
+
+var File_gtfsrt_gtfs_realtime_proto protoreflect.FileDescriptor
+
+const file_gtfsrt_gtfs_realtime_proto_rawDesc = "" +
+	"\n" +
+	"\x1agtfsrt/gtfs-realtime.proto\x12\x10transit_realtime\"\x81\x01\n" +
+	"\vFeedMessage\x124\n" +
+	"\x06header\x18\x01 \x02(\v2\x1c.transit_realtime.FeedHeaderR\x06header\x124\n" +
+	"\x06entity\x18\x02 \x03(\v2\x1c.transit_realtime.FeedEntityR\x06entity*\x06\b\xe8\a\x10\xd0\x0f\"\xff\x01\n" +
+	"\n" +
+	"FeedHeader\x122\n" +
+	"\x15gtfs_realtime_version\x18\x01 \x02(\tR\x13gtfsRealtimeVersion\x12a\n" +
+	"\x0eincrementality\x18\x02 \x01(\x0e2+.transit_realtime.FeedHeader.Incrementality:\fFULL_DATASETR\x0eincrementality\x12\x1c\n" +
+	"\ttimestamp\x18\x03 \x01(\x04R\ttimestamp\"4\n" +
+	"\x0eIncrementality\x12\x10\n" +
+	"\fFULL_DATASET\x10\x00\x12\x10\n" +
+	"\fDIFFERENTIAL\x10\x01*\x06\b\xe8\a\x10\xd0\x0f\"\x87\x01\n" +
+	"\n" +
+	"FeedEntity\x12\x0e\n" +
+	"\x02id\x18\x01 \x02(\tR\x02id\x12$\n" +
+	"\n" +
+	"is_deleted\x18\x02 \x01(\b:\x05falseR\tisDeleted\x12;\n" +
+	"\avehicle\x18\x04 \x01(\v2!.transit_realtime.VehiclePositionR\avehicle*\x06\b\xe8\a\x10\xd0\x0f\"\x9f\x06\n" +
+	"\x0fVehiclePosition\x124\n" +
+	"\x04trip\x18\x01 \x01(\v2 .transit_realtime.TripDescriptorR\x04trip\x12=\n" +
+	"\avehicle\x18\b \x01(\v2#.transit_realtime.VehicleDescriptorR\avehicle\x126\n" +
+	"\bposition\x18\x02 \x01(\v2\x1a.transit_realtime.PositionR\bposition\x122\n" +
+	"\x15current_stop_sequence\x18\x03 \x01(\rR\x13currentStopSequence\x12\x17\n" +
+	"\astop_id\x18\a \x01(\tR\x06stopId\x12i\n" +
+	"\x0ecurrent_status\x18\x04 \x01(\x0e23.transit_realtime.VehiclePosition.VehicleStopStatus:\rIN_TRANSIT_TOR\rcurrentStatus\x12\x1c\n" +
+	"\ttimestamp\x18\x05 \x01(\x04R\ttimestamp\x12\\\n" +
+	"\x10occupancy_status\x18\t \x01(\x0e21.transit_realtime.VehiclePosition.OccupancyStatusR\x0foccupancyStatus\"G\n" +
+	"\x11VehicleStopStatus\x12\x0f\n" +
+	"\vINCOMING_AT\x10\x00\x12\x0e\n" +
+	"\n" +
+	"STOPPED_AT\x10\x01\x12\x11\n" +
+	"\rIN_TRANSIT_TO\x10\x02\"\xd9\x01\n" +
+	"\x0fOccupancyStatus\x12\t\n" +
+	"\x05EMPTY\x10\x00\x12\x18\n" +
+	"\x14MANY_SEATS_AVAILABLE\x10\x01\x12\x17\n" +
+	"\x13FEW_SEATS_AVAILABLE\x10\x02\x12\x16\n" +
+	"\x12STANDING_ROOM_ONLY\x10\x03\x12\x1e\n" +
+	"\x1aCRUSHED_STANDING_ROOM_ONLY\x10\x04\x12\b\n" +
+	"\x04FULL\x10\x05\x12\x1c\n" +
+	"\x18NOT_ACCEPTING_PASSENGERS\x10\x06\x12\x15\n" +
+	"\x11NO_DATA_AVAILABLE\x10\a\x12\x11\n" +
+	"\rNOT_BOARDABLE\x10\b*\x06\b\xe8\a\x10\xd0\x0f\"\x98\x01\n" +
+	"\bPosition\x12\x1a\n" +
+	"\blatitude\x18\x01 \x02(\x02R\blatitude\x12\x1c\n" +
+	"\tlongitude\x18\x02 \x02(\x02R\tlongitude\x12\x18\n" +
+	"\abearing\x18\x03 \x01(\x02R\abearing\x12\x1a\n" +
+	"\bodometer\x18\x04 \x01(\x01R\bodometer\x12\x14\n" +
+	"\x05speed\x18\x05 \x01(\x02R\x05speed*\x06\b\xe8\a\x10\xd0\x0f\"\xad\x01\n" +
+	"\x0eTripDescriptor\x12\x17\n" +
+	"\atrip_id\x18\x01 \x01(\tR\x06tripId\x12\x19\n" +
+	"\broute_id\x18\x05 \x01(\tR\arouteId\x12!\n" +
+	"\fdirection_id\x18\x06 \x01(\rR\vdirectionId\x12\x1d\n" +
+	"\n" +
+	"start_time\x18\x02 \x01(\tR\tstartTime\x12\x1d\n" +
+	"\n" +
+	"start_date\x18\x03 \x01(\tR\tstartDate*\x06\b\xe8\a\x10\xd0\x0f\"f\n" +
+	"\x11VehicleDescriptor\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x14\n" +
+	"\x05label\x18\x02 \x01(\tR\x05label\x12#\n" +
+	"\rlicense_plate\x18\x03 \x01(\tR\flicensePlate*\x06\b\xe8\a\x10\xd0\x0fB\x16Z\x14transport/lib/gtfsrt"
+
+var (
+	file_gtfsrt_gtfs_realtime_proto_rawDescOnce sync.Once
+	file_gtfsrt_gtfs_realtime_proto_rawDescData []byte
+)
+
+func file_gtfsrt_gtfs_realtime_proto_rawDescGZIP() []byte {
+	file_gtfsrt_gtfs_realtime_proto_rawDescOnce.Do(func() {
+		file_gtfsrt_gtfs_realtime_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_gtfsrt_gtfs_realtime_proto_rawDesc), len(file_gtfsrt_gtfs_realtime_proto_rawDesc)))
+	})
+	return file_gtfsrt_gtfs_realtime_proto_rawDescData
+}
+
+var file_gtfsrt_gtfs_realtime_proto_enumTypes = make([]protoimpl.EnumInfo, 3)
+var file_gtfsrt_gtfs_realtime_proto_msgTypes = make([]protoimpl.MessageInfo, 7)
+var file_gtfsrt_gtfs_realtime_proto_goTypes = []any{
+	(FeedHeader_Incrementality)(0),         // 0: transit_realtime.FeedHeader.Incrementality
+	(VehiclePosition_VehicleStopStatus)(0), // 1: transit_realtime.VehiclePosition.VehicleStopStatus
+	(VehiclePosition_OccupancyStatus)(0),   // 2: transit_realtime.VehiclePosition.OccupancyStatus
+	(*FeedMessage)(nil),                    // 3: transit_realtime.FeedMessage
+	(*FeedHeader)(nil),                     // 4: transit_realtime.FeedHeader
+	(*FeedEntity)(nil),                     // 5: transit_realtime.FeedEntity
+	(*VehiclePosition)(nil),                // 6: transit_realtime.VehiclePosition
+	(*Position)(nil),                       // 7: transit_realtime.Position
+	(*TripDescriptor)(nil),                 // 8: transit_realtime.TripDescriptor
+	(*VehicleDescriptor)(nil),              // 9: transit_realtime.VehicleDescriptor
+}
+var file_gtfsrt_gtfs_realtime_proto_depIdxs = []int32{
+	4, // 0: transit_realtime.FeedMessage.header:type_name -> transit_realtime.FeedHeader
+	5, // 1: transit_realtime.FeedMessage.entity:type_name -> transit_realtime.FeedEntity
+	0, // 2: transit_realtime.FeedHeader.incrementality:type_name -> transit_realtime.FeedHeader.Incrementality
+	6, // 3: transit_realtime.FeedEntity.vehicle:type_name -> transit_realtime.VehiclePosition
+	8, // 4: transit_realtime.VehiclePosition.trip:type_name -> transit_realtime.TripDescriptor
+	9, // 5: transit_realtime.VehiclePosition.vehicle:type_name -> transit_realtime.VehicleDescriptor
+	7, // 6: transit_realtime.VehiclePosition.position:type_name -> transit_realtime.Position
+	1, // 7: transit_realtime.VehiclePosition.current_status:type_name -> transit_realtime.VehiclePosition.VehicleStopStatus
+	2, // 8: transit_realtime.VehiclePosition.occupancy_status:type_name -> transit_realtime.VehiclePosition.OccupancyStatus
+	9, // [9:9] is the sub-list for method output_type
+	9, // [9:9] is the sub-list for method input_type
+	9, // [9:9] is the sub-list for extension type_name
+	9, // [9:9] is the sub-list for extension extendee
+	0, // [0:9] is the sub-list for field type_name
+}
+
+func init() { file_gtfsrt_gtfs_realtime_proto_init() }
+func file_gtfsrt_gtfs_realtime_proto_init() {
+	if File_gtfsrt_gtfs_realtime_proto != nil {
+		return
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_gtfsrt_gtfs_realtime_proto_rawDesc), len(file_gtfsrt_gtfs_realtime_proto_rawDesc)),
+			NumEnums:      3,
+			NumMessages:   7,
+			NumExtensions: 0,
+			NumServices:   0,
+		},
+		GoTypes:           file_gtfsrt_gtfs_realtime_proto_goTypes,
+		DependencyIndexes: file_gtfsrt_gtfs_realtime_proto_depIdxs,
+		EnumInfos:         file_gtfsrt_gtfs_realtime_proto_enumTypes,
+		MessageInfos:      file_gtfsrt_gtfs_realtime_proto_msgTypes,
+	}.Build()
+	File_gtfsrt_gtfs_realtime_proto = out.File
+	file_gtfsrt_gtfs_realtime_proto_goTypes = nil
+	file_gtfsrt_gtfs_realtime_proto_depIdxs = nil
+}
@@ -0,0 +1,32 @@
+package health
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// MaxAgeCheck fails if `last` returns a time more than `maxAge` ago, or the zero time (meaning
+// the event hasn't happened yet). `event` describes what `last` returns, e.g. "successful fetch".
+func MaxAgeCheck(event string, last func() time.Time, maxAge time.Duration) Check {
+	return func() error {
+		t := last()
+		if t.IsZero() {
+			return fmt.Errorf("no %s yet", event)
+		}
+		if age := time.Since(t); age > maxAge {
+			return fmt.Errorf("last %s was %s ago, more than %s", event, age.Round(time.Second), maxAge)
+		}
+		return nil
+	}
+}
+
+// PingCheck fails if the database can't be reached
+func PingCheck(db *sql.DB) Check {
+	return func() error {
+		if err := db.Ping(); err != nil {
+			return fmt.Errorf("database unreachable: %s", err)
+		}
+		return nil
+	}
+}
@@ -0,0 +1,148 @@
+package health
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Status is the health of a component, or of a whole service
+type Status string
+
+const (
+	Up   Status = "up"
+	Down Status = "down"
+)
+
+// DefaultTimeout is how long a check can take before its component is reported as down
+const DefaultTimeout = 5 * time.Second
+
+// Check reports the health of a single component, returning an error describing
+// the problem if it is unhealthy
+type Check func() error
+
+// ComponentStatus is the result of running a component's check
+type ComponentStatus struct {
+	Status Status `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+// Report is the health of a service and each of its components. The service
+// is only up if every one of its components is up.
+type Report struct {
+	Status     Status                     `json:"status"`
+	Components map[string]ComponentStatus `json:"components"`
+}
+
+// Checker runs a set of named checks, and serves the results over HTTP
+type Checker struct {
+	mutex   sync.Mutex
+	checks  map[string]Check
+	timeout time.Duration
+}
+
+// NewChecker creates a Checker without any checks, which reports that it is up
+// Example Usage:
+// ready := health.NewChecker(health.TimeoutOption(time.Second))
+// ready.Register("database", health.PingCheck(db))
+// http.Handle("/readyz", ready)
+func NewChecker(options ...func(*Checker)) *Checker {
+	c := &Checker{checks: map[string]Check{}, timeout: DefaultTimeout}
+	for _, option := range options {
+		option(c)
+	}
+	return c
+}
+
+// TimeoutOption returns a function that can be passed to NewChecker to change
+// how long each check can take before it is treated as failed
+func TimeoutOption(timeout time.Duration) func(*Checker) {
+	return func(c *Checker) {
+		c.timeout = timeout
+	}
+}
+
+// Register adds a check for the named component, replacing any existing check with that name
+func (c *Checker) Register(name string, check Check) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.checks[name] = check
+}
+
+// Report runs every check concurrently and collects the results
+func (c *Checker) Report() Report {
+	c.mutex.Lock()
+	checks := make(map[string]Check, len(c.checks))
+	for name, check := range c.checks {
+		checks[name] = check
+	}
+	c.mutex.Unlock()
+
+	type result struct {
+		name string
+		err  error
+	}
+	results := make(chan result, len(checks))
+	for name, check := range checks {
+		go func(name string, check Check) {
+			results <- result{name, c.run(check)}
+		}(name, check)
+	}
+	report := Report{Status: Up, Components: map[string]ComponentStatus{}}
+	for range checks {
+		r := <-results
+		if r.err != nil {
+			report.Status = Down
+			report.Components[r.name] = ComponentStatus{Status: Down, Error: r.err.Error()}
+		} else {
+			report.Components[r.name] = ComponentStatus{Status: Up}
+		}
+	}
+	return report
+}
+
+// run runs the check, failing it if it takes longer than the timeout
+func (c *Checker) run(check Check) error {
+	done := make(chan error, 1)
+	go func() {
+		done <- check()
+	}()
+	select {
+	case err := <-done:
+		return err
+	case <-time.After(c.timeout):
+		return fmt.Errorf("check timed out after %s", c.timeout)
+	}
+}
+
+// ServeHTTP writes the report as JSON, with a 200 status if the service is
+// up, or 503 Service Unavailable if any component is down
+func (c *Checker) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	report := c.Report()
+	body, err := json.Marshal(report)
+	if err != nil {
+		log.Printf("health.Checker: error marshalling report into JSON: %s\n", err)
+		http.Error(w, "Failed to create health report", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	if report.Status != Up {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		var down []string
+		for name, component := range report.Components {
+			if component.Status != Up {
+				down = append(down, name)
+			}
+		}
+		sort.Strings(down)
+		log.Printf("health.Checker: components down: %v\n", down)
+	}
+	if _, err := w.Write(body); err != nil {
+		log.Printf("health.Checker: error writing response: %s\n", err)
+	}
+}
@@ -0,0 +1,50 @@
+package health
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func serve(t *testing.T, c *Checker) (int, Report) {
+	rec := httptest.NewRecorder()
+	c.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	var report Report
+	assert.Nil(t, json.Unmarshal(rec.Body.Bytes(), &report))
+	return rec.Code, report
+}
+
+func TestChecker_ServeHTTP(t *testing.T) {
+	c := NewChecker(TimeoutOption(50 * time.Millisecond))
+	code, report := serve(t, c)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, Report{Status: Up, Components: map[string]ComponentStatus{}}, report)
+
+	c.Register("feed", func() error { return nil })
+	c.Register("database", func() error { return errors.New("connection refused") })
+	c.Register("slow", func() error {
+		time.Sleep(time.Second)
+		return nil
+	})
+	code, report = serve(t, c)
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, Down, report.Status)
+	assert.Equal(t, ComponentStatus{Status: Up}, report.Components["feed"])
+	assert.Equal(t, ComponentStatus{Status: Down, Error: "connection refused"}, report.Components["database"])
+	assert.Equal(t, Down, report.Components["slow"].Status)
+}
+
+func TestMaxAgeCheck(t *testing.T) {
+	var last time.Time
+	check := MaxAgeCheck("successful fetch", func() time.Time { return last }, time.Minute)
+	assert.EqualError(t, check(), "no successful fetch yet")
+	last = time.Now().Add(-30 * time.Second)
+	assert.Nil(t, check())
+	last = time.Now().Add(-2 * time.Minute)
+	assert.NotNil(t, check())
+}
@@ -25,3 +25,13 @@ func GetEnv(key string) string {
 	}
 	return value
 }
+
+// GetEnvOrDefault fetches the value of the environment variable
+// stored at key, or returns defaultValue if it isn't set.
+func GetEnvOrDefault(key string, defaultValue string) string {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	return value
+}
@@ -0,0 +1,111 @@
+package mapping
+
+import (
+	"fmt"
+	"transport/lib/geo"
+	"transport/lib/math"
+)
+
+// The Distance Matrix API accepts at most 25 origins, 25 destinations and 100
+// elements (origins * destinations) per request. Splitting both origins and
+// destinations into blocks of 10 keeps every request within all three limits.
+const (
+	maxOriginsPerRequest      = 10
+	maxDestinationsPerRequest = 10
+)
+
+// matrixRequest is a set of origins and destinations that can be sent in a single request
+type matrixRequest struct {
+	origins      []geo.Point
+	destinations []geo.Point
+}
+
+// Distances returns the road distance for each of the stop pairs. Pairs that are already
+// cached are not requested again, and the rest are batched into as few requests as the
+// API's limits allow. If the Limiter's daily quota runs out part of the way through,
+// ErrQuotaExceeded is returned, but everything fetched up to that point remains cached.
+func (gp *GoogleProvider) Distances(routeID string, directionID int, pairs []StopPair) ([]float64, error) {
+	fetched := map[string]float64{}
+	var uncached []StopPair
+	for _, pair := range pairs {
+		if gp.cache == nil {
+			uncached = append(uncached, pair)
+		} else if _, found := gp.cache.Get(pair.From.Point(), pair.To.Point()); !found {
+			uncached = append(uncached, pair)
+		}
+	}
+	for _, req := range batchRequests(uncached) {
+		if gp.limiter != nil {
+			if err := gp.limiter.Wait(len(req.origins) * len(req.destinations)); err != nil {
+				return nil, err
+			}
+		}
+		distances, err := roadDistances(gp.client, req.origins, req.destinations)
+		if err != nil {
+			return nil, fmt.Errorf("mapping.GoogleProvider: route %s, direction %d: %s", routeID, directionID, err)
+		}
+		for key, distance := range distances {
+			fetched[key] = distance
+		}
+		if gp.cache != nil {
+			for _, origin := range req.origins {
+				for _, destination := range req.destinations {
+					if distance, ok := distances[cacheKey(origin, destination)]; ok {
+						gp.cache.Set(origin, destination, distance)
+					}
+				}
+			}
+		}
+	}
+	results := make([]float64, len(pairs))
+	for i, pair := range pairs {
+		from, to := pair.From.Point(), pair.To.Point()
+		distance, found := fetched[cacheKey(from, to)]
+		if !found && gp.cache != nil {
+			distance, found = gp.cache.Get(from, to)
+		}
+		if !found {
+			return nil, fmt.Errorf("mapping.GoogleProvider: no route found from %s to %s", pair.From.ID, pair.To.ID)
+		}
+		results[i] = distance
+	}
+	return results, nil
+}
+
+// batchRequests groups the pairs into requests that stay within the API's limits. Pairs
+// are grouped by origin, and each block of origins is sent with every block of the
+// destinations needed by those origins. For the upper-triangular set of pairs produced
+// by a route's stop list, this needs roughly n²/200 requests rather than n²/2.
+func batchRequests(pairs []StopPair) []matrixRequest {
+	var origins []geo.Point
+	destinationsByOrigin := map[geo.Point][]geo.Point{}
+	for _, pair := range pairs {
+		from, to := pair.From.Point(), pair.To.Point()
+		if _, seen := destinationsByOrigin[from]; !seen {
+			origins = append(origins, from)
+		}
+		destinationsByOrigin[from] = append(destinationsByOrigin[from], to)
+	}
+	var requests []matrixRequest
+	for start := 0; start < len(origins); start += maxOriginsPerRequest {
+		originBlock := origins[start:math.MinInt(start+maxOriginsPerRequest, len(origins))]
+		// Every destination required by at least one origin in this block
+		var destinations []geo.Point
+		seen := map[geo.Point]bool{}
+		for _, origin := range originBlock {
+			for _, destination := range destinationsByOrigin[origin] {
+				if !seen[destination] {
+					seen[destination] = true
+					destinations = append(destinations, destination)
+				}
+			}
+		}
+		for d := 0; d < len(destinations); d += maxDestinationsPerRequest {
+			requests = append(requests, matrixRequest{
+				origins:      originBlock,
+				destinations: destinations[d:math.MinInt(d+maxDestinationsPerRequest, len(destinations))],
+			})
+		}
+	}
+	return requests
+}
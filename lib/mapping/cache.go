@@ -0,0 +1,89 @@
+package mapping
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"sync"
+	"transport/lib/geo"
+)
+
+// DistanceCache stores previously fetched distances between pairs of coordinates,
+// so that the same pair never has to be requested twice. It can be persisted to disk
+// between runs using Save. Safe for concurrent use.
+type DistanceCache struct {
+	mux       sync.Mutex
+	path      string
+	distances map[string]float64
+}
+
+// LoadDistanceCache reads a DistanceCache from the JSON file at `path`. If the file
+// doesn't exist yet, an empty cache is returned which will be written to `path` by Save.
+func LoadDistanceCache(path string) (*DistanceCache, error) {
+	cache := &DistanceCache{path: path, distances: map[string]float64{}}
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return cache, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("mapping.LoadDistanceCache: failed to read '%s': %s", path, err)
+	}
+	if err := json.Unmarshal(data, &cache.distances); err != nil {
+		return nil, fmt.Errorf("mapping.LoadDistanceCache: failed to parse '%s': %s", path, err)
+	}
+	log.Printf("Loaded %d cached distances from %s\n", len(cache.distances), path)
+	return cache, nil
+}
+
+// Get returns the cached distance from `from` to `to`, if there is one
+func (c *DistanceCache) Get(from, to geo.Point) (distance float64, found bool) {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	distance, found = c.distances[cacheKey(from, to)]
+	return distance, found
+}
+
+// Set stores the distance from `from` to `to`
+func (c *DistanceCache) Set(from, to geo.Point, distance float64) {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	c.distances[cacheKey(from, to)] = distance
+}
+
+// Len returns the number of distances in the cache
+func (c *DistanceCache) Len() int {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	return len(c.distances)
+}
+
+// Save writes the cache to the path it was loaded from. The file is replaced
+// atomically, so an interrupted Save never corrupts an existing cache.
+func (c *DistanceCache) Save() error {
+	c.mux.Lock()
+	data, err := json.Marshal(c.distances)
+	c.mux.Unlock()
+	if err != nil {
+		return fmt.Errorf("mapping.DistanceCache.Save: failed to marshal cache: %s", err)
+	}
+	tmpPath := c.path + ".tmp"
+	if err := ioutil.WriteFile(tmpPath, data, 0644); err != nil {
+		return fmt.Errorf("mapping.DistanceCache.Save: failed to write '%s': %s", tmpPath, err)
+	}
+	if err := os.Rename(tmpPath, c.path); err != nil {
+		return fmt.Errorf("mapping.DistanceCache.Save: failed to replace '%s': %s", c.path, err)
+	}
+	return nil
+}
+
+// cacheKey uses the same coordinate precision as the requests sent to the API,
+// so points that produce identical requests share a cache entry
+func cacheKey(from, to geo.Point) string {
+	return fmt.Sprintf("%s;%s", coordinates(from), coordinates(to))
+}
+
+func coordinates(p geo.Point) string {
+	return fmt.Sprintf("%f,%f", p.Latitude, p.Longitude)
+}
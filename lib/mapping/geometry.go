@@ -0,0 +1,89 @@
+package mapping
+
+import (
+	"fmt"
+	"math"
+	"transport/lib/bustime"
+	"transport/lib/geo"
+
+	"googlemaps.github.io/maps"
+)
+
+// Stops further than this (in metres) from every polyline on their route
+// are assumed to be mismatched with the route's geometry
+const maxSnapDistance = 150
+
+// RouteKey identifies a single direction of travel along a route
+type RouteKey struct {
+	RouteID     string
+	DirectionID int
+}
+
+// GeometryProvider is a DistanceProvider that works entirely offline. Both stops are
+// projected onto the route's polylines and the distance between them is measured along
+// the polyline, rather than by requesting a route from an external service.
+type GeometryProvider struct {
+	shapes map[RouteKey][]geo.Polyline
+}
+
+// NewGeometryProvider takes a map of the form: routeID -> directionID -> []encodedPolyline
+// (as returned by bustime.Client.GetPolylines) and decodes the polylines for use in Distance.
+func NewGeometryProvider(encodedPolylines map[string]map[int][]string) (*GeometryProvider, error) {
+	gp := &GeometryProvider{shapes: map[RouteKey][]geo.Polyline{}}
+	for routeID, directions := range encodedPolylines {
+		for directionID, polylines := range directions {
+			key := RouteKey{RouteID: routeID, DirectionID: directionID}
+			for _, encoded := range polylines {
+				polyline, err := DecodePolyline(encoded)
+				if err != nil {
+					return nil, fmt.Errorf("mapping.NewGeometryProvider: failed to decode polyline for %v: %s", key, err)
+				}
+				if len(polyline.Points) < 2 {
+					continue
+				}
+				gp.shapes[key] = append(gp.shapes[key], polyline)
+			}
+		}
+	}
+	return gp, nil
+}
+
+// DecodePolyline converts a polyline encoded using Google's polyline algorithm into a geo.Polyline
+func DecodePolyline(encoded string) (geo.Polyline, error) {
+	latLngs, err := maps.DecodePolyline(encoded)
+	if err != nil {
+		return geo.Polyline{}, err
+	}
+	points := make([]geo.Point, len(latLngs))
+	for i, ll := range latLngs {
+		points[i] = geo.Point{Latitude: ll.Lat, Longitude: ll.Lng}
+	}
+	return geo.NewPolyline(points), nil
+}
+
+// Distance projects both stops onto the polylines for the given route and direction,
+// and returns the distance along the polyline between the two projected positions.
+// Where a direction has multiple polylines, the one that both stops lie closest to is used.
+func (gp *GeometryProvider) Distance(routeID string, directionID int, from, to bustime.BusStop) (float64, error) {
+	key := RouteKey{RouteID: routeID, DirectionID: directionID}
+	shapes, exists := gp.shapes[key]
+	if !exists {
+		return 0, fmt.Errorf("mapping.GeometryProvider: no polylines available for %v", key)
+	}
+	bestDistance, bestOffset := 0.0, math.Inf(1)
+	for _, shape := range shapes {
+		fromProj := shape.Project(from.Point())
+		// The destination must come after the origin along the polyline
+		toProj := shape.ProjectAfter(to.Point(), fromProj.DistanceAlong)
+		if fromProj.Offset > maxSnapDistance || toProj.Offset > maxSnapDistance {
+			continue
+		}
+		if offset := fromProj.Offset + toProj.Offset; offset < bestOffset {
+			bestDistance, bestOffset = toProj.DistanceAlong-fromProj.DistanceAlong, offset
+		}
+	}
+	if math.IsInf(bestOffset, 1) {
+		return 0, fmt.Errorf("mapping.GeometryProvider: stops %s and %s could not be placed along %v", from.ID, to.ID, key)
+	}
+	return bestDistance, nil
+}
@@ -0,0 +1,91 @@
+package mapping
+
+import (
+	"errors"
+	"sync"
+	"time"
+	"transport/lib/database"
+)
+
+// ErrQuotaExceeded is returned when a request would take the number of
+// elements requested today over the Limiter's daily quota
+var ErrQuotaExceeded = errors.New("mapping: daily element quota exceeded")
+
+// Google resets Distance Matrix quotas at midnight Pacific Time
+var quotaLoc, quotaLocErr = time.LoadLocation("America/Los_Angeles")
+
+// Limiter restricts the rate at which requests are sent to the Distance Matrix API,
+// and the number of elements (origins * destinations) requested per day.
+// Safe for concurrent use.
+type Limiter struct {
+	mux           sync.Mutex
+	interval      time.Duration
+	nextRequest   time.Time
+	dailyElements int
+	day           string
+	used          int
+	now           func() time.Time
+}
+
+// NewLimiter creates a Limiter that allows at most `qps` requests per second and
+// `dailyElements` elements per day. A `dailyElements` value of 0 disables the quota.
+func NewLimiter(qps float64, dailyElements int) *Limiter {
+	var interval time.Duration
+	if qps > 0 {
+		interval = time.Duration(float64(time.Second) / qps)
+	}
+	return &Limiter{interval: interval, dailyElements: dailyElements, now: time.Now}
+}
+
+// Wait blocks until a request for `elements` elements may be sent. If sending it
+// would exceed today's quota, ErrQuotaExceeded is returned immediately instead.
+func (l *Limiter) Wait(elements int) error {
+	l.mux.Lock()
+	now := l.now()
+	l.resetIfNewDay(now)
+	if l.dailyElements > 0 && l.used+elements > l.dailyElements {
+		l.mux.Unlock()
+		return ErrQuotaExceeded
+	}
+	l.used += elements
+	// Reserve the next available slot, then sleep until it arrives without holding the lock
+	slot := l.nextRequest
+	if slot.Before(now) {
+		slot = now
+	}
+	l.nextRequest = slot.Add(l.interval)
+	l.mux.Unlock()
+	time.Sleep(slot.Sub(now))
+	return nil
+}
+
+// Usage returns the current quota day (as a date string in Pacific Time)
+// and the number of elements used so far on that day
+func (l *Limiter) Usage() (day string, used int) {
+	l.mux.Lock()
+	defer l.mux.Unlock()
+	l.resetIfNewDay(l.now())
+	return l.day, l.used
+}
+
+// Restore sets the number of elements already used on `day`, so that usage from
+// a previous run counts towards the quota. Usage from earlier days is ignored.
+func (l *Limiter) Restore(day string, used int) {
+	l.mux.Lock()
+	defer l.mux.Unlock()
+	l.resetIfNewDay(l.now())
+	if day == l.day {
+		l.used = used
+	}
+}
+
+func (l *Limiter) resetIfNewDay(now time.Time) {
+	loc := quotaLoc
+	if quotaLocErr != nil {
+		loc = time.UTC
+	}
+	today := now.In(loc).Format(database.DateFormat)
+	if today != l.day {
+		l.day, l.used = today, 0
+	}
+}
@@ -4,18 +4,120 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"transport/lib/bustime"
+	"transport/lib/geo"
 
 	"googlemaps.github.io/maps"
 )
 
+// DistanceProvider calculates the distance in metres that a vehicle travelling
+// along the given route and direction covers between the `from` and `to` stops.
+type DistanceProvider interface {
+	Distance(routeID string, directionID int, from, to bustime.BusStop) (float64, error)
+}
+
+// BatchProvider is a DistanceProvider that can calculate many distances along
+// a route at once, more cheaply than calling Distance for each pair.
+type BatchProvider interface {
+	DistanceProvider
+	Distances(routeID string, directionID int, pairs []StopPair) ([]float64, error)
+}
+
+// StopPair is an ordered pair of stops along a route
+type StopPair struct {
+	From bustime.BusStop
+	To   bustime.BusStop
+}
+
+// GoogleProvider is a DistanceProvider backed by the Google Distance Matrix API.
+// Requests can optionally be cached and rate limited (see the functions suffixed
+// with 'Option' in this file).
+type GoogleProvider struct {
+	client  *maps.Client
+	cache   *DistanceCache
+	limiter *Limiter
+}
+
+// NewGoogleProvider creates a GoogleProvider that sends its requests using `mc`
+// Example Usage:
+// gp := mapping.NewGoogleProvider(mc, mapping.CacheOption(cache), mapping.LimiterOption(limiter))
+func NewGoogleProvider(mc *maps.Client, options ...func(*GoogleProvider)) *GoogleProvider {
+	gp := &GoogleProvider{client: mc}
+	for _, option := range options {
+		option(gp)
+	}
+	return gp
+}
+
+// CacheOption returns a function that can be passed to NewGoogleProvider
+// so that fetched distances are stored in, and served from, `cache`
+func CacheOption(cache *DistanceCache) func(*GoogleProvider) {
+	return func(gp *GoogleProvider) {
+		gp.cache = cache
+	}
+}
+
+// LimiterOption returns a function that can be passed to NewGoogleProvider
+// so that all requests are throttled by `limiter`
+func LimiterOption(limiter *Limiter) func(*GoogleProvider) {
+	return func(gp *GoogleProvider) {
+		gp.limiter = limiter
+	}
+}
+
+// Distance returns the road distance between the two stops. The route and direction
+// are ignored, as the Distance Matrix API picks its own path between the stops.
+func (gp *GoogleProvider) Distance(routeID string, directionID int, from, to bustime.BusStop) (float64, error) {
+	distances, err := gp.Distances(routeID, directionID, []StopPair{{From: from, To: to}})
+	if err != nil {
+		return 0, err
+	}
+	return distances[0], nil
+}
+
 func RoadDistance(mc *maps.Client, fromLat, fromLon, toLat, toLon float64) (distanceInMetres float64) {
+	from, to := geo.Point{Latitude: fromLat, Longitude: fromLon}, geo.Point{Latitude: toLat, Longitude: toLon}
+	distances, err := roadDistances(mc, []geo.Point{from}, []geo.Point{to})
+	if err != nil {
+		log.Fatalf("mapping.RoadDistance: %s", err)
+	}
+	distance, found := distances[cacheKey(from, to)]
+	if !found {
+		log.Fatalf("mapping.RoadDistance: no distance returned between %s and %s", coordinates(from), coordinates(to))
+	}
+	return distance
+}
+
+// roadDistances sends a single Distance Matrix request for every combination of
+// `origins` and `destinations`, and returns the distances keyed by cacheKey.
+// Elements that the API couldn't find a route for are omitted.
+func roadDistances(mc *maps.Client, origins, destinations []geo.Point) (map[string]float64, error) {
 	r := &maps.DistanceMatrixRequest{
-		Origins:      []string{fmt.Sprintf("%f,%f", fromLat, fromLon)},
-		Destinations: []string{fmt.Sprintf("%f,%f", toLat, toLon)},
+		Origins:      make([]string, len(origins)),
+		Destinations: make([]string, len(destinations)),
+	}
+	for i, p := range origins {
+		r.Origins[i] = coordinates(p)
 	}
-	distance, err := mc.DistanceMatrix(context.Background(), r)
+	for i, p := range destinations {
+		r.Destinations[i] = coordinates(p)
+	}
+	resp, err := mc.DistanceMatrix(context.Background(), r)
 	if err != nil {
-		log.Fatalf("mapping.RoadDistance: error whilst fetching distance matrix response: %s", err)
+		return nil, fmt.Errorf("error whilst fetching distance matrix response: %s", err)
+	}
+	if len(resp.Rows) != len(origins) {
+		return nil, fmt.Errorf("distance matrix response contained %d rows, expected %d", len(resp.Rows), len(origins))
+	}
+	distances := map[string]float64{}
+	for i, row := range resp.Rows {
+		for j, element := range row.Elements {
+			// Older responses may omit the element status, treat that as success
+			if j >= len(destinations) || (element.Status != "" && element.Status != "OK") {
+				continue
+			}
+			distances[cacheKey(origins[i], destinations[j])] = float64(element.Distance.Meters)
+		}
 	}
-	return float64(distance.Rows[0].Elements[0].Distance.Meters)
+	return distances, nil
 }
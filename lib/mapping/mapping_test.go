@@ -2,7 +2,15 @@ package mapping_test
 
 import (
 	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+	"time"
+	"transport/lib/bustime"
+	"transport/lib/geo"
 	"transport/lib/mapping"
 	"transport/lib/testhelper"
 
@@ -21,3 +29,140 @@ func TestRoadDistance(t *testing.T) {
 	expected := 1234.00
 	assert.Equal(t, expected, mapping.RoadDistance(mc, 1.2, 3.4, 5.6, 7.8))
 }
+
+func TestGoogleProvider_Distance(t *testing.T) {
+	ts := testhelper.ServeMock(`{"rows": [{"elements": [{"distance": {"value": 567}}]}], "status": "OK"}`)
+	defer ts.Close()
+	mc, err := maps.NewClient(maps.WithAPIKey("TEST"), maps.WithBaseURL(ts.URL))
+	if err != nil {
+		assert.Fail(t, fmt.Sprintf("failed to initialise maps client: %s", err))
+	}
+	from, to := bustime.BusStop{Latitude: 1.2, Longitude: 3.4}, bustime.BusStop{Latitude: 5.6, Longitude: 7.8}
+	actual, err := mapping.NewGoogleProvider(mc).Distance("MTA NYCT_M1", 0, from, to)
+	assert.NoError(t, err)
+	assert.Equal(t, 567.00, actual)
+}
+
+func TestGeometryProvider_Distance(t *testing.T) {
+	// A straight line running north along a single line of longitude
+	polyline := maps.Encode([]maps.LatLng{{Lat: 40.70, Lng: -73.99}, {Lat: 40.71, Lng: -73.99}, {Lat: 40.72, Lng: -73.99}})
+	gp, err := mapping.NewGeometryProvider(map[string]map[int][]string{"MTA NYCT_M1": {0: {polyline}}})
+	if err != nil {
+		assert.Fail(t, fmt.Sprintf("failed to initialise geometry provider: %s", err))
+	}
+	// Stops sit slightly to the side of the road, 0.01 degrees of latitude (~1112m) apart
+	from := bustime.BusStop{ID: "A", Latitude: 40.705, Longitude: -73.9901}
+	to := bustime.BusStop{ID: "B", Latitude: 40.715, Longitude: -73.9899}
+
+	actual, err := gp.Distance("MTA NYCT_M1", 0, from, to)
+	assert.NoError(t, err)
+	assert.InDelta(t, 1111.95, actual, 1)
+
+	// Travelling backwards along the route isn't possible
+	_, err = gp.Distance("MTA NYCT_M1", 0, to, from)
+	assert.Error(t, err)
+
+	// No geometry for the opposite direction
+	_, err = gp.Distance("MTA NYCT_M1", 1, from, to)
+	assert.Error(t, err)
+}
+
+// serveDistanceMatrix responds to Distance Matrix requests with the straight-line
+// distance between each origin and destination, and counts the requests received
+func serveDistanceMatrix(requestCount *int) *httptest.Server {
+	parse := func(param string) []geo.Point {
+		var points []geo.Point
+		for _, coords := range strings.Split(param, "|") {
+			var p geo.Point
+			fmt.Sscanf(coords, "%f,%f", &p.Latitude, &p.Longitude)
+			points = append(points, p)
+		}
+		return points
+	}
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*requestCount++
+		origins, destinations := parse(r.URL.Query().Get("origins")), parse(r.URL.Query().Get("destinations"))
+		var rows []string
+		for _, o := range origins {
+			var elements []string
+			for _, d := range destinations {
+				elements = append(elements, fmt.Sprintf(`{"status": "OK", "distance": {"value": %d}}`, int(geo.Haversine(o, d))))
+			}
+			rows = append(rows, fmt.Sprintf(`{"elements": [%s]}`, strings.Join(elements, ",")))
+		}
+		fmt.Fprintf(w, `{"rows": [%s], "status": "OK"}`, strings.Join(rows, ","))
+	}))
+}
+
+func routePairs(stopCount int) []mapping.StopPair {
+	stops := make([]bustime.BusStop, stopCount)
+	for i := range stops {
+		stops[i] = bustime.BusStop{ID: fmt.Sprintf("Stop%d", i), Latitude: 40.7 + float64(i)*0.001, Longitude: -73.99}
+	}
+	var pairs []mapping.StopPair
+	for i := range stops {
+		for j := i + 1; j < len(stops); j++ {
+			pairs = append(pairs, mapping.StopPair{From: stops[i], To: stops[j]})
+		}
+	}
+	return pairs
+}
+
+func TestGoogleProvider_DistancesBatchesAndCaches(t *testing.T) {
+	requestCount := 0
+	ts := serveDistanceMatrix(&requestCount)
+	defer ts.Close()
+	mc, err := maps.NewClient(maps.WithAPIKey("TEST"), maps.WithBaseURL(ts.URL))
+	if err != nil {
+		assert.Fail(t, fmt.Sprintf("failed to initialise maps client: %s", err))
+	}
+	cachePath := filepath.Join(os.TempDir(), fmt.Sprintf("distanceCache_%d.json", time.Now().UnixNano()))
+	defer os.Remove(cachePath)
+	cache, err := mapping.LoadDistanceCache(cachePath)
+	assert.NoError(t, err)
+	gp := mapping.NewGoogleProvider(mc, mapping.CacheOption(cache))
+
+	// 30 stops produce 435 pairs, which fit into 6 requests of at most 10x10 elements
+	pairs := routePairs(30)
+	distances, err := gp.Distances("MTA NYCT_M1", 0, pairs)
+	assert.NoError(t, err)
+	assert.Equal(t, 6, requestCount)
+	for i, pair := range pairs {
+		assert.Equal(t, float64(int(geo.Haversine(pair.From.Point(), pair.To.Point()))), distances[i])
+	}
+
+	// Everything is now cached, including after a reload from disk
+	assert.NoError(t, cache.Save())
+	reloaded, err := mapping.LoadDistanceCache(cachePath)
+	assert.NoError(t, err)
+	assert.Equal(t, cache.Len(), reloaded.Len())
+	_, err = mapping.NewGoogleProvider(mc, mapping.CacheOption(reloaded)).Distances("MTA NYCT_M1", 0, pairs)
+	assert.NoError(t, err)
+	assert.Equal(t, 6, requestCount)
+}
+
+func TestGoogleProvider_DistancesQuota(t *testing.T) {
+	requestCount := 0
+	ts := serveDistanceMatrix(&requestCount)
+	defer ts.Close()
+	mc, err := maps.NewClient(maps.WithAPIKey("TEST"), maps.WithBaseURL(ts.URL))
+	if err != nil {
+		assert.Fail(t, fmt.Sprintf("failed to initialise maps client: %s", err))
+	}
+	limiter := mapping.NewLimiter(1000, 150)
+	gp := mapping.NewGoogleProvider(mc, mapping.LimiterOption(limiter))
+
+	// The second request would take the day's usage to 200 elements
+	_, err = gp.Distances("MTA NYCT_M1", 0, routePairs(30))
+	assert.Equal(t, mapping.ErrQuotaExceeded, err)
+	assert.Equal(t, 1, requestCount)
+	_, used := limiter.Usage()
+	assert.Equal(t, 100, used)
+
+	// Usage restored from a previous run counts towards the quota
+	day, _ := limiter.Usage()
+	restored := mapping.NewLimiter(1000, 150)
+	restored.Restore(day, 100)
+	assert.Equal(t, mapping.ErrQuotaExceeded, restored.Wait(51))
+	assert.NoError(t, restored.Wait(50))
+}
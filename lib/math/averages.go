@@ -15,10 +15,14 @@ func SliceMeanRounded(nums []int) int {
 	return int(total / len(nums))
 }
 
+// SliceMedian returns the upper median of a slice of integers.
+// The input slice is not modified. Empty slices return 0.
 func SliceMedian(nums []int) int {
 	if len(nums) == 0 {
 		return 0
 	}
-	sort.Ints(nums)
-	return nums[int(len(nums)/2)]
+	sorted := make([]int, len(nums))
+	copy(sorted, nums)
+	sort.Ints(sorted)
+	return sorted[int(len(sorted)/2)]
 }
@@ -6,3 +6,10 @@ func MaxInt(a int, b int) int {
 	}
 	return b
 }
+
+func MinInt(a int, b int) int {
+	if a <= b {
+		return a
+	}
+	return b
+}
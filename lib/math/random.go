@@ -5,8 +5,20 @@ import (
 	"time"
 )
 
-// RandInRange returns an integer in the range [min, max)
-func RandInRange(min, max int) int {
-	rand.Seed(time.Now().Unix())
-	return rand.Intn(max-min) + min
+// NewRand returns a random number generator seeded with `seed`. Passing the
+// same seed will always produce the same sequence of numbers, which allows
+// randomised runs (e.g. evaluations) to be reproduced exactly.
+func NewRand(seed int64) *rand.Rand {
+	return rand.New(rand.NewSource(seed))
+}
+
+// TimeSeed returns a seed derived from the current time, for use when
+// a run doesn't need to be reproducible
+func TimeSeed() int64 {
+	return time.Now().UnixNano()
+}
+
+// RandInRange uses `r` to return an integer in the range [min, max)
+func RandInRange(r *rand.Rand, min, max int) int {
+	return r.Intn(max-min) + min
 }
@@ -0,0 +1,22 @@
+package math
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRandInRange(t *testing.T) {
+	r := NewRand(1)
+	for i := 0; i < 1000; i++ {
+		n := RandInRange(r, 60, 300)
+		assert.True(t, n >= 60 && n < 300, "%d is outside of [60, 300)", n)
+	}
+}
+
+func TestNewRand_SameSeedIsReproducible(t *testing.T) {
+	first, second := NewRand(42), NewRand(42)
+	for i := 0; i < 100; i++ {
+		assert.Equal(t, RandInRange(first, 0, 1000), RandInRange(second, 0, 1000))
+	}
+}
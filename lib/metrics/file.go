@@ -0,0 +1,51 @@
+package metrics
+
+import (
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"transport/lib/iohelper"
+)
+
+// PathEnvVar is the environment variable holding the path batch jobs write their metrics to
+const PathEnvVar = "METRICS_PATH"
+
+// WriteFile writes the metrics in the registry to `path` in the Prometheus text format, e.g.
+// for the node exporter's textfile collector (which expects the file to end in .prom). The
+// file is replaced atomically, so a collector never reads a partially written file.
+func (r *Registry) WriteFile(path string) error {
+	tmp, err := ioutil.TempFile(filepath.Dir(path), filepath.Base(path)+".tmp")
+	if err != nil {
+		return fmt.Errorf("metrics.WriteFile: error creating temporary file: %s", err)
+	}
+	defer os.Remove(tmp.Name())
+	if err := r.Write(tmp); err != nil {
+		tmp.Close()
+		return fmt.Errorf("metrics.WriteFile: error writing %s: %s", tmp.Name(), err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("metrics.WriteFile: error closing %s: %s", tmp.Name(), err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("metrics.WriteFile: error replacing %s: %s", path, err)
+	}
+	return nil
+}
+
+// PushToFile writes the metrics in the Default registry to the file at METRICS_PATH,
+// if it is set. Batch jobs call this before exiting, as they can't be scraped.
+// Example Usage:
+// defer metrics.PushToFile()
+func PushToFile() {
+	path := iohelper.GetEnvOrDefault(PathEnvVar, "")
+	if path == "" {
+		return
+	}
+	if err := Default.WriteFile(path); err != nil {
+		log.Printf("Failed to push metrics: %s\n", err)
+		return
+	}
+	log.Printf("Pushed metrics to %s\n", path)
+}
@@ -0,0 +1,82 @@
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// ContentType is the content type of the Prometheus text exposition format
+const ContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// HTTP metrics recorded by InstrumentHandler, labelled with the name of the handler
+var (
+	httpRequests        = NewCounter("http_requests_total", "HTTP requests served, by handler and status code.", "handler", "code")
+	httpRequestDuration = NewHistogram("http_request_duration_seconds", "Time taken to serve HTTP requests, by handler.", nil, "handler")
+)
+
+// Handler serves the metrics in the registry, so they can be scraped by Prometheus
+func (r *Registry) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", ContentType)
+		if err := r.Write(w); err != nil {
+			log.Printf("metrics.Handler: error writing metrics: %s\n", err)
+		}
+	})
+}
+
+// Handler serves the metrics in the Default registry
+// Example Usage:
+// http.Handle("/metrics", metrics.Handler())
+func Handler() http.Handler {
+	return Default.Handler()
+}
+
+// InstrumentHandler wraps `h` so that the number of requests it serves and how long they
+// take are recorded under `name`. Streaming responses (e.g. server-sent events and
+// WebSockets) still work, as the wrapped writer can be flushed and hijacked.
+func InstrumentHandler(name string, h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		start := time.Now()
+		sw := &statusWriter{ResponseWriter: w, status: http.StatusOK}
+		h.ServeHTTP(sw, req)
+		httpRequests.Inc(name, strconv.Itoa(sw.status))
+		httpRequestDuration.ObserveSince(start, name)
+	})
+}
+
+// InstrumentHandlerFunc is InstrumentHandler for handler functions
+func InstrumentHandlerFunc(name string, h http.HandlerFunc) http.Handler {
+	return InstrumentHandler(name, h)
+}
+
+// statusWriter records the status code written to a response
+type statusWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+func (sw *statusWriter) WriteHeader(status int) {
+	sw.status = status
+	sw.ResponseWriter.WriteHeader(status)
+}
+
+func (sw *statusWriter) Flush() {
+	if flusher, ok := sw.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+func (sw *statusWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hijacker, ok := sw.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, fmt.Errorf("metrics: the underlying ResponseWriter can't be hijacked")
+	}
+	// Hijacked connections are upgraded (e.g. to a WebSocket), which is recorded as 101 Switching Protocols
+	sw.status = http.StatusSwitchingProtocols
+	return hijacker.Hijack()
+}
@@ -0,0 +1,294 @@
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultBuckets are the histogram bucket upper bounds (in seconds) used for latencies,
+// ranging from 5ms to 30s
+var DefaultBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30}
+
+// metricType is the type of a metric in the Prometheus text format
+type metricType string
+
+const (
+	counterType   metricType = "counter"
+	gaugeType     metricType = "gauge"
+	histogramType metricType = "histogram"
+)
+
+// Registry holds a set of metrics, which can be written out in the Prometheus text format
+type Registry struct {
+	mutex    sync.Mutex
+	families map[string]*family
+}
+
+// Default is the registry used by the package-level functions, which is
+// served by Handler and written by WriteFile
+var Default = NewRegistry()
+
+// NewRegistry creates an empty Registry
+func NewRegistry() *Registry {
+	return &Registry{families: map[string]*family{}}
+}
+
+// family is a metric, split into one series for each combination of label values
+type family struct {
+	name       string
+	help       string
+	kind       metricType
+	labelNames []string
+	buckets    []float64
+	// collect is set for metrics whose value is computed when they are written
+	collect func() float64
+
+	mutex  sync.Mutex
+	series map[string]*series
+}
+
+// series holds the value of a metric for one combination of label values
+type series struct {
+	labelValues []string
+	value       float64
+	// Histograms only: the number of observations in each bucket (not cumulative)
+	bucketCounts []uint64
+	count        uint64
+}
+
+// register adds the family to the registry, or returns the existing family with the same
+// name so that packages can safely declare the same metric. Declaring a metric twice with a
+// different type or labels is a programming error, so it panics.
+func (r *Registry) register(f *family) *family {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if existing, found := r.families[f.name]; found {
+		if existing.kind != f.kind || strings.Join(existing.labelNames, ",") != strings.Join(f.labelNames, ",") {
+			log.Panicf("metrics: %s is already registered as a %s with labels %v", f.name, existing.kind, existing.labelNames)
+		}
+		return existing
+	}
+	f.series = map[string]*series{}
+	r.families[f.name] = f
+	return f
+}
+
+// get returns the series for the label values, creating it if needed
+func (f *family) get(labelValues []string) *series {
+	if len(labelValues) != len(f.labelNames) {
+		log.Panicf("metrics: %s expects %d label values %v, got %d", f.name, len(f.labelNames), f.labelNames, len(labelValues))
+	}
+	key := strings.Join(labelValues, "\xff")
+	s, found := f.series[key]
+	if !found {
+		s = &series{labelValues: append([]string{}, labelValues...)}
+		if f.kind == histogramType {
+			s.bucketCounts = make([]uint64, len(f.buckets))
+		}
+		f.series[key] = s
+	}
+	return s
+}
+
+// Counter is a value that only ever increases, e.g. the number of requests served
+type Counter struct {
+	f *family
+}
+
+// NewCounter registers a counter, whose series are identified by the values of `labelNames`
+func (r *Registry) NewCounter(name string, help string, labelNames ...string) *Counter {
+	return &Counter{r.register(&family{name: name, help: help, kind: counterType, labelNames: labelNames})}
+}
+
+// Inc adds one to the series with the given label values
+func (c *Counter) Inc(labelValues ...string) {
+	c.Add(1, labelValues...)
+}
+
+// Add adds `v` (which must not be negative) to the series with the given label values
+func (c *Counter) Add(v float64, labelValues ...string) {
+	if v < 0 {
+		log.Panicf("metrics: counter %s can't be decreased", c.f.name)
+	}
+	c.f.mutex.Lock()
+	defer c.f.mutex.Unlock()
+	c.f.get(labelValues).value += v
+}
+
+// Gauge is a value that can go up and down, e.g. the number of vehicles in a snapshot
+type Gauge struct {
+	f *family
+}
+
+// NewGauge registers a gauge, whose series are identified by the values of `labelNames`
+func (r *Registry) NewGauge(name string, help string, labelNames ...string) *Gauge {
+	return &Gauge{r.register(&family{name: name, help: help, kind: gaugeType, labelNames: labelNames})}
+}
+
+// Set sets the series with the given label values to `v`
+func (g *Gauge) Set(v float64, labelValues ...string) {
+	g.f.mutex.Lock()
+	defer g.f.mutex.Unlock()
+	g.f.get(labelValues).value = v
+}
+
+// Add adds `v` (which can be negative) to the series with the given label values
+func (g *Gauge) Add(v float64, labelValues ...string) {
+	g.f.mutex.Lock()
+	defer g.f.mutex.Unlock()
+	g.f.get(labelValues).value += v
+}
+
+// NewGaugeFunc registers a gauge without labels, whose value is computed by calling
+// `collect` whenever the metrics are written, e.g. the age of the current snapshot
+func (r *Registry) NewGaugeFunc(name string, help string, collect func() float64) {
+	r.register(&family{name: name, help: help, kind: gaugeType, collect: collect})
+}
+
+// Histogram counts observations (e.g. latencies) in buckets, along with their sum and count
+type Histogram struct {
+	f *family
+}
+
+// NewHistogram registers a histogram with the given bucket upper bounds, which must be in
+// increasing order, or DefaultBuckets if `buckets` is nil
+func (r *Registry) NewHistogram(name string, help string, buckets []float64, labelNames ...string) *Histogram {
+	if buckets == nil {
+		buckets = DefaultBuckets
+	}
+	return &Histogram{r.register(&family{name: name, help: help, kind: histogramType, labelNames: labelNames, buckets: buckets})}
+}
+
+// Observe adds an observation to the series with the given label values
+func (h *Histogram) Observe(v float64, labelValues ...string) {
+	h.f.mutex.Lock()
+	defer h.f.mutex.Unlock()
+	s := h.f.get(labelValues)
+	if i := sort.SearchFloat64s(h.f.buckets, v); i < len(h.f.buckets) {
+		s.bucketCounts[i]++
+	}
+	s.value += v
+	s.count++
+}
+
+// ObserveSince observes the number of seconds since `start`
+// Example Usage:
+// defer fetchDuration.ObserveSince(time.Now())
+func (h *Histogram) ObserveSince(start time.Time, labelValues ...string) {
+	h.Observe(time.Since(start).Seconds(), labelValues...)
+}
+
+// NewCounter registers a counter in the Default registry
+func NewCounter(name string, help string, labelNames ...string) *Counter {
+	return Default.NewCounter(name, help, labelNames...)
+}
+
+// NewGauge registers a gauge in the Default registry
+func NewGauge(name string, help string, labelNames ...string) *Gauge {
+	return Default.NewGauge(name, help, labelNames...)
+}
+
+// NewGaugeFunc registers a computed gauge in the Default registry
+func NewGaugeFunc(name string, help string, collect func() float64) {
+	Default.NewGaugeFunc(name, help, collect)
+}
+
+// NewHistogram registers a histogram in the Default registry
+func NewHistogram(name string, help string, buckets []float64, labelNames ...string) *Histogram {
+	return Default.NewHistogram(name, help, buckets, labelNames...)
+}
+
+// Write writes every metric in the Prometheus text exposition format, ordered by name
+func (r *Registry) Write(w io.Writer) error {
+	r.mutex.Lock()
+	families := make([]*family, 0, len(r.families))
+	for _, f := range r.families {
+		families = append(families, f)
+	}
+	r.mutex.Unlock()
+	sort.Slice(families, func(i, j int) bool {
+		return families[i].name < families[j].name
+	})
+	var b strings.Builder
+	for _, f := range families {
+		f.write(&b)
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func (f *family) write(b *strings.Builder) {
+	fmt.Fprintf(b, "# HELP %s %s\n", f.name, escape(f.help, false))
+	fmt.Fprintf(b, "# TYPE %s %s\n", f.name, f.kind)
+	if f.collect != nil {
+		fmt.Fprintf(b, "%s %s\n", f.name, formatValue(f.collect()))
+		return
+	}
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	keys := make([]string, 0, len(f.series))
+	for key := range f.series {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		s := f.series[key]
+		if f.kind != histogramType {
+			fmt.Fprintf(b, "%s%s %s\n", f.name, formatLabels(f.labelNames, s.labelValues, ""), formatValue(s.value))
+			continue
+		}
+		var cumulative uint64
+		for i, bound := range f.buckets {
+			cumulative += s.bucketCounts[i]
+			fmt.Fprintf(b, "%s_bucket%s %d\n", f.name, formatLabels(f.labelNames, s.labelValues, formatValue(bound)), cumulative)
+		}
+		fmt.Fprintf(b, "%s_bucket%s %d\n", f.name, formatLabels(f.labelNames, s.labelValues, "+Inf"), s.count)
+		fmt.Fprintf(b, "%s_sum%s %s\n", f.name, formatLabels(f.labelNames, s.labelValues, ""), formatValue(s.value))
+		fmt.Fprintf(b, "%s_count%s %d\n", f.name, formatLabels(f.labelNames, s.labelValues, ""), s.count)
+	}
+}
+
+// formatLabels formats label pairs as {name="value",...}, adding an `le` label for
+// histogram buckets if `le` isn't empty
+func formatLabels(names []string, values []string, le string) string {
+	var pairs []string
+	for i, name := range names {
+		pairs = append(pairs, fmt.Sprintf(`%s="%s"`, name, escape(values[i], true)))
+	}
+	if le != "" {
+		pairs = append(pairs, fmt.Sprintf(`le="%s"`, le))
+	}
+	if len(pairs) == 0 {
+		return ""
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+func formatValue(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+// escape escapes backslashes and newlines, along with double quotes in label values
+func escape(s string, quotes bool) string {
+	s = strings.Replace(s, `\`, `\\`, -1)
+	s = strings.Replace(s, "\n", `\n`, -1)
+	if quotes {
+		s = strings.Replace(s, `"`, `\"`, -1)
+	}
+	return s
+}
@@ -0,0 +1,89 @@
+package metrics
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func written(t *testing.T, r *Registry) string {
+	var b strings.Builder
+	assert.Nil(t, r.Write(&b))
+	return b.String()
+}
+
+func TestRegistry_Write(t *testing.T) {
+	r := NewRegistry()
+	inserted := r.NewCounter("rows_total", "Rows inserted.", "table")
+	inserted.Inc("headway")
+	inserted.Add(2, "headway")
+	inserted.Inc(`odd "table"`)
+	r.NewGauge("vehicles", "Vehicles in the snapshot.").Set(1200)
+	r.NewGaugeFunc("age_seconds", "Age of the snapshot.", func() float64 { return 4.5 })
+	latency := r.NewHistogram("latency_seconds", "Fetch latency.", []float64{0.1, 1})
+	latency.Observe(0.05)
+	latency.Observe(0.5)
+	latency.Observe(3)
+
+	expected := `# HELP age_seconds Age of the snapshot.
+# TYPE age_seconds gauge
+age_seconds 4.5
+# HELP latency_seconds Fetch latency.
+# TYPE latency_seconds histogram
+latency_seconds_bucket{le="0.1"} 1
+latency_seconds_bucket{le="1"} 2
+latency_seconds_bucket{le="+Inf"} 3
+latency_seconds_sum 3.55
+latency_seconds_count 3
+# HELP rows_total Rows inserted.
+# TYPE rows_total counter
+rows_total{table="headway"} 3
+rows_total{table="odd \"table\""} 1
+# HELP vehicles Vehicles in the snapshot.
+# TYPE vehicles gauge
+vehicles 1200
+`
+	assert.Equal(t, expected, written(t, r))
+}
+
+func TestRegistry_RegisterTwice(t *testing.T) {
+	r := NewRegistry()
+	r.NewCounter("requests_total", "Requests.", "code").Inc("200")
+	// Declaring the same metric again shares its series
+	r.NewCounter("requests_total", "Requests.", "code").Inc("200")
+	assert.Contains(t, written(t, r), `requests_total{code="200"} 2`)
+	assert.Panics(t, func() { r.NewGauge("requests_total", "Requests.") })
+	assert.Panics(t, func() { r.NewCounter("requests_total", "Requests.", "code").Inc() })
+}
+
+func TestInstrumentHandler(t *testing.T) {
+	h := InstrumentHandler("test_handler", http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		http.Error(w, "bad query", http.StatusBadRequest)
+	}))
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+	rec := httptest.NewRecorder()
+	Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	assert.Equal(t, ContentType, rec.Header().Get("Content-Type"))
+	assert.Contains(t, rec.Body.String(), `http_requests_total{handler="test_handler",code="400"} 1`)
+	assert.Contains(t, rec.Body.String(), `http_request_duration_seconds_count{handler="test_handler"} 1`)
+}
+
+func TestRegistry_WriteFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "metrics")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	r := NewRegistry()
+	r.NewGauge("vehicles", "Vehicles.").Set(3)
+	path := filepath.Join(dir, "job.prom")
+	assert.Nil(t, r.WriteFile(path))
+	contents, err := ioutil.ReadFile(path)
+	assert.Nil(t, err)
+	assert.Equal(t, written(t, r), string(contents))
+}
@@ -24,7 +24,7 @@ func TimestampFrom(ts database.Timestamp) Timestamp {
 
 // MarshalJSON converts a null.Timestamp into a JSON []byte
 func (ts Timestamp) MarshalJSON() ([]byte, error) {
-	if ts.Valid || !ts.Time.IsZero() {
+	if ts.Valid {
 		return []byte(fmt.Sprintf(`"%s"`, ts.Timestamp.Format(database.TimeFormat))), nil
 	}
 	return []byte("null"), nil
@@ -42,7 +42,7 @@ func (ts *Timestamp) UnmarshalJSON(b []byte) error {
 	if err != nil {
 		return err
 	}
-	ts.Timestamp = database.Timestamp{Time: t}
+	ts.Timestamp, ts.Valid = database.Timestamp{Time: t}, true
 	return nil
 }
 
@@ -1,18 +1,225 @@
 package progress
 
 import (
+	"encoding/json"
+	"fmt"
+	"io"
 	"log"
 	"math"
+	"os"
+	"sync"
+	"time"
 )
 
-// PrintAtIntervals prints a progress message every time another ~10%
-// of inputs are processed
-func PrintAtIntervals(completed int, total int, process string) {
-	tenPercentIncrement := int(math.Floor(float64(total / 10)))
-	if tenPercentIncrement == 0 || completed%tenPercentIncrement == 0 {
-		log.Printf(
-			"%s: processed %d/%d tasks (~%f%%)\n",
-			process, completed, total, float64(completed)/float64(total)*100,
-		)
+// Format determines how a Tracker emits its progress reports
+type Format string
+
+const (
+	// TextFormat logs each report as a line of key=value fields
+	TextFormat Format = "text"
+	// JSONFormat writes each report as a single line of JSON
+	JSONFormat Format = "json"
+)
+
+// FormatEnvVar is the environment variable used to choose the default Format
+// of every Tracker in a process, e.g. PROGRESS_FORMAT=json
+const FormatEnvVar = "PROGRESS_FORMAT"
+
+const (
+	// defaultInterval is the longest a Tracker will go without reporting
+	defaultInterval = 30 * time.Second
+	// defaultMinGap is the shortest time between two reports (other than the
+	// final one), so that small or fast tasks don't report on every item
+	defaultMinGap = time.Second
+	// reportEvery is the fraction of the total that triggers a new report
+	reportEvery = 0.1
+)
+
+// Report is a snapshot of a Tracker's progress
+type Report struct {
+	Process    string  `json:"process"`
+	Stage      string  `json:"stage,omitempty"`
+	Completed  int     `json:"completed"`
+	Total      int     `json:"total"`
+	Percent    float64 `json:"percent"`
+	Elapsed    float64 `json:"elapsedSeconds"`
+	ETA        float64 `json:"etaSeconds"`
+	Throughput float64 `json:"perSecond"`
+	Done       bool    `json:"done"`
+}
+
+// String formats the report as a line of key=value fields
+func (r Report) String() string {
+	return fmt.Sprintf(
+		"process=%q stage=%q completed=%d total=%d percent=%.1f elapsed=%s eta=%s rate=%.2f/s done=%t",
+		r.Process, r.Stage, r.Completed, r.Total, r.Percent,
+		seconds(r.Elapsed), seconds(r.ETA), r.Throughput, r.Done,
+	)
+}
+
+// Tracker tracks the progress of a long-running process through one or more
+// named stages. Reports are emitted every time another ~10% of the current stage
+// is completed, at least every 30 seconds, and when each stage is finished.
+// A Tracker is safe for concurrent use.
+type Tracker struct {
+	mu          sync.Mutex
+	process     string
+	stage       string
+	total       int
+	completed   int
+	start       time.Time
+	lastReport  time.Time
+	lastPercent float64
+	finished    bool
+	format      Format
+	out         io.Writer
+	interval    time.Duration
+	minGap      time.Duration
+	now         func() time.Time
+}
+
+// NewTracker creates a Tracker for `total` items of work in the given process
+// Example Usage:
+// tracker := progress.NewTracker("Inserting into DB", len(rows), progress.FormatOption(progress.JSONFormat))
+func NewTracker(process string, total int, options ...func(*Tracker)) *Tracker {
+	t := &Tracker{
+		process:  process,
+		total:    total,
+		format:   Format(os.Getenv(FormatEnvVar)),
+		out:      os.Stderr,
+		interval: defaultInterval,
+		minGap:   defaultMinGap,
+		now:      time.Now,
+	}
+	for _, option := range options {
+		option(t)
+	}
+	t.start = t.now()
+	t.lastReport = t.start
+	return t
+}
+
+// FormatOption returns a function that can be passed to NewTracker to
+// set the format of its reports
+func FormatOption(format Format) func(*Tracker) {
+	return func(t *Tracker) {
+		t.format = format
+	}
+}
+
+// OutputOption returns a function that can be passed to NewTracker so that
+// JSON reports are written to `out` rather than stderr. Text reports always
+// go through the standard logger.
+func OutputOption(out io.Writer) func(*Tracker) {
+	return func(t *Tracker) {
+		t.out = out
+	}
+}
+
+// IntervalOption returns a function that can be passed to NewTracker to change
+// the longest time the tracker will go without reporting
+func IntervalOption(interval time.Duration) func(*Tracker) {
+	return func(t *Tracker) {
+		t.interval = interval
+	}
+}
+
+// Stage starts a new named stage containing `total` items, resetting the
+// completed count and timings. The previous stage is reported as finished.
+func (t *Tracker) Stage(name string, total int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.stage != "" && !t.finished {
+		t.emit(true)
+	}
+	t.stage, t.total, t.completed, t.finished = name, total, 0, false
+	t.start = t.now()
+	t.lastReport, t.lastPercent = t.start, 0
+}
+
+// Increment marks a single item as completed
+func (t *Tracker) Increment() {
+	t.Add(1)
+}
+
+// Add marks `n` more items as completed, emitting a report if one is due
+func (t *Tracker) Add(n int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.completed += n
+	report := t.report(false)
+	now := t.now()
+	sinceLast := now.Sub(t.lastReport)
+	crossedStep := report.Percent >= t.lastPercent+reportEvery*100
+	switch {
+	case t.finished:
+		return
+	case report.Completed >= report.Total && report.Total > 0:
+		t.emit(true)
+	case sinceLast >= t.interval, crossedStep && sinceLast >= t.minGap:
+		t.emit(false)
+	}
+}
+
+// Done emits a final report for the current stage, unless one has already
+// been emitted because all of its items were completed
+func (t *Tracker) Done() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if !t.finished {
+		t.emit(true)
 	}
 }
+
+// Report returns the tracker's current progress without emitting it
+func (t *Tracker) Report() Report {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.report(false)
+}
+
+// report must be called whilst holding t.mu
+func (t *Tracker) report(done bool) Report {
+	elapsed := t.now().Sub(t.start).Seconds()
+	r := Report{
+		Process:   t.process,
+		Stage:     t.stage,
+		Completed: t.completed,
+		Total:     t.total,
+		Elapsed:   elapsed,
+		Done:      done,
+	}
+	if t.total > 0 {
+		r.Percent = math.Min(100, float64(t.completed)/float64(t.total)*100)
+	}
+	if elapsed > 0 {
+		r.Throughput = float64(t.completed) / elapsed
+	}
+	if r.Throughput > 0 && t.completed < t.total {
+		r.ETA = float64(t.total-t.completed) / r.Throughput
+	}
+	return r
+}
+
+// emit must be called whilst holding t.mu
+func (t *Tracker) emit(done bool) {
+	r := t.report(done)
+	t.lastReport, t.lastPercent, t.finished = t.now(), r.Percent, done
+	if t.format == JSONFormat {
+		line, err := json.Marshal(r)
+		if err != nil {
+			log.Printf("progress.Tracker: failed to marshal report: %s", err)
+			return
+		}
+		if _, err := fmt.Fprintln(t.out, string(line)); err != nil {
+			log.Printf("progress.Tracker: failed to write report: %s", err)
+		}
+		return
+	}
+	log.Printf("progress: %s", r)
+}
+
+// seconds formats a number of seconds as a rounded duration
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second)).Round(time.Second)
+}
@@ -0,0 +1,102 @@
+package progress
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeClock returns a clock function that advances by `step` every time it is read
+func fakeClock(step time.Duration) func() time.Time {
+	now := time.Date(2019, 6, 1, 12, 0, 0, 0, time.UTC)
+	return func() time.Time {
+		now = now.Add(step)
+		return now
+	}
+}
+
+// newTestTracker creates a Tracker that writes JSON reports to `out`, using a fake clock
+func newTestTracker(total int, out *bytes.Buffer, step time.Duration) *Tracker {
+	tracker := NewTracker("test", total, FormatOption(JSONFormat), OutputOption(out))
+	tracker.now = fakeClock(step)
+	tracker.start = tracker.now()
+	tracker.lastReport = tracker.start
+	return tracker
+}
+
+func readReports(t *testing.T, out *bytes.Buffer) []Report {
+	var reports []Report
+	for _, line := range strings.Split(strings.TrimSpace(out.String()), "\n") {
+		if line == "" {
+			continue
+		}
+		var r Report
+		assert.NoError(t, json.Unmarshal([]byte(line), &r))
+		reports = append(reports, r)
+	}
+	return reports
+}
+
+func TestTracker_ReportsEveryTenPercent(t *testing.T) {
+	out := &bytes.Buffer{}
+	tracker := newTestTracker(100, out, time.Second)
+	for i := 0; i < 100; i++ {
+		tracker.Increment()
+	}
+	tracker.Done()
+	reports := readReports(t, out)
+	assert.Len(t, reports, 10)
+	assert.Equal(t, 10, reports[0].Completed)
+	last := reports[len(reports)-1]
+	assert.True(t, last.Done)
+	assert.Equal(t, 100, last.Completed)
+	assert.Equal(t, 100.0, last.Percent)
+}
+
+func TestTracker_SmallTotalsDontReportEveryItem(t *testing.T) {
+	out := &bytes.Buffer{}
+	tracker := newTestTracker(5, out, time.Millisecond)
+	for i := 0; i < 5; i++ {
+		tracker.Increment()
+	}
+	reports := readReports(t, out)
+	assert.Len(t, reports, 1)
+	assert.True(t, reports[0].Done)
+}
+
+func TestTracker_ThroughputAndETA(t *testing.T) {
+	tracker := newTestTracker(100, &bytes.Buffer{}, 0)
+	now := tracker.start
+	tracker.now = func() time.Time { return now }
+	now = now.Add(10 * time.Second)
+	tracker.Add(25)
+	r := tracker.Report()
+	assert.Equal(t, 25.0, r.Percent)
+	assert.Equal(t, 10.0, r.Elapsed)
+	assert.Equal(t, 2.5, r.Throughput)
+	assert.Equal(t, 30.0, r.ETA)
+}
+
+func TestTracker_Stages(t *testing.T) {
+	out := &bytes.Buffer{}
+	tracker := newTestTracker(0, out, time.Second)
+	tracker.Stage("fetch", 2)
+	tracker.Add(2)
+	tracker.Stage("label", 4)
+	tracker.Add(1)
+	tracker.Done()
+	reports := readReports(t, out)
+	assert.Len(t, reports, 3)
+	assert.Equal(t, "fetch", reports[0].Stage)
+	assert.True(t, reports[0].Done)
+	assert.Equal(t, "label", reports[1].Stage)
+	assert.False(t, reports[1].Done)
+	assert.Equal(t, 25.0, reports[1].Percent)
+	assert.True(t, reports[2].Done)
+	assert.Equal(t, 1, reports[2].Completed)
+	assert.Equal(t, 4, reports[2].Total)
+}
@@ -0,0 +1,108 @@
+package stats
+
+import (
+	"math"
+	"sort"
+)
+
+// P2Quantile estimates a single quantile of a stream of values in constant memory,
+// using the P² algorithm (Jain & Chlamtac, 1985). It is intended for long-running
+// aggregations, such as journey times observed by a service over a whole day,
+// where keeping every value in memory isn't practical.
+type P2Quantile struct {
+	p     float64
+	count int
+	// Marker heights, actual positions, desired positions and desired position increments
+	heights   [5]float64
+	positions [5]float64
+	desired   [5]float64
+	increment [5]float64
+}
+
+// NewP2Quantile creates an estimator for the q-th quantile (0 < q < 1)
+func NewP2Quantile(q float64) *P2Quantile {
+	return &P2Quantile{
+		p:         q,
+		desired:   [5]float64{1, 1 + 2*q, 1 + 4*q, 3 + 2*q, 5},
+		increment: [5]float64{0, q / 2, q, (1 + q) / 2, 1},
+	}
+}
+
+// Add adds a new observation to the estimator
+func (e *P2Quantile) Add(x float64) {
+	// The first five observations are stored directly as the marker heights
+	if e.count < 5 {
+		e.heights[e.count] = x
+		e.count++
+		if e.count == 5 {
+			sort.Float64s(e.heights[:])
+			for i := range e.positions {
+				e.positions[i] = float64(i + 1)
+			}
+		}
+		return
+	}
+	e.count++
+
+	// Find the cell k that x falls into, adjusting the extreme markers if needed
+	var k int
+	switch {
+	case x < e.heights[0]:
+		e.heights[0], k = x, 0
+	case x >= e.heights[4]:
+		e.heights[4], k = x, 3
+	default:
+		for i := 1; i < 5; i++ {
+			if x < e.heights[i] {
+				k = i - 1
+				break
+			}
+		}
+	}
+	for i := k + 1; i < 5; i++ {
+		e.positions[i]++
+	}
+	for i := range e.desired {
+		e.desired[i] += e.increment[i]
+	}
+
+	// Adjust the heights of the middle markers if they're out of position
+	for i := 1; i <= 3; i++ {
+		d := e.desired[i] - e.positions[i]
+		if (d >= 1 && e.positions[i+1]-e.positions[i] > 1) || (d <= -1 && e.positions[i-1]-e.positions[i] < -1) {
+			sign := math.Copysign(1, d)
+			height := e.parabolic(i, sign)
+			if e.heights[i-1] < height && height < e.heights[i+1] {
+				e.heights[i] = height
+			} else {
+				e.heights[i] = e.linear(i, sign)
+			}
+			e.positions[i] += sign
+		}
+	}
+}
+
+// Value returns the current estimate of the quantile. Until five values have been
+// added, the exact quantile of the values seen so far is returned. With no values,
+// NaN is returned.
+func (e *P2Quantile) Value() float64 {
+	if e.count < 5 {
+		return Percentile(e.heights[:e.count], e.p*100)
+	}
+	return e.heights[2]
+}
+
+// Count returns the number of values added so far
+func (e *P2Quantile) Count() int {
+	return e.count
+}
+
+func (e *P2Quantile) parabolic(i int, d float64) float64 {
+	n, q := e.positions, e.heights
+	return q[i] + d/(n[i+1]-n[i-1])*((n[i]-n[i-1]+d)*(q[i+1]-q[i])/(n[i+1]-n[i])+(n[i+1]-n[i]-d)*(q[i]-q[i-1])/(n[i]-n[i-1]))
+}
+
+func (e *P2Quantile) linear(i int, d float64) float64 {
+	j := i + int(d)
+	return e.heights[i] + d*(e.heights[j]-e.heights[i])/(e.positions[j]-e.positions[i])
+}
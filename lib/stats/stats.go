@@ -0,0 +1,140 @@
+package stats
+
+import (
+	"math"
+	"sort"
+)
+
+// madScale converts the median absolute deviation into a consistent
+// estimator of the standard deviation for normally distributed data
+const madScale = 1.4826
+
+// DefaultOutlierThreshold is the number of scaled MADs from the median beyond
+// which a value is considered an outlier (the commonly used "3.5 rule")
+const DefaultOutlierThreshold = 3.5
+
+// IntsToFloats converts a slice of integers into a slice of float64s
+func IntsToFloats(nums []int) []float64 {
+	floats := make([]float64, len(nums))
+	for i, num := range nums {
+		floats[i] = float64(num)
+	}
+	return floats
+}
+
+// Mean returns the arithmetic mean of values. Empty slices return NaN.
+func Mean(values []float64) float64 {
+	if len(values) == 0 {
+		return math.NaN()
+	}
+	total := 0.0
+	for _, v := range values {
+		total += v
+	}
+	return total / float64(len(values))
+}
+
+// Percentile returns the p-th percentile (0 <= p <= 100) of values, linearly
+// interpolating between the closest ranks. The input slice is not modified.
+// Empty slices return NaN.
+func Percentile(values []float64, p float64) float64 {
+	return percentileOfSorted(sortedCopy(values), p)
+}
+
+// Percentiles returns several percentiles of values, only sorting them once
+func Percentiles(values []float64, ps ...float64) []float64 {
+	sorted := sortedCopy(values)
+	results := make([]float64, len(ps))
+	for i, p := range ps {
+		results[i] = percentileOfSorted(sorted, p)
+	}
+	return results
+}
+
+// Median returns the 50th percentile of values. The input slice is not modified.
+func Median(values []float64) float64 {
+	return Percentile(values, 50)
+}
+
+// TrimmedMean discards the lowest and highest `proportion` (0 <= proportion < 0.5)
+// of values and returns the mean of those remaining
+func TrimmedMean(values []float64, proportion float64) float64 {
+	sorted := sortedCopy(values)
+	cut := trimCount(len(sorted), proportion)
+	return Mean(sorted[cut : len(sorted)-cut])
+}
+
+// WinsorizedMean replaces the lowest and highest `proportion` (0 <= proportion < 0.5)
+// of values with the closest remaining value, and returns the mean of the result
+func WinsorizedMean(values []float64, proportion float64) float64 {
+	sorted := sortedCopy(values)
+	cut := trimCount(len(sorted), proportion)
+	if len(sorted) == 0 {
+		return math.NaN()
+	}
+	low, high := sorted[cut], sorted[len(sorted)-cut-1]
+	for i := 0; i < cut; i++ {
+		sorted[i], sorted[len(sorted)-i-1] = low, high
+	}
+	return Mean(sorted)
+}
+
+// MAD returns the median absolute deviation of values from their median
+func MAD(values []float64) float64 {
+	median := Median(values)
+	deviations := make([]float64, len(values))
+	for i, v := range values {
+		deviations[i] = math.Abs(v - median)
+	}
+	return Median(deviations)
+}
+
+// RejectOutliers splits values into those within `threshold` scaled MADs of the
+// median and those outside it. If more than half of the values are identical the
+// MAD is 0, in which case only values equal to the median are kept.
+func RejectOutliers(values []float64, threshold float64) (kept []float64, rejected []float64) {
+	if len(values) == 0 {
+		return nil, nil
+	}
+	median, mad := Median(values), MAD(values)*madScale
+	for _, v := range values {
+		deviation := math.Abs(v - median)
+		if (mad == 0 && deviation > 0) || (mad > 0 && deviation/mad > threshold) {
+			rejected = append(rejected, v)
+		} else {
+			kept = append(kept, v)
+		}
+	}
+	return kept, rejected
+}
+
+func sortedCopy(values []float64) []float64 {
+	sorted := make([]float64, len(values))
+	copy(sorted, values)
+	sort.Float64s(sorted)
+	return sorted
+}
+
+func percentileOfSorted(sorted []float64, p float64) float64 {
+	if len(sorted) == 0 {
+		return math.NaN()
+	}
+	p = math.Max(0, math.Min(100, p))
+	rank := p / 100 * float64(len(sorted)-1)
+	lower := int(math.Floor(rank))
+	upper := int(math.Ceil(rank))
+	fraction := rank - float64(lower)
+	return sorted[lower] + fraction*(sorted[upper]-sorted[lower])
+}
+
+// trimCount returns the number of values to remove from each end of a
+// slice of length n when trimming `proportion` of it
+func trimCount(n int, proportion float64) int {
+	proportion = math.Max(0, math.Min(0.5, proportion))
+	cut := int(math.Floor(float64(n) * proportion))
+	// Always leave at least one value
+	if n > 0 && 2*cut >= n {
+		cut = (n - 1) / 2
+	}
+	return cut
+}
@@ -0,0 +1,73 @@
+package stats
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPercentile(t *testing.T) {
+	values := []float64{15, 20, 35, 40, 50}
+	assert.Equal(t, 15.0, Percentile(values, 0))
+	assert.Equal(t, 35.0, Percentile(values, 50))
+	assert.Equal(t, 29.0, Percentile(values, 40))
+	assert.Equal(t, 50.0, Percentile(values, 100))
+	assert.True(t, math.IsNaN(Percentile(nil, 50)))
+	assert.Equal(t, []float64{35, 46}, Percentiles(values, 50, 90))
+}
+
+func TestMedianDoesNotModifyInput(t *testing.T) {
+	values := []float64{3, 1, 2}
+	assert.Equal(t, 2.0, Median(values))
+	assert.Equal(t, []float64{3, 1, 2}, values)
+}
+
+func TestTrimmedAndWinsorizedMeans(t *testing.T) {
+	values := []float64{1, 2, 3, 4, 5, 6, 7, 8, 9, 1000}
+	assert.Equal(t, 5.5, TrimmedMean(values, 0.1))
+	// 1 -> 2 and 1000 -> 9
+	assert.Equal(t, 5.5, WinsorizedMean(values, 0.1))
+	assert.Equal(t, 104.5, TrimmedMean(values, 0))
+	// Trimming everything still leaves the middle value
+	assert.Equal(t, 2.0, TrimmedMean([]float64{1, 2, 3}, 0.5))
+}
+
+func TestRejectOutliers(t *testing.T) {
+	values := []float64{300, 310, 290, 305, 295, 2000, 30}
+	kept, rejected := RejectOutliers(values, DefaultOutlierThreshold)
+	assert.Equal(t, []float64{300, 310, 290, 305, 295}, kept)
+	assert.Equal(t, []float64{2000, 30}, rejected)
+	assert.Equal(t, 10.0, MAD(values))
+}
+
+func TestWeightedQuantile(t *testing.T) {
+	values := []float64{10, 20, 30}
+	assert.Equal(t, 20.0, WeightedQuantile(values, []float64{1, 1, 1}, 0.5))
+	assert.Equal(t, 30.0, WeightedQuantile(values, []float64{1, 1, 8}, 0.5))
+	assert.Equal(t, 10.0, WeightedQuantile(values, []float64{8, 1, 1}, 0.5))
+	assert.True(t, math.IsNaN(WeightedQuantile(values, []float64{1}, 0.5)))
+}
+
+func TestP2Quantile(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	median, p90 := NewP2Quantile(0.5), NewP2Quantile(0.9)
+	var values []float64
+	for i := 0; i < 10000; i++ {
+		v := rng.Float64() * 1000
+		values = append(values, v)
+		median.Add(v)
+		p90.Add(v)
+	}
+	assert.Equal(t, 10000, median.Count())
+	assert.InDelta(t, Percentile(values, 50), median.Value(), 15)
+	assert.InDelta(t, Percentile(values, 90), p90.Value(), 15)
+
+	// With fewer than five values the exact quantile is returned
+	small := NewP2Quantile(0.5)
+	small.Add(3)
+	small.Add(1)
+	small.Add(2)
+	assert.Equal(t, 2.0, small.Value())
+}
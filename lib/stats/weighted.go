@@ -0,0 +1,44 @@
+package stats
+
+import (
+	"math"
+	"sort"
+)
+
+// WeightedQuantile returns the q-th quantile (0 <= q <= 1) of values, where each value
+// counts in proportion to the weight at the same index. Values with a weight of 0 or
+// less are ignored. Returns NaN if there are no positively weighted values, or if
+// values and weights have different lengths.
+func WeightedQuantile(values []float64, weights []float64, q float64) float64 {
+	if len(values) != len(weights) {
+		return math.NaN()
+	}
+	type weighted struct {
+		value  float64
+		weight float64
+	}
+	var items []weighted
+	total := 0.0
+	for i, v := range values {
+		if weights[i] > 0 {
+			items = append(items, weighted{v, weights[i]})
+			total += weights[i]
+		}
+	}
+	if len(items) == 0 {
+		return math.NaN()
+	}
+	sort.Slice(items, func(i, j int) bool {
+		return items[i].value < items[j].value
+	})
+	// Return the first value at which the cumulative weight reaches q of the total weight
+	target := math.Max(0, math.Min(1, q)) * total
+	cumulative := 0.0
+	for _, item := range items {
+		cumulative += item.weight
+		if cumulative >= target {
+			return item.value
+		}
+	}
+	return items[len(items)-1].value
+}
@@ -0,0 +1,392 @@
+package testhelper
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"transport/lib/bus"
+	"transport/lib/bustime"
+	"transport/lib/database"
+	"transport/lib/geo"
+	"transport/lib/nulltypes"
+
+	"gopkg.in/guregu/null.v3"
+)
+
+// metresPerDegree is the (approximate) length of one degree of latitude
+const metresPerDegree = 111320
+
+// siriTimeFormat is the timestamp format used in MTA SIRI responses
+const siriTimeFormat = "2006-01-02T15:04:05.000-07:00"
+
+// FleetConfig describes the service that a FleetSimulator should generate
+type FleetConfig struct {
+	RouteID     string
+	DirectionID int
+	// The stops served by the route, in order
+	Stops []bustime.BusStop
+	// Speed in metres per second between each pair of consecutive stops. If there are
+	// fewer speeds than segments, the last speed is used for the remaining segments.
+	SegmentSpeeds []float64
+	// The first trip leaves the first stop at Start, and every `Headway` after that
+	Start   time.Time
+	Headway time.Duration
+	Trips   int
+	// How long each vehicle waits at every intermediate stop
+	DwellTime time.Duration
+	// How often each vehicle reports its position (defaults to 30 seconds)
+	ReportInterval time.Duration
+	// The probability (0 to 1) that any single position report is lost
+	SkipProbability float64
+	// Standard deviation of the noise added to reported positions, in metres
+	GPSNoise float64
+	// Seed for all of the random choices made by the simulator
+	Seed int64
+	// If set, reports describe the vehicle's progress towards this stop until it has been
+	// passed, as when the MTA feed is queried with a MonitoringRef. Otherwise (and once the
+	// stop has been passed) reports describe the stop the vehicle is at or heading towards.
+	MonitoredStopID string
+}
+
+// StopArrival is the ground truth time that a simulated vehicle arrived at and departed from a stop
+type StopArrival struct {
+	TripID     string
+	VehicleRef string
+	StopID     string
+	Arrival    time.Time
+	Departure  time.Time
+}
+
+// Simulation is the output of a FleetSimulator: every position report that the
+// vehicles sent (sorted by timestamp) and the times they actually reached each stop
+type Simulation struct {
+	Movements []bus.VehicleJourney
+	Arrivals  []StopArrival
+	// The time each vehicle finished its trip, keyed by VehicleRef
+	finished map[string]time.Time
+}
+
+// FleetSimulator generates synthetic, but realistic, vehicle movements for a single
+// route and direction, so that the code consuming live data can be tested without it
+type FleetSimulator struct {
+	config   FleetConfig
+	rng      *rand.Rand
+	line     geo.Polyline
+	distance []float64
+}
+
+// NewFleetSimulator validates the config and creates a simulator for it
+func NewFleetSimulator(config FleetConfig) (*FleetSimulator, error) {
+	if len(config.Stops) < 2 {
+		return nil, fmt.Errorf("testhelper.NewFleetSimulator: at least 2 stops are required, got %d", len(config.Stops))
+	}
+	if len(config.SegmentSpeeds) == 0 {
+		return nil, fmt.Errorf("testhelper.NewFleetSimulator: at least one segment speed is required")
+	}
+	for _, speed := range config.SegmentSpeeds {
+		if speed <= 0 {
+			return nil, fmt.Errorf("testhelper.NewFleetSimulator: segment speeds must be positive, got %f", speed)
+		}
+	}
+	if config.ReportInterval == 0 {
+		config.ReportInterval = 30 * time.Second
+	}
+	points := make([]geo.Point, len(config.Stops))
+	for i, stop := range config.Stops {
+		points[i] = stop.Point()
+	}
+	line := geo.NewPolyline(points)
+	distance := make([]float64, len(points))
+	for i := 1; i < len(points); i++ {
+		distance[i] = distance[i-1] + geo.Haversine(points[i-1], points[i])
+	}
+	return &FleetSimulator{config: config, rng: rand.New(rand.NewSource(config.Seed)), line: line, distance: distance}, nil
+}
+
+// Run simulates every trip, returning the reports sent and the ground truth arrival times
+func (fs *FleetSimulator) Run() Simulation {
+	sim := Simulation{finished: map[string]time.Time{}}
+	for trip := 0; trip < fs.config.Trips; trip++ {
+		movements, arrivals := fs.simulateTrip(trip)
+		sim.Movements = append(sim.Movements, movements...)
+		sim.Arrivals = append(sim.Arrivals, arrivals...)
+		last := arrivals[len(arrivals)-1]
+		sim.finished[last.VehicleRef] = last.Arrival
+	}
+	sortMovements(sim.Movements)
+	return sim
+}
+
+// simulateTrip drives a single vehicle along the route
+func (fs *FleetSimulator) simulateTrip(trip int) ([]bus.VehicleJourney, []StopArrival) {
+	c := fs.config
+	tripID := fmt.Sprintf("%s_SIM-%03d", c.RouteID, trip)
+	vehicleRef := fmt.Sprintf("%s_%d", operatorRef(c.RouteID), 9000+trip)
+	// Work out when the vehicle arrives at, and departs from, each stop
+	arrivals := make([]StopArrival, len(c.Stops))
+	t := c.Start.Add(time.Duration(trip) * c.Headway)
+	for i, stop := range c.Stops {
+		if i > 0 {
+			travel := (fs.distance[i] - fs.distance[i-1]) / fs.speed(i-1)
+			t = t.Add(time.Duration(travel * float64(time.Second)))
+		}
+		arrival := StopArrival{TripID: tripID, VehicleRef: vehicleRef, StopID: stop.ID, Arrival: t, Departure: t}
+		if i > 0 && i < len(c.Stops)-1 {
+			arrival.Departure = t.Add(c.DwellTime)
+		}
+		arrivals[i] = arrival
+		t = arrival.Departure
+	}
+	// Send a report every ReportInterval, until the vehicle reaches the final stop
+	var movements []bus.VehicleJourney
+	end := arrivals[len(arrivals)-1].Arrival
+	for t := arrivals[0].Departure; ; t = t.Add(c.ReportInterval) {
+		if t.After(end) {
+			t = end
+		}
+		if fs.rng.Float64() >= c.SkipProbability {
+			movements = append(movements, fs.report(tripID, vehicleRef, arrivals, t))
+		}
+		if t.Equal(end) {
+			break
+		}
+	}
+	return movements, arrivals
+}
+
+// report builds the position report a vehicle would send at time `t`
+func (fs *FleetSimulator) report(tripID, vehicleRef string, arrivals []StopArrival, t time.Time) bus.VehicleJourney {
+	c := fs.config
+	// Find the stop the vehicle is at or heading towards, and how far along the route it is
+	next, distanceAlong := len(arrivals)-1, fs.distance[len(arrivals)-1]
+	for i := 1; i < len(arrivals); i++ {
+		if t.Before(arrivals[i].Arrival) {
+			elapsed := t.Sub(arrivals[i-1].Departure).Seconds()
+			next, distanceAlong = i, fs.distance[i-1]+math.Max(0, elapsed)*fs.speed(i-1)
+			break
+		}
+		if t.Before(arrivals[i].Departure) {
+			next, distanceAlong = i, fs.distance[i]
+			break
+		}
+	}
+	// The monitored call is the stop the report describes, which may be several stops ahead
+	call := next
+	for i := next; i < len(c.Stops); i++ {
+		if c.Stops[i].ID == c.MonitoredStopID {
+			call = i
+		}
+	}
+	position := fs.addNoise(fs.line.PointAt(distanceAlong))
+	return bus.VehicleJourney{
+		LineRef:                  null.StringFrom(c.RouteID),
+		DirectionRef:             null.IntFrom(int64(c.DirectionID)),
+		TripID:                   null.StringFrom(tripID),
+		PublishedLineName:        null.StringFrom(bus.RemoveAgencyID(c.RouteID)),
+		OperatorRef:              null.StringFrom(operatorRef(c.RouteID)),
+		OriginRef:                null.StringFrom(c.Stops[0].ID),
+		DestinationRef:           null.StringFrom(c.Stops[len(c.Stops)-1].ID),
+		OriginAimedDepartureTime: nulltypes.TimestampFrom(database.Timestamp{Time: arrivals[0].Departure}),
+		SituationRef:             nulltypes.StringSliceFrom([]string{}),
+		Longitude:                null.FloatFrom(position.Longitude),
+		Latitude:                 null.FloatFrom(position.Latitude),
+		ProgressRate:             null.StringFrom("normalProgress"),
+		Occupancy:                null.StringFrom(""),
+		VehicleRef:               null.StringFrom(vehicleRef),
+		ExpectedArrivalTime:      nulltypes.TimestampFrom(database.Timestamp{Time: arrivals[call].Arrival}),
+		ExpectedDepartureTime:    nulltypes.TimestampFrom(database.Timestamp{Time: arrivals[call].Departure}),
+		DistanceFromStop:         null.IntFrom(int64(math.Round(fs.distance[call] - distanceAlong))),
+		NumberOfStopsAway:        null.IntFrom(int64(call - next)),
+		StopPointRef:             null.StringFrom(c.Stops[call].ID),
+		Timestamp:                nulltypes.TimestampFrom(database.Timestamp{Time: t}),
+	}
+}
+
+// speed returns the speed of the vehicle along the segment starting at stop `i`
+func (fs *FleetSimulator) speed(i int) float64 {
+	if i >= len(fs.config.SegmentSpeeds) {
+		return fs.config.SegmentSpeeds[len(fs.config.SegmentSpeeds)-1]
+	}
+	return fs.config.SegmentSpeeds[i]
+}
+
+// addNoise moves `p` by a normally distributed distance in both the north-south
+// and east-west directions
+func (fs *FleetSimulator) addNoise(p geo.Point) geo.Point {
+	if fs.config.GPSNoise == 0 {
+		return p
+	}
+	north, east := fs.rng.NormFloat64()*fs.config.GPSNoise, fs.rng.NormFloat64()*fs.config.GPSNoise
+	return geo.Point{
+		Latitude:  p.Latitude + north/metresPerDegree,
+		Longitude: p.Longitude + east/(metresPerDegree*math.Cos(p.Latitude*math.Pi/180)),
+	}
+}
+
+// Merge combines the simulations of several routes into a single simulation
+func Merge(simulations ...Simulation) Simulation {
+	merged := Simulation{finished: map[string]time.Time{}}
+	for _, sim := range simulations {
+		merged.Movements = append(merged.Movements, sim.Movements...)
+		merged.Arrivals = append(merged.Arrivals, sim.Arrivals...)
+		for vehicleRef, finished := range sim.finished {
+			merged.finished[vehicleRef] = finished
+		}
+	}
+	sortMovements(merged.Movements)
+	return merged
+}
+
+// At returns the most recent report from each vehicle that is still in service at `t`,
+// sorted by VehicleRef. This is what a live feed would have shown at that time.
+func (sim Simulation) At(t time.Time) []bus.VehicleJourney {
+	latest := map[string]bus.VehicleJourney{}
+	for _, mvmt := range sim.Movements {
+		if mvmt.Timestamp.After(t) {
+			break
+		}
+		latest[mvmt.VehicleRef.String] = mvmt
+	}
+	var active []bus.VehicleJourney
+	for vehicleRef, mvmt := range latest {
+		if finished, ok := sim.finished[vehicleRef]; ok && finished.Before(t) {
+			continue
+		}
+		active = append(active, mvmt)
+	}
+	sort.Slice(active, func(i, j int) bool {
+		return active[i].VehicleRef.String < active[j].VehicleRef.String
+	})
+	return active
+}
+
+// SIRI returns the vehicles active at `t` as an MTA SIRI vehicle monitoring response
+func (sim Simulation) SIRI(t time.Time, validFor time.Duration) ([]byte, error) {
+	activity := []siriVehicleActivity{}
+	for _, mvmt := range sim.At(t) {
+		activity = append(activity, toSIRI(mvmt))
+	}
+	var response siriResponse
+	response.Siri.ServiceDelivery.ResponseTimestamp = siriTime(t)
+	response.Siri.ServiceDelivery.VehicleMonitoringDelivery = []siriVehicleMonitoringDelivery{{
+		VehicleActivity:   activity,
+		ResponseTimestamp: siriTime(t),
+		ValidUntil:        siriTime(t.Add(validFor)),
+	}}
+	return json.Marshal(response)
+}
+
+// ServeSIRI creates an httptest server that responds to every request with a SIRI
+// vehicle monitoring response for the time returned by `clock`, e.g. pass
+// time.Now to replay a simulation that starts now in real time.
+func (sim Simulation) ServeSIRI(clock func() time.Time) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		response, err := sim.SIRI(clock(), 30*time.Second)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if _, err := w.Write(response); err != nil {
+			log.Printf("testhelper.ServeSIRI: error writing response: %s", err)
+		}
+	}))
+}
+
+func sortMovements(movements []bus.VehicleJourney) {
+	sort.SliceStable(movements, func(i, j int) bool {
+		return movements[i].Timestamp.Before(movements[j].Timestamp.Time)
+	})
+}
+
+// operatorRef extracts the operator from a route ID, e.g. "MTA NYCT_B41" => "MTA NYCT"
+func operatorRef(routeID string) string {
+	return strings.Split(routeID, "_")[0]
+}
+
+func siriTime(t time.Time) string {
+	return t.In(database.TimeLoc).Format(siriTimeFormat)
+}
+
+func toSIRI(mvmt bus.VehicleJourney) siriVehicleActivity {
+	var activity siriVehicleActivity
+	mvj := &activity.MonitoredVehicleJourney
+	mvj.LineRef = mvmt.LineRef.String
+	mvj.DirectionRef = strconv.FormatInt(mvmt.DirectionRef.Int64, 10)
+	mvj.FramedVehicleJourneyRef.DataFrameRef = mvmt.OriginAimedDepartureTime.In(database.TimeLoc).Format(database.DateFormat)
+	mvj.FramedVehicleJourneyRef.DatedVehicleJourneyRef = mvmt.TripID.String
+	mvj.PublishedLineName = []string{mvmt.PublishedLineName.String}
+	mvj.OperatorRef = mvmt.OperatorRef.String
+	mvj.OriginRef = mvmt.OriginRef.String
+	mvj.DestinationRef = mvmt.DestinationRef.String
+	mvj.OriginAimedDepartureTime = siriTime(mvmt.OriginAimedDepartureTime.Time)
+	mvj.SituationRef = []struct{ SituationSimpleRef string }{}
+	mvj.Monitored = true
+	mvj.VehicleLocation.Longitude = mvmt.Longitude.Float64
+	mvj.VehicleLocation.Latitude = mvmt.Latitude.Float64
+	mvj.ProgressRate = mvmt.ProgressRate.String
+	mvj.VehicleRef = mvmt.VehicleRef.String
+	mvj.MonitoredCall.ExpectedArrivalTime = siriTime(mvmt.ExpectedArrivalTime.Time)
+	mvj.MonitoredCall.ExpectedDepartureTime = siriTime(mvmt.ExpectedDepartureTime.Time)
+	mvj.MonitoredCall.DistanceFromStop = mvmt.DistanceFromStop.Int64
+	mvj.MonitoredCall.NumberOfStopsAway = mvmt.NumberOfStopsAway.Int64
+	mvj.MonitoredCall.StopPointRef = mvmt.StopPointRef.String
+	activity.RecordedAtTime = siriTime(mvmt.Timestamp.Time)
+	return activity
+}
+
+// The subset of the MTA's SIRI vehicle monitoring format produced by the simulator
+type siriResponse struct {
+	Siri struct {
+		ServiceDelivery struct {
+			ResponseTimestamp         string
+			VehicleMonitoringDelivery []siriVehicleMonitoringDelivery
+		}
+	}
+}
+
+type siriVehicleMonitoringDelivery struct {
+	VehicleActivity   []siriVehicleActivity
+	ResponseTimestamp string
+	ValidUntil        string
+}
+
+type siriVehicleActivity struct {
+	MonitoredVehicleJourney struct {
+		LineRef                 string
+		DirectionRef            string
+		FramedVehicleJourneyRef struct {
+			DataFrameRef           string
+			DatedVehicleJourneyRef string
+		}
+		PublishedLineName        []string
+		OperatorRef              string
+		OriginRef                string
+		DestinationRef           string
+		OriginAimedDepartureTime string
+		SituationRef             []struct{ SituationSimpleRef string }
+		Monitored                bool
+		VehicleLocation          struct {
+			Longitude float64
+			Latitude  float64
+		}
+		ProgressRate  string
+		VehicleRef    string
+		MonitoredCall struct {
+			ExpectedArrivalTime   string
+			ExpectedDepartureTime string
+			DistanceFromStop      int64
+			NumberOfStopsAway     int64
+			StopPointRef          string
+		}
+	}
+	RecordedAtTime string
+}
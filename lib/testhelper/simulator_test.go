@@ -0,0 +1,135 @@
+package testhelper
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"testing"
+	"time"
+	"transport/lib/bustime"
+	"transport/lib/database"
+	"transport/lib/geo"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Four stops roughly 500m apart, heading north
+var simulatedStops = []bustime.BusStop{
+	{ID: "MTA_1", Name: "First", Latitude: 40.7000, Longitude: -73.9900},
+	{ID: "MTA_2", Name: "Second", Latitude: 40.7045, Longitude: -73.9900},
+	{ID: "MTA_3", Name: "Third", Latitude: 40.7090, Longitude: -73.9900},
+	{ID: "MTA_4", Name: "Fourth", Latitude: 40.7135, Longitude: -73.9900},
+}
+
+var simulationStart = time.Date(2019, 6, 3, 8, 0, 0, 0, database.TimeLoc)
+
+func simulate(t *testing.T, config FleetConfig) Simulation {
+	config.RouteID, config.Stops, config.Start = "MTA NYCT_B41", simulatedStops, simulationStart
+	fs, err := NewFleetSimulator(config)
+	assert.NoError(t, err)
+	return fs.Run()
+}
+
+func TestFleetSimulator_GroundTruthArrivals(t *testing.T) {
+	sim := simulate(t, FleetConfig{SegmentSpeeds: []float64{5, 10}, Headway: 10 * time.Minute, Trips: 2, DwellTime: 20 * time.Second})
+	assert.Len(t, sim.Arrivals, 8)
+	first := sim.Arrivals[:4]
+	segment := geo.Haversine(simulatedStops[0].Point(), simulatedStops[1].Point())
+	// ~500m at 5m/s, then dwell, then ~500m at 10m/s
+	assert.Equal(t, simulationStart, first[0].Departure)
+	assert.InDelta(t, segment/5, first[1].Arrival.Sub(simulationStart).Seconds(), 0.01)
+	assert.Equal(t, 20*time.Second, first[1].Departure.Sub(first[1].Arrival))
+	assert.InDelta(t, segment/10, first[2].Arrival.Sub(first[1].Departure).Seconds(), 1)
+	assert.Equal(t, first[3].Arrival, first[3].Departure)
+	// The second trip follows one headway behind
+	assert.Equal(t, 10*time.Minute, sim.Arrivals[5].Arrival.Sub(first[1].Arrival))
+	assert.NotEqual(t, first[0].VehicleRef, sim.Arrivals[4].VehicleRef)
+}
+
+func TestFleetSimulator_Movements(t *testing.T) {
+	sim := simulate(t, FleetConfig{SegmentSpeeds: []float64{5}, Headway: 5 * time.Minute, Trips: 1})
+	mvmts := sim.Movements
+	assert.True(t, len(mvmts) > 5)
+	// Vehicles start at the first stop, heading for the second
+	assert.Equal(t, "MTA_2", mvmts[0].StopPointRef.String)
+	assert.InDelta(t, geo.Haversine(simulatedStops[0].Point(), simulatedStops[1].Point()), mvmts[0].DistanceFromStop.Int64, 1)
+	// Stops are visited in order, and the distance to the next stop decreases until it changes
+	for i := 1; i < len(mvmts); i++ {
+		prev, cur := mvmts[i-1], mvmts[i]
+		assert.True(t, cur.StopPointRef.String >= prev.StopPointRef.String)
+		if cur.StopPointRef == prev.StopPointRef {
+			assert.True(t, cur.DistanceFromStop.Int64 <= prev.DistanceFromStop.Int64)
+		}
+	}
+	last := mvmts[len(mvmts)-1]
+	assert.Equal(t, "MTA_4", last.StopPointRef.String)
+	assert.Equal(t, int64(0), last.DistanceFromStop.Int64)
+	assert.Equal(t, sim.Arrivals[3].Arrival, last.Timestamp.Time)
+}
+
+func TestFleetSimulator_NumberOfStopsAway(t *testing.T) {
+	// Reports describe the next stop by default, which is never any stops away
+	for _, mvmt := range simulate(t, FleetConfig{SegmentSpeeds: []float64{5}, Trips: 1}).Movements {
+		assert.Equal(t, int64(0), mvmt.NumberOfStopsAway.Int64)
+	}
+	sim := simulate(t, FleetConfig{SegmentSpeeds: []float64{5}, Trips: 1, MonitoredStopID: "MTA_3"})
+	first := sim.Movements[0]
+	assert.Equal(t, "MTA_3", first.StopPointRef.String)
+	assert.Equal(t, int64(1), first.NumberOfStopsAway.Int64)
+	assert.InDelta(t, geo.Haversine(simulatedStops[0].Point(), simulatedStops[1].Point())+geo.Haversine(simulatedStops[1].Point(), simulatedStops[2].Point()), first.DistanceFromStop.Int64, 1)
+	assert.Equal(t, sim.Arrivals[2].Arrival, first.ExpectedArrivalTime.Time)
+	// The count falls as stops are passed, until the monitored stop is passed too
+	for i := 1; i < len(sim.Movements); i++ {
+		prev, cur := sim.Movements[i-1], sim.Movements[i]
+		if cur.StopPointRef == prev.StopPointRef {
+			assert.True(t, cur.NumberOfStopsAway.Int64 <= prev.NumberOfStopsAway.Int64)
+		}
+	}
+	last := sim.Movements[len(sim.Movements)-1]
+	assert.Equal(t, "MTA_4", last.StopPointRef.String)
+	assert.Equal(t, int64(0), last.NumberOfStopsAway.Int64)
+}
+
+func TestFleetSimulator_SkippedReportsAndNoise(t *testing.T) {
+	clean := simulate(t, FleetConfig{SegmentSpeeds: []float64{5}, Trips: 1})
+	noisy := simulate(t, FleetConfig{SegmentSpeeds: []float64{5}, Trips: 1, SkipProbability: 0.5, GPSNoise: 10, Seed: 1})
+	assert.True(t, len(noisy.Movements) < len(clean.Movements))
+	assert.Equal(t, clean.Arrivals, noisy.Arrivals)
+	// The same seed always produces the same movements
+	assert.Equal(t, noisy.Movements, simulate(t, FleetConfig{SegmentSpeeds: []float64{5}, Trips: 1, SkipProbability: 0.5, GPSNoise: 10, Seed: 1}).Movements)
+}
+
+func TestSimulation_ServeSIRI(t *testing.T) {
+	sim := simulate(t, FleetConfig{SegmentSpeeds: []float64{5}, Headway: 2 * time.Minute, Trips: 3})
+	now := simulationStart.Add(3 * time.Minute)
+	assert.Len(t, sim.At(now), 2)
+	server := sim.ServeSIRI(func() time.Time { return now })
+	defer server.Close()
+	resp, err := http.Get(server.URL)
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	assert.NoError(t, err)
+	var parsed struct {
+		Siri struct {
+			ServiceDelivery struct {
+				VehicleMonitoringDelivery []struct {
+					VehicleActivity []struct {
+						MonitoredVehicleJourney struct {
+							LineRef       string
+							DirectionRef  string
+							MonitoredCall struct{ StopPointRef string }
+						}
+					}
+					ValidUntil string
+				}
+			}
+		}
+	}
+	assert.NoError(t, json.Unmarshal(body, &parsed))
+	delivery := parsed.Siri.ServiceDelivery.VehicleMonitoringDelivery[0]
+	assert.Len(t, delivery.VehicleActivity, 2)
+	assert.Equal(t, "MTA NYCT_B41", delivery.VehicleActivity[0].MonitoredVehicleJourney.LineRef)
+	assert.Equal(t, "0", delivery.VehicleActivity[0].MonitoredVehicleJourney.DirectionRef)
+	assert.Equal(t, "2019-06-03T08:03:30.000-04:00", delivery.ValidUntil)
+}
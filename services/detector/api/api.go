@@ -2,16 +2,22 @@ package api
 
 import (
 	"database/sql"
+	"detector/fetch"
 	"detector/request"
 	"detector/response"
 	"encoding/json"
+	"fmt"
 	"io/ioutil"
 	"log"
 	"net/http"
+	"strconv"
+	"sync/atomic"
 	"time"
 	"transport/lib/bustime"
 	"transport/lib/database"
+	"transport/lib/health"
 	"transport/lib/iohelper"
+	"transport/lib/metrics"
 	"transport/lib/network"
 
 	"github.com/rs/cors"
@@ -20,18 +26,26 @@ import (
 )
 
 var bt = bustime.NewClient(iohelper.GetEnv("MTA_API_KEY"))
-var stopInfo []byte
-var parsedStopInfo map[string]map[int][]bustime.BusStop
+// The stop details as JSON ([]byte), and as a map of routeID -> directionID -> []BusStop.
+// They are fetched in the background, so both are empty until the fetch has finished.
+var stopInfo, parsedStopInfo atomic.Value
 var db *sql.DB
 
+// Number of subscribers waiting to be notified
+var activeSubscriptions = metrics.NewGauge("active_subscriptions", "Subscribers waiting to be notified.")
+
 func Start() {
 	r := mux.NewRouter()
-	fetchStopDetails()
-	r.HandleFunc("/getStops", fetchStops)
-	r.HandleFunc("/subscribe", subscribe).Methods("POST")
+	// Fetching every stop takes a while, so serve requests (and report not ready) in the meantime
+	go fetchStopDetails()
+	r.Handle("/getStops", metrics.InstrumentHandlerFunc("getStops", fetchStops))
+	r.Handle("/subscribe", metrics.InstrumentHandlerFunc("subscribe", subscribe)).Methods("POST")
+	r.Handle("/metrics", metrics.Handler())
 	// Open a DB connection and schedule it to be closed after the program returns
 	db = database.OpenDBConnection()
 	defer db.Close()
+	r.Handle("/healthz", health.NewChecker())
+	r.Handle("/readyz", newReadinessChecker(db))
 	corsOptions := cors.New(cors.Options{
 		AllowedOrigins: []string{"*"},
 	})
@@ -41,26 +55,54 @@ func Start() {
 	log.Fatal(http.ListenAndServe(port, handler))
 }
 
+// newReadinessChecker creates the checks behind /readyz: the service is ready once the
+// stop details have been fetched, while the DB can be reached, and while livedataloader
+// is serving up to date journeys
+func newReadinessChecker(db *sql.DB) *health.Checker {
+	ready := health.NewChecker()
+	ready.Register("stops", func() error {
+		if loadStopInfo() == nil {
+			return fmt.Errorf("stop details haven't been fetched yet")
+		}
+		return nil
+	})
+	ready.Register("database", health.PingCheck(db))
+	ready.Register("livedataloader", fetch.LiveDataCheck)
+	return ready
+}
+
 func fetchStopDetails() {
 	agencies := bt.GetAgencies()
 	log.Printf("%d agencies fetched\n", len(agencies))
 	routes := bt.GetRoutes(agencies...)
 	log.Printf("%d routes fetched\n", len(agencies))
 	stopDetails := bt.GetStops(routes...)
-	parsedStopInfo = stopDetails
 	jsonStopDetails, err := json.Marshal(stopDetails)
 	if err != nil {
 		log.Fatalf("failed to convert stop details into JSON due to error: %v", err)
 	}
-	stopInfo = jsonStopDetails
+	parsedStopInfo.Store(stopDetails)
+	stopInfo.Store(jsonStopDetails)
 }
 
+// loadStopInfo returns the stop details as JSON, or nil if they haven't been fetched yet
+func loadStopInfo() []byte {
+	info, _ := stopInfo.Load().([]byte)
+	return info
+}
+
+// stopsRetryAfter is how long clients are asked to wait before retrying while the stops load
+const stopsRetryAfter = 30 * time.Second
+
 func fetchStops(w http.ResponseWriter, r *http.Request) {
-	if stopInfo == nil {
-		w.Write([]byte("Stops not yet fetched"))
+	info := loadStopInfo()
+	if info == nil {
+		w.Header().Set("Retry-After", strconv.Itoa(int(stopsRetryAfter.Seconds())))
+		http.Error(w, "Stops not yet fetched", http.StatusServiceUnavailable)
 		return
 	}
-	w.Write(stopInfo)
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(info)
 }
 
 func subscribe(w http.ResponseWriter, r *http.Request) {
@@ -78,6 +120,8 @@ func subscribe(w http.ResponseWriter, r *http.Request) {
 }
 
 func notifyAtOptimalDeparture(params request.JourneyParams) {
+	activeSubscriptions.Add(1)
+	defer activeSubscriptions.Add(-1)
 	// Mock notification code
 	time.Sleep(10 * time.Second)
 	t := time.Now().In(database.TimeLoc)
@@ -88,7 +132,7 @@ func notifyAtOptimalDeparture(params request.JourneyParams) {
 	})
 	//// Get the list of stops for the requested route and direction
 	//log.Println("Extracting list of stops from cache...")
-	//stopList := parsedStopInfo[params.RouteID][params.DirectionID]
+	//stopList := parsedStopInfo.Load().(map[string]map[int][]bustime.BusStop)[params.RouteID][params.DirectionID]
 	//// Get average time to travel between stops
 	//// avgTime, err := calc.AvgTimeBetweenStops(stopList, params, db)
 	//avgTime := 1039
@@ -5,9 +5,10 @@ import (
 	"detector/fetch"
 	"detector/request"
 	"log"
+	"math"
 	"transport/lib/bus"
 	"transport/lib/bustime"
-	"transport/lib/math"
+	"transport/lib/stats"
 )
 
 type Journey struct {
@@ -15,29 +16,66 @@ type Journey struct {
 	PostStop bus.LabelledJourney
 }
 
+// JourneyTimeSummary describes the distribution of historical journey times
+// (in seconds) between two stops, once outliers have been removed
+type JourneyTimeSummary struct {
+	P50      float64
+	P90      float64
+	Count    int
+	Outliers int
+}
+
 // Get the average time taken for vehicles to travel between the two stops
 // around the requested arrival time
 func AvgTimeBetweenStops(stopList []bustime.BusStop, jp request.JourneyParams, db *sql.DB) (int, error) {
-	log.Printf("Calculating average time between requested stops")
+	summary, err := JourneyTimesBetweenStops(stopList, jp, db)
+	if err != nil {
+		return 0, err
+	}
+	if summary.Count == 0 {
+		return 0, nil
+	}
+	return int(math.Round(summary.P50)), nil
+}
+
+// JourneyTimesBetweenStops summarises the times taken for vehicles to travel between
+// the two stops around the requested arrival time
+func JourneyTimesBetweenStops(stopList []bustime.BusStop, jp request.JourneyParams, db *sql.DB) (JourneyTimeSummary, error) {
+	log.Printf("Calculating journey times between requested stops")
 	// Fetch movements that match the requested parameters
 	mvmts, err := fetch.MovementsInWindow(db, stopList, jp)
 	if err != nil {
-		return 0, nil
+		return JourneyTimeSummary{}, err
 	}
-	// Split movements up by vehicleID
-	splitMvmts := SplitMovementsByVehicleID(mvmts)
+	// Split movements up into individual trips, so that journeys can't span a layover
+	trips := bus.NewSegmenter().LabelledTrips(mvmts)
 	// Get a list containing how long each 'fromStop' -> 'toStop' journey took in seconds
-	journeyTimes := mvmtsToJourneyTimes(splitMvmts, stopList, jp)
-	avgJourneyTime := math.SliceMedian(journeyTimes)
-	return avgJourneyTime, nil
+	journeyTimes := mvmtsToJourneyTimes(trips, stopList, jp)
+	summary := SummariseJourneyTimes(journeyTimes)
+	log.Printf(
+		"Journey times: p50 %.0fs, p90 %.0fs (%d journeys, %d outliers removed)\n",
+		summary.P50, summary.P90, summary.Count, summary.Outliers,
+	)
+	return summary, nil
+}
+
+// SummariseJourneyTimes removes outlying journey times (e.g. a vehicle that went out of
+// service part way through the journey) and returns the percentiles of those remaining
+func SummariseJourneyTimes(journeyTimes []int) JourneyTimeSummary {
+	kept, rejected := stats.RejectOutliers(stats.IntsToFloats(journeyTimes), stats.DefaultOutlierThreshold)
+	if len(kept) == 0 {
+		return JourneyTimeSummary{Outliers: len(rejected)}
+	}
+	percentiles := stats.Percentiles(kept, 50, 90)
+	return JourneyTimeSummary{P50: percentiles[0], P90: percentiles[1], Count: len(kept), Outliers: len(rejected)}
 }
 
-// Takes a list of movements (split by vehicleID) and returns a list containing the durations of individual
+// Takes a list of trips and returns a list containing the durations of individual
 // journeys between the two stops in the request.JourneyParams struct
-func mvmtsToJourneyTimes(splitMvmts map[string][]bus.LabelledJourney, stopList []bustime.BusStop, jp request.JourneyParams) []int {
+func mvmtsToJourneyTimes(trips []bus.LabelledTrip, stopList []bustime.BusStop, jp request.JourneyParams) []int {
 	var journeyTimes []int
-	for _, mvmts := range splitMvmts {
-		journeys := getPreAndPostStopMvmts(mvmts, stopList, jp)
+	for _, trip := range trips {
+		journeys := getPreAndPostStopMvmts(trip.Movements, stopList, jp)
 		for _, journey := range journeys {
 			preStop, postStop := journey.PreStop.Timestamp, journey.PostStop.Timestamp
 			journeyTimes = append(journeyTimes, int(postStop.Sub(preStop.Time).Seconds()))
@@ -106,12 +144,3 @@ func stopsAfter(stopID string, stopList []bustime.BusStop) map[string]bool {
 	}
 	return result
 }
-
-// Takes a list of movements and returns those same movements as a map, keyed by vehicleID
-func SplitMovementsByVehicleID(mvmts []bus.LabelledJourney) map[string][]bus.LabelledJourney {
-	splitMvmts := make(map[string][]bus.LabelledJourney)
-	for _, mvmt := range mvmts {
-		splitMvmts[mvmt.VehicleRef.String] = append(splitMvmts[mvmt.VehicleRef.String], mvmt)
-	}
-	return splitMvmts
-}
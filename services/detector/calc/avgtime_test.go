@@ -2,27 +2,14 @@ package calc
 
 import (
 	"testing"
-	"transport/lib/bus"
 
 	"github.com/stretchr/testify/assert"
-	"gopkg.in/guregu/null.v3"
 )
 
-func TestSplitMovementsByVehicleID(t *testing.T) {
-	mvmts := []bus.LabelledJourney{
-		{VehicleRef: null.StringFrom("A"), StopPointRef: null.StringFrom("A1")},
-		{VehicleRef: null.StringFrom("B"), StopPointRef: null.StringFrom("B1")},
-		{VehicleRef: null.StringFrom("B"), StopPointRef: null.StringFrom("B2")},
-	}
-	expected := map[string][]bus.LabelledJourney{
-		"A": {
-			{VehicleRef: null.StringFrom("A"), StopPointRef: null.StringFrom("A1")},
-		},
-		"B": {
-			{VehicleRef: null.StringFrom("B"), StopPointRef: null.StringFrom("B1")},
-			{VehicleRef: null.StringFrom("B"), StopPointRef: null.StringFrom("B2")},
-		},
-	}
-	actual := SplitMovementsByVehicleID(mvmts)
-	assert.Equal(t, expected, actual)
+func TestSummariseJourneyTimes(t *testing.T) {
+	// The 4000s journey is a bus that went out of service part way through
+	journeyTimes := []int{600, 620, 580, 610, 640, 590, 4000, 700}
+	expected := JourneyTimeSummary{P50: 610, P90: 664, Count: 7, Outliers: 1}
+	assert.Equal(t, expected, SummariseJourneyTimes(journeyTimes))
+	assert.Equal(t, JourneyTimeSummary{}, SummariseJourneyTimes(nil))
 }
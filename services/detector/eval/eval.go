@@ -23,8 +23,12 @@ import (
 	"github.com/VividCortex/ewma"
 )
 
-func Evaluate() {
-	log.Println("Evaluation mode...")
+// Evaluate runs the notification evaluation for a set of randomly generated journeys.
+// All random choices are made using a generator seeded with `seed`, so passing the
+// same seed selects the same journeys from the same set of live data.
+func Evaluate(seed int64) {
+	log.Printf("Evaluation mode (seed: %d)...", seed)
+	rng := math.NewRand(seed)
 	// Open a DB connection and schedule it to be closed after the program returns
 	db := database.OpenDBConnection()
 	defer db.Close()
@@ -36,13 +40,14 @@ func Evaluate() {
 	wg.Add(numJourneys)
 	log.Printf("Evaluating %d journeys...", numJourneys)
 	for i := 0; i < numJourneys; i++ {
-		params := generateRandomParams(bt)
-		go performJourneyEvaluation(params, bt, db, wg)
+		params := generateRandomParams(bt, rng)
+		go performJourneyEvaluation(params, bt, db, &wg)
 	}
 	wg.Wait()
 }
 
-func performJourneyEvaluation(params request.JourneyParams, bt *bustime.Client, db *sql.DB, wg sync.WaitGroup) {
+func performJourneyEvaluation(params request.JourneyParams, bt *bustime.Client, db *sql.DB, wg *sync.WaitGroup) {
+	defer wg.Done()
 	// Fetch the list of stops for the requested route and direction
 	stops := bt.GetStops(params.RouteID)[params.RouteID][params.DirectionID]
 	// Get average time to travel between stops
@@ -159,7 +164,7 @@ func NotificationEvalToInterface(entries []NotificationEval) []interface{} {
 	return r
 }
 
-func generateRandomParams(bt *bustime.Client) request.JourneyParams {
+func generateRandomParams(bt *bustime.Client, rng *rand.Rand) request.JourneyParams {
 	log.Println("Generating random parameter set...")
 	rj, err := fetch.RawJourneys()
 	if err != nil {
@@ -170,7 +175,7 @@ func generateRandomParams(bt *bustime.Client) request.JourneyParams {
 	var stops []bustime.BusStop
 	for {
 		// Select a random journey from the list of currently active ones
-		randomMvmt := rjArr[rand.Intn(len(rjArr))]
+		randomMvmt := rjArr[rng.Intn(len(rjArr))]
 		routeID := randomMvmt.Get("LineRef").String()
 		if !validRouteID(routeID) {
 			continue
@@ -188,10 +193,10 @@ func generateRandomParams(bt *bustime.Client) request.JourneyParams {
 	}
 	// Pick random destination stop
 	stopsAfterSource := bustime.ExtractStops("after", params.FromStop, false, stops)
-	destStop := stopsAfterSource[rand.Intn(math.MaxInt(len(stopsAfterSource)-1, 1))]
+	destStop := stopsAfterSource[rng.Intn(math.MaxInt(len(stopsAfterSource)-1, 1))]
 	params.ToStop = destStop
 	// Pick random arrival time
-	delay := time.Duration(math.RandInRange(60, 300)) * time.Minute
+	delay := time.Duration(math.RandInRange(rng, 60, 300)) * time.Minute
 	params.ArrivalTime = database.Timestamp{Time: time.Now().In(database.TimeLoc).Add(delay)}
 	log.Printf("Selected random parameter set: %s", params.String())
 	return params
@@ -0,0 +1,45 @@
+package fetch
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"transport/lib/bus"
+)
+
+const alertsURL = "http://d.zeshan.me:8090/api/v1/alerts"
+
+// RouteAlerts returns the service alerts currently affecting the route in the given direction
+func RouteAlerts(routeID string, directionID int) ([]bus.ServiceAlert, error) {
+	req, err := http.NewRequest("GET", alertsURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	query := req.URL.Query()
+	query.Add("LineRef", routeID)
+	query.Add("DirectionRef", strconv.Itoa(directionID))
+	req.URL.RawQuery = query.Encode()
+
+	client := &http.Client{}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("error fetching service alerts: received response with status: %s", resp.Status)
+	}
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("error reading service alerts response: %s", err)
+	}
+	var alerts []bus.ServiceAlert
+	err = json.Unmarshal(body, &alerts)
+	if err != nil {
+		return nil, fmt.Errorf("error unmarshalling service alerts response: %s", err)
+	}
+	return alerts, nil
+}
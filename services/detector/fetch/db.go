@@ -8,12 +8,30 @@ import (
 	"time"
 	"transport/lib/bus"
 	"transport/lib/bustime"
+	"transport/lib/calendar"
+	"transport/lib/dates"
 
 	"github.com/lib/pq"
 )
 
 const arrivalWindow = 2 * time.Hour
 
+// serviceCalendar classifies the days that historical movements were recorded on,
+// so that e.g. Sunday movements aren't used to predict a weekday journey
+var serviceCalendar = loadCalendar()
+
+func loadCalendar() *calendar.Calendar {
+	sc, err := dates.ServiceCalendarFromEnv()
+	if err != nil {
+		log.Fatalf("Failed to load service calendar: %s", err)
+	}
+	cal, err := calendar.FromEnv(sc)
+	if err != nil {
+		log.Fatalf("Failed to load calendar: %s", err)
+	}
+	return cal
+}
+
 func MovementsInWindow(db *sql.DB, stopList []bustime.BusStop, jp request.JourneyParams) ([]bus.LabelledJourney, error) {
 	fromHour, toHour := jp.ArrivalTime.Add(-arrivalWindow).Hour(), jp.ArrivalTime.Add(arrivalWindow).Hour()
 	log.Printf("Fetching movements in window: %d to %d", fromHour, toHour)
@@ -39,5 +57,19 @@ func MovementsInWindow(db *sql.DB, stopList []bustime.BusStop, jp request.Journe
 		return nil, err
 	}
 	log.Printf("Succesfully fetched movements")
-	return journeys, nil
+	return filterByDayType(journeys, jp), nil
+}
+
+// filterByDayType keeps only the movements recorded on the same type of service day as
+// the requested arrival time. If there's no history for that type of day (e.g. a newly
+// added special event), all of the movements are used instead.
+func filterByDayType(journeys []bus.LabelledJourney, jp request.JourneyParams) []bus.LabelledJourney {
+	dayType := serviceCalendar.ClassifyTime(jp.ArrivalTime.Time)
+	matching := bus.FilterByDayType(journeys, serviceCalendar, dayType)
+	if len(matching) == 0 {
+		log.Printf("No movements found for day type '%s', using all %d movements", dayType, len(journeys))
+		return journeys
+	}
+	log.Printf("Using %d of %d movements with day type '%s'", len(matching), len(journeys), dayType)
+	return matching
 }
@@ -4,16 +4,32 @@ import (
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
+	"log"
 	"net/http"
-	"strings"
+	"strconv"
+	"time"
 	"transport/lib/bus"
+	"transport/lib/metrics"
 
 	"github.com/tidwall/gjson"
 )
 
 const baseURL = "http://d.zeshan.me:8090/api/v1/vehicles"
 
-func LiveJourneys(routeID string, directionID int) (map[string]bus.VehicleJourney, error) {
+// Metrics describing requests for live data made to livedataloader
+var (
+	fetchDuration = metrics.NewHistogram("vehicle_fetch_duration_seconds", "Time taken to fetch live journeys from livedataloader.", nil)
+	fetchErrors   = metrics.NewCounter("vehicle_fetch_errors_total", "Fetches of live journeys from livedataloader that failed.")
+)
+
+// LiveJourneys fetches the vehicles currently on the route, keyed by VehicleRef
+func LiveJourneys(routeID string, directionID int) (journeys map[string]bus.VehicleJourney, err error) {
+	defer fetchDuration.ObserveSince(time.Now())
+	defer func() {
+		if err != nil {
+			fetchErrors.Inc()
+		}
+	}()
 	// Create GET request
 	req, err := http.NewRequest("get", baseURL, nil)
 	if err != nil {
@@ -24,10 +40,8 @@ func LiveJourneys(routeID string, directionID int) (map[string]bus.VehicleJourne
 	// Build up the required query string
 	query := req.URL.Query()
 	query.Add("LineRef", routeID)
-	query.Add("DirectionRef", string(directionID))
-	// Remove encodings of digits
-	queryStr := strings.Replace(query.Encode(), "%0", "", -1)
-	req.URL.RawQuery = queryStr
+	query.Add("DirectionRef", strconv.Itoa(directionID))
+	req.URL.RawQuery = query.Encode()
 
 	fmt.Println(req.URL)
 
@@ -41,6 +55,10 @@ func LiveJourneys(routeID string, directionID int) (map[string]bus.VehicleJourne
 	if resp.Status != "200 OK" {
 		return nil, fmt.Errorf("error fetching live journeys: received response with status: %s", resp.Status)
 	}
+	// Stale data is still the best guess of where vehicles are, so use it but make a note
+	if resp.Header.Get("X-Snapshot-Stale") == "true" {
+		log.Printf("warning: live journeys are stale, last fetched %s seconds ago", resp.Header.Get("X-Snapshot-Age"))
+	}
 
 	// Read in the response body
 	body, err := ioutil.ReadAll(resp.Body)
@@ -49,14 +67,38 @@ func LiveJourneys(routeID string, directionID int) (map[string]bus.VehicleJourne
 	}
 
 	// Unmarshal the JSON response into a slice of VehicleJourney structs
-	var journeys []bus.VehicleJourney
-	err = json.Unmarshal(body, &journeys)
+	var fetched []bus.VehicleJourney
+	err = json.Unmarshal(body, &fetched)
 	if err != nil {
 		return nil, fmt.Errorf("error unmarshalling live journeys response: %s", err)
 	}
 
 	// Return a map of the VehicleJourney structs keyed by VehicleRef
-	return bus.VehicleJourneysByVehicleRef(journeys), nil
+	return bus.VehicleJourneysByVehicleRef(fetched), nil
+}
+
+// healthClient is used to check livedataloader, giving up long before a health check would time out
+var healthClient = &http.Client{Timeout: 3 * time.Second}
+
+// LiveDataCheck returns an error if livedataloader can't be reached, has no data yet,
+// or is serving stale data. Only a single vehicle is requested to keep the check cheap.
+func LiveDataCheck() error {
+	return liveDataCheck(baseURL)
+}
+
+func liveDataCheck(url string) error {
+	resp, err := healthClient.Get(url + "?limit=1&fields=VehicleRef")
+	if err != nil {
+		return fmt.Errorf("livedataloader can't be reached: %s", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("livedataloader responded with status: %s", resp.Status)
+	}
+	if resp.Header.Get("X-Snapshot-Stale") == "true" {
+		return fmt.Errorf("live journeys are stale, last fetched %s seconds ago", resp.Header.Get("X-Snapshot-Age"))
+	}
+	return nil
 }
 
 func RawJourneys() (gjson.Result, error) {
@@ -3,8 +3,11 @@ package main
 import (
 	"detector/api"
 	"detector/eval"
+	"flag"
 	"log"
 	"os"
+	"transport/lib/math"
+	"transport/lib/metrics"
 )
 
 func main() {
@@ -13,8 +16,20 @@ func main() {
 	}
 	mode := os.Args[1]
 	if mode == "-e" {
-		eval.Evaluate()
+		eval.Evaluate(parseSeed(os.Args[2:]))
+		metrics.PushToFile()
 	} else {
 		api.Start()
 	}
 }
+
+// parseSeed reads the optional --seed argument passed after the evaluation mode flag,
+// defaulting to a time-based seed if one isn't given
+func parseSeed(args []string) int64 {
+	evalFlags := flag.NewFlagSet("evaluation", flag.ExitOnError)
+	seed := evalFlags.Int64("seed", math.TimeSeed(), "seed for the random journey selection, pass the same seed to reproduce a run")
+	if err := evalFlags.Parse(args); err != nil {
+		log.Fatalf("error parsing evaluation arguments: %s", err)
+	}
+	return *seed
+}
@@ -44,9 +44,11 @@ func monitorBuses(ticker *time.Ticker, params request.JourneyParams, stopList []
 	waitingToStart, startedJourneys := map[string]time.Time{}, map[string]time.Time{}
 	stopsBeforeSource := stringhelper.SliceToSet(bustime.ExtractStops("before", params.FromStop, true, stopList))
 	stopsAfterDest := stringhelper.SliceToSet(bustime.ExtractStops("after", params.ToStop, false, stopList))
+	warnedAbout := map[string]bool{}
 	for {
 		select {
 		case <-ticker.C:
+			WarnAboutDisruptions(params, warnedAbout)
 			allJourneys, journeysBeforeStop, journeysApproachingStop := FetchJourneySets(params, stopsBeforeSource)
 			FindApproachingVehicles(journeysApproachingStop, waitingToStart)
 			DetectStartedJourneys(waitingToStart, startedJourneys, allJourneys, params)
@@ -88,6 +90,38 @@ func monitorBuses(ticker *time.Ticker, params request.JourneyParams, stopList []
 	}
 }
 
+// WarnAboutDisruptions sends the subscriber an alert for each disruption on their route
+// that they haven't already been warned about
+func WarnAboutDisruptions(params request.JourneyParams, warnedAbout map[string]bool) {
+	alerts, err := fetch.RouteAlerts(params.RouteID, params.DirectionID)
+	if err != nil {
+		log.Printf("error fetching service alerts: %s", err)
+		return
+	}
+	for _, alert := range NewAlerts(alerts, warnedAbout) {
+		log.Printf("Warning subscriber about disruption '%s': %s", alert.SituationNumber, alert.Summary)
+		response.SendDisruptionAlert(params, response.DisruptionAlert{
+			SituationNumber: alert.SituationNumber,
+			Severity:        alert.Severity,
+			Summary:         alert.Summary,
+			Description:     alert.Description,
+			PublishedAt:     alert.PublicationStart,
+		})
+	}
+}
+
+// NewAlerts returns the alerts that aren't in `warnedAbout`, and adds them to it
+func NewAlerts(alerts []bus.ServiceAlert, warnedAbout map[string]bool) []bus.ServiceAlert {
+	var fresh []bus.ServiceAlert
+	for _, alert := range alerts {
+		if !warnedAbout[alert.SituationNumber] {
+			warnedAbout[alert.SituationNumber] = true
+			fresh = append(fresh, alert)
+		}
+	}
+	return fresh
+}
+
 func UpdateAverageJourneyTime(startedJourneys map[string]time.Time, allJourneys map[string]bus.VehicleJourney,
 	stopsAfterDest map[string]bool, movingAverageJourneyTime ewma.MovingAverage) {
 	log.Println("Updating moving averages using any vehicles that have completed the route segment...")
@@ -16,7 +16,10 @@ var client = pusher.Client{
 	Secure:  true,
 }
 
-const departureNotification = "departureNotification"
+const (
+	departureNotification = "departureNotification"
+	disruptionAlert       = "disruptionAlert"
+)
 
 type Notification struct {
 	VehicleID            string             `json:"vehicleID"`
@@ -30,3 +33,19 @@ func SendNotification(params request.JourneyParams, notification Notification) {
 		log.Printf("error sending departure notification: %v", err)
 	}
 }
+
+// DisruptionAlert warns a subscriber about a service alert affecting their route
+type DisruptionAlert struct {
+	SituationNumber string             `json:"situationNumber"`
+	Severity        string             `json:"severity"`
+	Summary         string             `json:"summary"`
+	Description     string             `json:"description"`
+	PublishedAt     database.Timestamp `json:"publishedAt"`
+}
+
+func SendDisruptionAlert(params request.JourneyParams, alert DisruptionAlert) {
+	err := client.Trigger(params.Channel, disruptionAlert, alert)
+	if err != nil {
+		log.Printf("error sending disruption alert: %v", err)
+	}
+}
@@ -0,0 +1,100 @@
+package events
+
+import (
+	"labeller/labels"
+	"labeller/stopdistance"
+	"math"
+	"time"
+	"transport/lib/bus"
+	"transport/lib/database"
+)
+
+// Extract takes a slice of trips (see bus.Segmenter) and returns a StopEvent for every stop
+// that each vehicle visited. If a vehicle went past a stop without reporting from it, the
+// arrival time is extrapolated in the same way as labels.Create.
+// The stop a trip starts at is skipped, as the vehicle was already there when it started reporting.
+func Extract(trips []bus.Trip, stopDistances map[stopdistance.Key]float64, averageStopDistances map[string]int) (events []bus.StopEvent) {
+	for _, trip := range trips {
+		events = append(events, extractForTrip(trip, stopDistances, averageStopDistances)...)
+	}
+	return events
+}
+
+// Returns the StopEvents for a single trip
+func extractForTrip(trip bus.Trip, stopDistances map[stopdistance.Key]float64, averageStopDistances map[string]int) []bus.StopEvent {
+	var events []bus.StopEvent
+	mvmts := trip.Movements
+	start := 0
+	if len(mvmts) > 0 && atStop(mvmts[0]) {
+		start = endOfVisit(mvmts, 0)
+	}
+	for i := start; i < len(mvmts); {
+		end := endOfVisit(mvmts, i)
+		event, found := eventForVisit(trip, mvmts, i, end, stopDistances, averageStopDistances)
+		if found {
+			events = append(events, event)
+		}
+		i = end
+	}
+	return events
+}
+
+// eventForVisit returns the StopEvent for the stop that mvmts[start:end] were heading towards.
+// If the vehicle didn't report from the stop, the arrival is extrapolated using mvmts[end],
+// the first movement heading to the following stop. If there isn't one, the trip ended
+// before the vehicle reached the stop and no event is returned.
+func eventForVisit(trip bus.Trip, mvmts []bus.VehicleJourney, start int, end int, stopDistances map[stopdistance.Key]float64, averageStopDistances map[string]int) (bus.StopEvent, bool) {
+	event := bus.StopEvent{
+		RouteID:     trip.Route.RouteID,
+		DirectionID: trip.Route.DirectionID,
+		TripID:      trip.TripID,
+		VehicleRef:  trip.Route.VehicleRef,
+		StopID:      mvmts[start].StopPointRef.String,
+	}
+	firstAtStop, lastAtStop := -1, -1
+	for i := start; i < end; i++ {
+		if atStop(mvmts[i]) {
+			if firstAtStop == -1 {
+				firstAtStop = i
+			}
+			lastAtStop = i
+		}
+	}
+	switch {
+	case firstAtStop != -1:
+		// Perfect stop, the vehicle reported while it was at the stop
+		event.Arrival = mvmts[firstAtStop].Timestamp.Timestamp
+		event.Departure = mvmts[lastAtStop].Timestamp.Timestamp
+	case end < len(mvmts):
+		// Went past stop: need to extrapolate time
+		preStopMvmt, postStopMvmt := &mvmts[end-1], &mvmts[end]
+		timeToStop := labels.GetTimeToStopFromFinalMovement(trip.Route, *preStopMvmt, preStopMvmt, postStopMvmt, stopDistances, averageStopDistances)
+		timeBetweenMvmts := labels.SubtractMvmtTimestamps(postStopMvmt, preStopMvmt)
+		if math.IsNaN(timeToStop) || timeToStop < 0 {
+			timeToStop = 0
+		} else if timeToStop > timeBetweenMvmts {
+			timeToStop = timeBetweenMvmts
+		}
+		arrival := preStopMvmt.Timestamp.Add(time.Duration(timeToStop * float64(time.Second)))
+		event.Arrival = database.Timestamp{Time: arrival}
+		event.Departure = event.Arrival
+		event.Interpolated = true
+	default:
+		return bus.StopEvent{}, false
+	}
+	event.DwellSeconds = int(event.Departure.Sub(event.Arrival.Time).Seconds())
+	return event, true
+}
+
+// endOfVisit returns the index of the first movement after `start` heading to a different stop
+func endOfVisit(mvmts []bus.VehicleJourney, start int) int {
+	end := start + 1
+	for end < len(mvmts) && mvmts[end].StopPointRef.String == mvmts[start].StopPointRef.String {
+		end++
+	}
+	return end
+}
+
+func atStop(mvmt bus.VehicleJourney) bool {
+	return mvmt.DistanceFromStop.Valid && mvmt.DistanceFromStop.Int64 == 0
+}
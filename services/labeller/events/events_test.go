@@ -0,0 +1,75 @@
+package events_test
+
+import (
+	"labeller/events"
+	"labeller/stopdistance"
+	"testing"
+	"time"
+	"transport/lib/bus"
+	"transport/lib/database"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var route = bus.DirectedRoute{RouteID: "M55", DirectionID: 0, VehicleRef: "ABC"}
+
+func mvmt(stopID string, distance int64, minute int, second int) bus.VehicleJourney {
+	return bus.ExampleJourney(bus.StopPointRefOption(stopID), bus.DistanceFromStopOption(distance), bus.TimestampOption(at(minute, second)))
+}
+
+func at(minute int, second int) time.Time {
+	return time.Date(2019, 04, 23, 16, minute, second, 0, time.UTC)
+}
+
+func stamp(minute int, second int) database.Timestamp {
+	return database.Timestamp{Time: at(minute, second)}
+}
+
+// The vehicle reports from the stop twice, so we know both its arrival and departure
+func TestExtract_ReachesStop(t *testing.T) {
+	trips := []bus.Trip{{Route: route, TripID: "T1", Movements: []bus.VehicleJourney{
+		mvmt("1", 200, 30, 0),
+		mvmt("1", 0, 31, 0),
+		mvmt("1", 0, 31, 40),
+		mvmt("2", 300, 32, 0),
+	}}}
+	expected := []bus.StopEvent{{
+		RouteID: "M55", DirectionID: 0, TripID: "T1", VehicleRef: "ABC", StopID: "1",
+		Arrival: stamp(31, 0), Departure: stamp(31, 40), DwellSeconds: 40,
+	}}
+	assert.Equal(t, expected, events.Extract(trips, nil, nil))
+}
+
+// The vehicle goes past stop 1 without reporting from it, so the arrival is extrapolated
+// from the speed between the reports either side of it
+func TestExtract_GoesPastStop(t *testing.T) {
+	stopDistances := map[stopdistance.Key]float64{
+		{RouteID: "M55", DirectionID: 0, FromID: "1", ToID: "2"}: 250.0,
+	}
+	trips := []bus.Trip{{Route: route, TripID: "T1", Movements: []bus.VehicleJourney{
+		mvmt("1", 100, 30, 0),
+		mvmt("2", 150, 30, 20),
+	}}}
+	// 100m to the stop + 100m past it in 20 seconds: 10m/s, so 10 seconds to reach the stop
+	expected := []bus.StopEvent{{
+		RouteID: "M55", DirectionID: 0, TripID: "T1", VehicleRef: "ABC", StopID: "1",
+		Arrival: stamp(30, 10), Departure: stamp(30, 10), DwellSeconds: 0, Interpolated: true,
+	}}
+	assert.Equal(t, expected, events.Extract(trips, stopDistances, nil))
+}
+
+// The trip starts at stop 1 and ends before reaching stop 3, so only stop 2 has an event
+func TestExtract_SkipsUnobservedStops(t *testing.T) {
+	trips := []bus.Trip{{Route: route, TripID: "T1", Movements: []bus.VehicleJourney{
+		mvmt("1", 0, 30, 0),
+		mvmt("2", 200, 31, 0),
+		mvmt("2", 0, 32, 0),
+		mvmt("3", 400, 33, 0),
+		mvmt("3", 100, 34, 0),
+	}}}
+	actual := events.Extract(trips, nil, nil)
+	assert.Len(t, actual, 1)
+	assert.Equal(t, "2", actual[0].StopID)
+	assert.Equal(t, stamp(32, 0), actual[0].Arrival)
+	assert.False(t, actual[0].Interpolated)
+}
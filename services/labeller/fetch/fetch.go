@@ -4,29 +4,35 @@ import (
 	"database/sql"
 	"fmt"
 	"log"
-	"time"
+	"strings"
 	"transport/lib/bus"
 	"transport/lib/database"
 	"transport/lib/dates"
+	"transport/lib/progress"
 )
 
-func DateRange(db *sql.DB, startDate time.Time, lastDate time.Time) [][]bus.VehicleJourney {
-	dates.Printf("Fetching rows from vehicle_journey table with timestamps between %s and %s\n", startDate, lastDate)
-	endDate := lastDate.AddDate(0, 0, 1)
+// DateRange fetches the vehicle journeys for every service day from `first` to `last` (inclusive),
+// returning one slice of journeys per service day
+func DateRange(db *sql.DB, first dates.ServiceDay, last dates.ServiceDay) [][]bus.VehicleJourney {
+	log.Printf("Fetching rows from vehicle_journey table for service days %s to %s\n", first, last)
 	rowCount := 0
 	var journeys [][]bus.VehicleJourney
-	for d := startDate; !dates.Equal(d, endDate); d = d.AddDate(0, 0, 1) {
+	days := dates.Range(first, last)
+	tracker := progress.NewTracker("Fetching vehicle journeys", len(days))
+	for _, d := range days {
 		data := getDataForDate(db, d)
 		rowCount += len(data)
 		journeys = append(journeys, data)
+		tracker.Increment()
 	}
-	dates.Printf("Succesfully fetched rows for timestamps between %s and %s\n", startDate, lastDate)
+	tracker.Done()
+	log.Printf("Succesfully fetched rows for service days %s to %s\n", first, last)
 	log.Printf("Row count: %d", rowCount)
 	return journeys
 }
 
-func getDataForDate(db *sql.DB, date time.Time) []bus.VehicleJourney {
-	log.Printf("Fetching rows for date %s\n", date.Format(database.DateFormat))
+func getDataForDate(db *sql.DB, date dates.ServiceDay) []bus.VehicleJourney {
+	log.Printf("Fetching rows for date %s\n", date)
 	rows := queryByDate(date, db)
 	defer rows.Close()
 	journeys := scanVehicleJournies(rows)
@@ -34,38 +40,21 @@ func getDataForDate(db *sql.DB, date time.Time) []bus.VehicleJourney {
 }
 
 func scanVehicleJournies(rows *sql.Rows) []bus.VehicleJourney {
-	// Don't need to store the entryID from each row, so just write it
-	// to this placeholder and ignore it
-	entryIDPtr := 0
-	var journeys []bus.VehicleJourney
-	for rows.Next() {
-		journey := bus.VehicleJourney{}
-		err := rows.Scan(
-			&journey.LineRef, &journey.DirectionRef, &journey.TripID, &journey.PublishedLineName, &journey.OperatorRef,
-			&journey.OriginRef, &journey.DestinationRef, &journey.OriginAimedDepartureTime, &journey.SituationRef,
-			&journey.Longitude, &journey.Latitude, &journey.ProgressRate, &journey.Occupancy, &journey.VehicleRef,
-			&journey.ExpectedArrivalTime, &journey.ExpectedDepartureTime, &journey.DistanceFromStop,
-			&journey.NumberOfStopsAway, &journey.StopPointRef, &journey.Timestamp, &entryIDPtr,
-		)
-		if err != nil {
-			log.Fatalf("getDataForDate: error whilst scanning row from DB into a struct: %s", err)
-		}
-		journeys = append(journeys, journey)
-	}
-	err := rows.Err()
+	journeys, err := bus.ScanVehicleJourneyRows(rows)
 	if err != nil {
 		log.Fatalf("getDataForDate: error whilst scanning rows from DB: %s\n", err)
 	}
 	return journeys
 }
 
-func queryByDate(start time.Time, db *sql.DB) *sql.Rows {
-	end := start.AddDate(0, 0, 1)
+// queryByDate fetches every row in the half-open interval covered by the service day
+func queryByDate(date dates.ServiceDay, db *sql.DB) *sql.Rows {
+	start, end := date.Bounds()
 	q := fmt.Sprintf(
-		`SELECT * FROM %s WHERE TIMESTAMP BETWEEN '%s 04:00:00' AND '%s 03:59:59' ORDER BY TIMESTAMP ASC`,
+		`SELECT * FROM %s WHERE TIMESTAMP >= '%s' AND TIMESTAMP < '%s' ORDER BY TIMESTAMP ASC`,
 		database.VehicleJourneyTable.Name,
-		start.Format(database.DateFormat),
-		end.Format(database.DateFormat),
+		start.Format(database.TimeFormat),
+		end.Format(database.TimeFormat),
 	)
 	rows, err := db.Query(q)
 	if err != nil {
@@ -73,3 +62,27 @@ func queryByDate(start time.Time, db *sql.DB) *sql.Rows {
 	}
 	return rows
 }
+
+// StopEvents fetches the stop events that arrived at their stop during the service days
+// from `first` to `last` (inclusive)
+func StopEvents(db *sql.DB, first dates.ServiceDay, last dates.ServiceDay) []bus.StopEvent {
+	log.Printf("Fetching rows from %s table for service days %s to %s\n", database.StopEventTable.Name, first, last)
+	q := fmt.Sprintf(
+		`SELECT %s FROM %s WHERE arrival_time >= '%s' AND arrival_time < '%s' ORDER BY arrival_time ASC`,
+		strings.Join(database.StopEventTable.Columns, ", "),
+		database.StopEventTable.Name,
+		first.Start().Format(database.TimeFormat),
+		last.End().Format(database.TimeFormat),
+	)
+	rows, err := db.Query(q)
+	if err != nil {
+		log.Fatalf("StopEvents: error executing SQL query to fetch stop events: %s\n", err)
+	}
+	defer rows.Close()
+	events, err := bus.ScanStopEventRows(rows)
+	if err != nil {
+		log.Fatalf("StopEvents: error whilst scanning rows from DB: %s\n", err)
+	}
+	log.Printf("Row count: %d", len(events))
+	return events
+}
@@ -1,17 +1,19 @@
 package fetch_test
 
 import (
+	"database/sql/driver"
 	"labeller/fetch"
 	"testing"
 	"time"
 	"transport/lib/bus"
 	"transport/lib/database"
+	"transport/lib/dates"
 	"transport/lib/testhelper"
 
 	"github.com/stretchr/testify/assert"
 )
 
-var expectedQuery = `SELECT \* FROM ([^ ])* WHERE TIMESTAMP BETWEEN '([0-9]){4}-(([0-9]){1}|([0-9]){2})-(([0-9]){1}|([0-9]){2}) 04:00:00' AND '([0-9]){4}-(([0-9]){1}|([0-9]){2})-(([0-9]){1}|([0-9]){2}) 03:59:59' ORDER BY TIMESTAMP ASC`
+var expectedQuery = `SELECT \* FROM ([^ ])* WHERE TIMESTAMP >= '2019-06-01 04:00:00' AND TIMESTAMP < '2019-06-02 04:00:00' ORDER BY TIMESTAMP ASC`
 
 func TestShouldGetDateRange(t *testing.T) {
 	colNames := database.VehicleJourneyTable.Columns
@@ -21,7 +23,8 @@ func TestShouldGetDateRange(t *testing.T) {
 
 	// Verify the final returned slice of structs is as expected
 	expected := [][]bus.VehicleJourney{{bus.ExampleVJs[0], bus.ExampleVJs[1]}}
-	actual := fetch.DateRange(db, time.Now(), time.Now())
+	day := dates.DefaultServiceCalendar.Day(2019, 6, 1)
+	actual := fetch.DateRange(db, day, day)
 	assert.Equal(t, expected, actual)
 
 	// Verify the correct query was executed
@@ -29,3 +32,26 @@ func TestShouldGetDateRange(t *testing.T) {
 		t.Errorf("TestShouldGetStopDistances: there were unfulfilled expectations: %s", err)
 	}
 }
+
+func TestShouldGetStopEvents(t *testing.T) {
+	query := `SELECT route_id, .*, interpolated FROM stop_event WHERE arrival_time >= '2019-06-01 04:00:00' AND arrival_time < '2019-06-03 04:00:00' ORDER BY arrival_time ASC`
+	arrival := time.Date(2019, 6, 1, 8, 0, 0, 0, database.TimeLoc)
+	rows := [][]driver.Value{
+		{"MTA NYCT_B41", 0, "TRIP_1", "MTA NYCT_1", "MTA_1", arrival, arrival.Add(30 * time.Second), 30, false},
+		{"MTA NYCT_B41", 0, "TRIP_2", "MTA NYCT_2", "MTA_1", arrival.Add(5 * time.Minute), arrival.Add(5 * time.Minute), 0, true},
+	}
+	db, mock := testhelper.SetupDBMock(t, database.StopEventTable.Columns, rows, query)
+	defer db.Close()
+
+	first := dates.DefaultServiceCalendar.Day(2019, 6, 1)
+	events := fetch.StopEvents(db, first, first.AddDays(1))
+	assert.Len(t, events, 2)
+	assert.Equal(t, "MTA NYCT_2", events[1].VehicleRef)
+	assert.True(t, arrival.Equal(events[0].Arrival.Time))
+	assert.Equal(t, 30, events[0].DwellSeconds)
+	assert.True(t, events[1].Interpolated)
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("TestShouldGetStopEvents: there were unfulfilled expectations: %s", err)
+	}
+}
@@ -6,14 +6,15 @@ import (
 	"transport/lib/bus"
 )
 
-// Create takes a map of journeys partitioned by their DirectedRoute
+// Create takes a slice of trips (see bus.Segmenter)
 // returns: a slice of labelledMovements that can be inserted into the DB.
-func Create(partitionedJourneys map[bus.DirectedRoute][]bus.VehicleJourney, stopDistances map[stopdistance.Key]float64, averageStopDistances map[string]int) (labelledMvmts []bus.LabelledJourney) {
-	for route, mvmts := range partitionedJourneys {
-		if len(mvmts) < 2 {
+// Movements are only labelled using later movements from the same trip.
+func Create(trips []bus.Trip, stopDistances map[stopdistance.Key]float64, averageStopDistances map[string]int) (labelledMvmts []bus.LabelledJourney) {
+	for _, trip := range trips {
+		if len(trip.Movements) < 2 {
 			continue
 		}
-		labelledMvmts = append(labelledMvmts, labelMvmtsForRoute(route, mvmts, stopDistances, averageStopDistances)...)
+		labelledMvmts = append(labelledMvmts, labelMvmtsForRoute(trip.Route, trip.Movements, stopDistances, averageStopDistances)...)
 	}
 	return labelledMvmts
 }
@@ -15,8 +15,9 @@ import (
 
 // Perfect stop case: bus approaches a stop and the distanceToStop goes exactly to 0.
 func TestReachesStop(t *testing.T) {
-	reachesStopSequence := map[bus.DirectedRoute][]bus.VehicleJourney{
-		{"M55", 0, "ABC"}: {
+	reachesStopSequence := []bus.Trip{{
+		Route: bus.DirectedRoute{RouteID: "M55", DirectionID: 0, VehicleRef: "ABC"},
+		Movements: []bus.VehicleJourney{
 			{
 				DistanceFromStop: null.IntFrom(200), StopPointRef: null.StringFrom("1"),
 				Timestamp: nulltypes.TimestampFrom(database.Timestamp{Time: time.Date(2019, 04, 23, 16, 30, 00, 0, time.UTC)}),
@@ -38,7 +39,7 @@ func TestReachesStop(t *testing.T) {
 				Timestamp: nulltypes.TimestampFrom(database.Timestamp{Time: time.Date(2019, 04, 23, 16, 34, 00, 0, time.UTC)}),
 			},
 		},
-	}
+	}}
 	actual := labels.Create(reachesStopSequence, nil, nil)
 	expected := getExpectedLabelledJourneys(reachesStopSequence, []int{180, 120, 60})
 	assert.Equal(t, expected, actual)
@@ -47,8 +48,9 @@ func TestReachesStop(t *testing.T) {
 // Stop Not Reached case: the distance to stop never goes to 0 and the stopID never changes.
 // We shouldn't get any results in this case, we can't know when it got to the stop.
 func TestStopNotReached(t *testing.T) {
-	stopNotReached := map[bus.DirectedRoute][]bus.VehicleJourney{
-		{"M55", 0, "ABC"}: {
+	stopNotReached := []bus.Trip{{
+		Route: bus.DirectedRoute{RouteID: "M55", DirectionID: 0, VehicleRef: "ABC"},
+		Movements: []bus.VehicleJourney{
 			{
 				DistanceFromStop: null.IntFrom(200), StopPointRef: null.StringFrom("1"),
 				Timestamp: nulltypes.TimestampFrom(database.Timestamp{Time: time.Date(2019, 04, 23, 16, 35, 00, 0, time.UTC)}),
@@ -62,7 +64,7 @@ func TestStopNotReached(t *testing.T) {
 				Timestamp: nulltypes.TimestampFrom(database.Timestamp{Time: time.Date(2019, 04, 23, 16, 32, 00, 0, time.UTC)}),
 			},
 		},
-	}
+	}}
 	expected := getExpectedLabelledJourneys(stopNotReached, []int{})
 	actual := labels.Create(stopNotReached, nil, nil)
 	assert.Equal(t, expected, actual)
@@ -72,8 +74,9 @@ func TestGoesPastStop(t *testing.T) {
 	stopDistances := map[stopdistance.Key]float64{
 		{"M55", 0, "1", "2"}: 250.0,
 	}
-	goesPastStop := map[bus.DirectedRoute][]bus.VehicleJourney{
-		{"M55", 0, "ABC"}: {
+	goesPastStop := []bus.Trip{{
+		Route: bus.DirectedRoute{RouteID: "M55", DirectionID: 0, VehicleRef: "ABC"},
+		Movements: []bus.VehicleJourney{
 			{
 				DistanceFromStop: null.IntFrom(200), StopPointRef: null.StringFrom("1"),
 				Timestamp: nulltypes.TimestampFrom(database.Timestamp{Time: time.Date(2019, 04, 23, 16, 38, 00, 0, time.UTC)}),
@@ -91,16 +94,16 @@ func TestGoesPastStop(t *testing.T) {
 				Timestamp: nulltypes.TimestampFrom(database.Timestamp{Time: time.Date(2019, 04, 23, 16, 50, 00, 0, time.UTC)}),
 			},
 		},
-	}
+	}}
 	expected := getExpectedLabelledJourneys(goesPastStop, []int{120, 60, 600})
 	actual := labels.Create(goesPastStop, stopDistances, nil)
 	assert.Equal(t, expected, actual)
 }
 
-func getExpectedLabelledJourneys(sequence map[bus.DirectedRoute][]bus.VehicleJourney, labels []int) (expected []bus.LabelledJourney) {
-	for _, sequence := range sequence {
+func getExpectedLabelledJourneys(trips []bus.Trip, labels []int) (expected []bus.LabelledJourney) {
+	for _, trip := range trips {
 		for i, label := range labels {
-			expected = append(expected, bus.LabelledJourneyFrom(sequence[i], label))
+			expected = append(expected, bus.LabelledJourneyFrom(trip.Movements[i], label))
 		}
 	}
 	return expected
@@ -2,6 +2,7 @@ package main
 
 import (
 	"fmt"
+	"labeller/events"
 	"labeller/fetch"
 	"labeller/labels"
 	"labeller/stopdistance"
@@ -12,17 +13,31 @@ import (
 	"transport/lib/bus"
 	"transport/lib/database"
 	"transport/lib/dates"
+	"transport/lib/metrics"
+	"transport/lib/progress"
 )
 
 var dbConn = database.OpenDBConnection()
-var newYorkLoc, _ = time.LoadLocation("America/New_York")
 
-// Boundary between days is at 4am
-var dayBoundary = 4
+// Metrics describing the movements that were labelled, pushed to METRICS_PATH after each run
+var (
+	movementsKept     = metrics.NewCounter("movements_kept_total", "Movements kept after cleaning.")
+	movementsRejected = metrics.NewCounter("movements_rejected_total", "Movements rejected whilst cleaning, by reason.", "reason")
+)
+var serviceCalendar = loadServiceCalendar()
 
+// DateRange is an inclusive range of service days
 type DateRange struct {
-	Start time.Time
-	End   time.Time
+	First dates.ServiceDay
+	Last  dates.ServiceDay
+}
+
+func loadServiceCalendar() dates.ServiceCalendar {
+	sc, err := dates.ServiceCalendarFromEnv()
+	if err != nil {
+		log.Fatalf("Failed to load service calendar: %s", err)
+	}
+	return sc
 }
 
 func main() {
@@ -38,18 +53,19 @@ func executeMode(mode string, stopDistances map[stopdistance.Key]float64, avgSto
 		processDateRange(dr, stopDistances, avgStopDistances)
 		deleteFromDB(dr)
 	case "single":
-		date, err := time.Parse(database.DateFormat, os.Args[2])
+		day, err := serviceCalendar.ParseDay(os.Args[2])
 		if err != nil {
-			log.Fatalf("%s is not a valid date, make sure you use the format YYYY-MM-DD", os.Args[2])
+			log.Fatal(err)
 		}
-		dr := DateRange{date, date}
+		dr := DateRange{day, day}
 		processDateRange(dr, stopDistances, avgStopDistances)
 		deleteFromDB(dr)
 	case "live":
 		for {
 			sleepUntilProcessingTime()
-			dateToProcess := time.Now().In(newYorkLoc).AddDate(0, 0, -1)
-			dr := DateRange{dateToProcess, dateToProcess}
+			// Process the service day that has just finished
+			dayToProcess := serviceCalendar.DayOf(time.Now()).Prev()
+			dr := DateRange{dayToProcess, dayToProcess}
 			processDateRange(dr, stopDistances, avgStopDistances)
 			deleteFromDB(dr)
 		}
@@ -59,21 +75,44 @@ func executeMode(mode string, stopDistances map[stopdistance.Key]float64, avgSto
 }
 
 func processDateRange(dateRange DateRange, stopDistances map[stopdistance.Key]float64, avgStopDistances map[string]int) {
-	dataForDates := fetch.DateRange(dbConn, dateRange.Start, dateRange.End)
-	labelledJourneys := labelDataForDates(dataForDates, stopDistances, avgStopDistances)
+	dataForDates := fetch.DateRange(dbConn, dateRange.First, dateRange.Last)
+	labelledJourneys, stopEvents := labelDataForDates(dataForDates, stopDistances, avgStopDistances)
 	database.Store(database.LabelledJourneyTable, bus.ExtractEntriesFromLabelledJourney, bus.LabelledJourneyToInterface(labelledJourneys))
+	database.Store(database.StopEventTable, bus.ExtractEntriesFromStopEvent, bus.StopEventToInterface(stopEvents))
+	storeHeadways(dateRange)
+	metrics.PushToFile()
 }
 
-func sleepUntilProcessingTime() {
-	// Sleep until it's 4am
-	t := time.Now().In(newYorkLoc)
-	endDate := t.Day()
-	// If the current time is after 4am, the current day will be cut off *tomorrow* at 4am
-	if t.Hour() >= dayBoundary {
-		endDate += 1
+// storeHeadways computes the headways at every stop from the stop events stored for the date
+// range. These replace the headways livedataloader stored for the range as it happened, as
+// the stop events come from cleaned movements and include the interpolated arrivals.
+func storeHeadways(dateRange DateRange) {
+	stopEvents := fetch.StopEvents(dbConn, dateRange.First, dateRange.Last)
+	headways := bus.NewHeadwayAnalyser().Headways(stopEvents)
+
+	transaction := database.CreateTransaction(dbConn)
+	startStamp := dateRange.First.Start().Format(database.TimeFormat)
+	endStamp := dateRange.Last.End().Format(database.TimeFormat)
+	_, err := transaction.Exec(fmt.Sprintf(
+		"DELETE FROM %s WHERE arrival_time >= '%s' AND arrival_time < '%s'",
+		database.HeadwayTable.Name, startStamp, endStamp,
+	))
+	if err != nil {
+		log.Fatalf("storeHeadways: error whilst deleting live headways: %s\n", err)
 	}
-	// Sleep until the dayBoundary time (4am) has been reached
-	processAtTime := time.Date(t.Year(), t.Month(), endDate, dayBoundary, 0, 0, 0, newYorkLoc)
+	rows := database.CopyIntoDB(database.HeadwayTable, bus.ExtractEntriesFromHeadway, transaction, bus.HeadwayToInterface(headways))
+	err = transaction.Commit()
+	database.RecordCommit(database.HeadwayTable, rows, err)
+	if err != nil {
+		log.Fatal(err)
+	}
+	log.Printf("Stored %d headways between %s and %s\n", len(headways), startStamp, endStamp)
+}
+
+func sleepUntilProcessingTime() {
+	// Sleep until the current service day ends
+	t := time.Now()
+	processAtTime := serviceCalendar.DayOf(t).End()
 	timeToSleep := processAtTime.Sub(t)
 	log.Printf("Sleeping until %s\n", processAtTime.Format(database.TimeFormat))
 	time.Sleep(timeToSleep)
@@ -82,7 +121,7 @@ func sleepUntilProcessingTime() {
 func getHostDateRange() DateRange {
 	hostID, hostCount, totalDateRange := extractCLIArgs()
 	r := calculateHostDateRange(hostID, hostCount, totalDateRange)
-	log.Printf("Labelling from %s to %s\n", r.Start, r.End)
+	log.Printf("Labelling from %s to %s\n", r.First, r.Last)
 	return r
 }
 
@@ -95,8 +134,8 @@ func extractCLIArgs() (hostID int, hostCount int, dateRange DateRange) {
 	if hostIDErr != nil || hostCountErr != nil {
 		log.Fatalf("Failed to convert one or more arguments to integers: %v\n", os.Args)
 	}
-	sd, sdErr := time.Parse("2006-01-02", os.Args[4])
-	ed, edErr := time.Parse("2006-01-02", os.Args[5])
+	sd, sdErr := serviceCalendar.ParseDay(os.Args[4])
+	ed, edErr := serviceCalendar.ParseDay(os.Args[5])
 	if sdErr != nil || edErr != nil {
 		log.Fatalf("Failed to parse start or end date from args: %v\n", os.Args)
 	}
@@ -105,24 +144,36 @@ func extractCLIArgs() (hostID int, hostCount int, dateRange DateRange) {
 
 // Returns the (inclusive) date range that this host needs to label.
 func calculateHostDateRange(hostID int, hostCount int, dRange DateRange) DateRange {
-	daysCount := dates.DaysBetween(dRange.Start, dRange.End)
+	daysCount := dRange.First.DaysUntil(dRange.Last) + 1
 	daysPerHost := daysCount / hostCount
 	offset := daysPerHost * (hostID - 1)
-	startDate := dRange.Start.AddDate(0, 0, offset)
-	endDate := startDate.AddDate(0, 0, daysPerHost-1)
-	return DateRange{startDate, endDate}
+	first := dRange.First.AddDays(offset)
+	last := first.AddDays(daysPerHost - 1)
+	return DateRange{first, last}
 }
 
-func labelDataForDates(dataForDates [][]bus.VehicleJourney, stopDistances map[stopdistance.Key]float64, avgStopDistances map[string]int) []bus.LabelledJourney {
+func labelDataForDates(dataForDates [][]bus.VehicleJourney, stopDistances map[stopdistance.Key]float64, avgStopDistances map[string]int) ([]bus.LabelledJourney, []bus.StopEvent) {
 	var labelledJourneys []bus.LabelledJourney
-	for i, journeysOnDate := range dataForDates {
-		log.Printf("Labelling data for date %d of %d...\n", i, len(dataForDates))
-		partitionedJourneys := bus.PartitionJourneys(journeysOnDate)
-		labelledData := labels.Create(partitionedJourneys, stopDistances, avgStopDistances)
+	var stopEvents []bus.StopEvent
+	cleaner := bus.NewCleaner(bus.RouteStopsOption(stopdistance.RouteStops(stopDistances)))
+	tracker := progress.NewTracker("Labelling vehicle journeys", len(dataForDates))
+	for _, journeysOnDate := range dataForDates {
+		// Remove invalid movements (GPS jumps, repeated reports etc.) before labelling
+		cleaned := cleaner.Clean(journeysOnDate)
+		log.Printf("Cleaned movements: %s\n", cleaned)
+		movementsKept.Add(float64(len(cleaned.Kept)))
+		for reason, count := range cleaned.Counts() {
+			movementsRejected.Add(float64(count), string(reason))
+		}
+		trips := bus.SegmentTrips(cleaned.Kept)
+		labelledData := labels.Create(trips, stopDistances, avgStopDistances)
 		labelledJourneys = append(labelledJourneys, labelledData...)
+		stopEvents = append(stopEvents, events.Extract(trips, stopDistances, avgStopDistances)...)
+		tracker.Increment()
 	}
+	tracker.Done()
 	log.Println("Successfully labelled data for all dates!")
-	return labelledJourneys
+	return labelledJourneys, stopEvents
 }
 
 func deleteFromDB(dateRange DateRange) {
@@ -131,12 +182,12 @@ func deleteFromDB(dateRange DateRange) {
 	if err != nil {
 		log.Fatal(err)
 	}
-	start, end := dateRange.Start, dateRange.End.AddDate(0, 0, 1)
-	startStamp := dates.SetHour(start, dayBoundary, newYorkLoc).Format(database.TimeFormat)
-	endStamp := dates.SetHour(end, dayBoundary, newYorkLoc).Format(database.TimeFormat)
+	// Half-open interval, so that the first movement of the following service day is kept
+	startStamp := dateRange.First.Start().Format(database.TimeFormat)
+	endStamp := dateRange.Last.End().Format(database.TimeFormat)
 	log.Printf("Deleting entries in DB with timestamps between %s and %s", startStamp, endStamp)
 	query := fmt.Sprintf(
-		"DELETE FROM %s WHERE timestamp BETWEEN '%s' and '%s'",
+		"DELETE FROM %s WHERE timestamp >= '%s' AND timestamp < '%s'",
 		database.VehicleJourneyTable.Name, startStamp, endStamp,
 	)
 	stat, err := transaction.Prepare(query)
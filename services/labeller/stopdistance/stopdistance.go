@@ -14,26 +14,11 @@ func Get(db *sql.DB) map[Key]float64 {
 	log.Println("Fetching all stop distances from DB")
 	rows := database.FetchAllRows(db, database.StopDistanceTable.Name)
 	defer rows.Close()
-	sdList := scanIntoStructs(rows)
-	return partitionStopDistanceList(sdList)
-}
-
-// Convert database rows into bus.StopDistance structs
-func scanIntoStructs(rows *sql.Rows) []bus.StopDistance {
-	var sdList []bus.StopDistance
-	for rows.Next() {
-		var sd bus.StopDistance
-		err := rows.Scan(&sd.RouteID, &sd.FromID, &sd.ToID, &sd.Distance, &sd.DirectionID)
-		if err != nil {
-			log.Fatal(err)
-		}
-		sdList = append(sdList, sd)
-	}
-	err := rows.Err()
+	sdList, err := bus.ScanStopDistanceRows(rows)
 	if err != nil {
 		log.Fatal(err)
 	}
-	return sdList
+	return partitionStopDistanceList(sdList)
 }
 
 type Key struct {
@@ -53,6 +38,31 @@ func partitionStopDistanceList(distances []bus.StopDistance) map[Key]float64 {
 	return partitioned
 }
 
+// RouteStops returns the IDs of every stop that appears in the stop distances,
+// keyed by route ID and direction ID, in the format expected by bus.RouteStopsOption
+func RouteStops(distances map[Key]float64) map[string]map[int][]string {
+	type stopOnRoute struct {
+		routeID     string
+		directionID int
+		stopID      string
+	}
+	seen := map[stopOnRoute]bool{}
+	routeStops := map[string]map[int][]string{}
+	for key := range distances {
+		if routeStops[key.RouteID] == nil {
+			routeStops[key.RouteID] = map[int][]string{}
+		}
+		for _, stopID := range []string{key.FromID, key.ToID} {
+			stopKey := stopOnRoute{key.RouteID, key.DirectionID, stopID}
+			if !seen[stopKey] {
+				seen[stopKey] = true
+				routeStops[key.RouteID][key.DirectionID] = append(routeStops[key.RouteID][key.DirectionID], stopID)
+			}
+		}
+	}
+	return routeStops
+}
+
 func GetAverage(db *sql.DB) map[string]int {
 	log.Println("Fetching average stop distances for each route from the DB")
 	// Init map
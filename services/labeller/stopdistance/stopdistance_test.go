@@ -52,3 +52,14 @@ func TestShouldGetAverageStopDistances(t *testing.T) {
 		t.Errorf("TestShouldGetAverageStopDistances: there were unfulfilled expectations: %s", err)
 	}
 }
+
+func TestRouteStops(t *testing.T) {
+	distances := map[Key]float64{
+		{"route1", 0, "stop1", "stop2"}: 123.0,
+		{"route1", 0, "stop2", "stop3"}: 456.0,
+		{"route1", 1, "stop3", "stop1"}: 789.0,
+	}
+	actual := RouteStops(distances)
+	assert.ElementsMatch(t, []string{"stop1", "stop2", "stop3"}, actual["route1"][0])
+	assert.ElementsMatch(t, []string{"stop3", "stop1"}, actual["route1"][1])
+}
@@ -1,8 +1,8 @@
 module livedataloader
 
 require (
+	github.com/gorilla/websocket v1.5.3
 	github.com/lib/pq v1.0.0
-	github.com/tidwall/gjson v1.2.1
 	gopkg.in/guregu/null.v3 v3.4.0
 	transport/lib v0.0.0
 )
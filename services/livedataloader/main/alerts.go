@@ -0,0 +1,175 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+	"transport/lib/bus"
+	"transport/lib/database"
+)
+
+// alertsAt returns the alerts in the snapshot that are active at `now` and satisfy the filters.
+// Filtering by VehicleRef joins the alerts to that vehicle, returning the alerts that affect it.
+// LineRef and DirectionRef return the alerts affecting the route (in that direction, if given).
+// Passing all=true includes alerts outside of their publication window.
+func alertsAt(snap *snapshot, filters url.Values, now time.Time) ([]bus.ServiceAlert, error) {
+	candidates := snap.Alerts
+	if vehicleRef := filters.Get("VehicleRef"); vehicleRef != "" {
+		vj, found := bus.VehicleJourneysByVehicleRef(snap.Journeys)[vehicleRef]
+		if !found {
+			return []bus.ServiceAlert{}, nil
+		}
+		candidates = bus.AlertsForJourney(candidates, vj)
+	}
+	directionID := -1
+	if direction := filters.Get("DirectionRef"); direction != "" {
+		var err error
+		if directionID, err = strconv.Atoi(direction); err != nil {
+			return nil, fmt.Errorf("DirectionRef must be a number, got '%s'", direction)
+		}
+	}
+	matches := []bus.ServiceAlert{}
+	for _, alert := range candidates {
+		if filters.Get("all") != "true" && !alert.Active(now) {
+			continue
+		}
+		if routeID := filters.Get("LineRef"); routeID != "" && !affectsRoute(alert, routeID, directionID) {
+			continue
+		}
+		matches = append(matches, alert)
+	}
+	return matches, nil
+}
+
+// affectsRoute returns true if the alert affects the route, in any direction if `directionID` is -1
+func affectsRoute(alert bus.ServiceAlert, routeID string, directionID int) bool {
+	if directionID >= 0 {
+		return alert.AffectsRoute(routeID, directionID)
+	}
+	return alert.AffectsRoute(routeID, 0) || alert.AffectsRoute(routeID, 1)
+}
+
+func alertRequestHandler(w http.ResponseWriter, req *http.Request) {
+	snap := snapshots.load()
+	if writeUnavailable(w, snap) {
+		return
+	}
+	// Replayed snapshots are in the past, so check publication windows against the time
+	// the snapshot was created, rather than the current time
+	now := snap.ResponseTimestamp
+	if now.IsZero() {
+		now = time.Now()
+	}
+	alerts, err := alertsAt(snap, req.URL.Query(), now)
+	if err != nil {
+		log.Printf("alertRequestHandler: invalid query: %s\n", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	response, err := json.Marshal(alerts)
+	if err != nil {
+		log.Printf("alertRequestHandler: error marshalling alerts into JSON: %s\n", err)
+		http.Error(w, "Failed to create response", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	snapshots.writeHeaders(w, snap, time.Now())
+	_, err = w.Write(response)
+	if err != nil {
+		log.Printf("error occurred whilst writing response in alertRequestHandler: %s\n", err)
+	}
+}
+
+// alertRetention is how long an alert is remembered after it was last seen in a response,
+// in case it briefly drops out of the feed and then reappears
+const alertRetention = 24 * time.Hour
+
+// storedAlerts records the alerts that have already been stored in the DB, and when each was
+// last seen. Alerts are repeated in every response until they end, so each one is only stored
+// when it is first seen (or when it is updated, which gives it a new creation time).
+type storedAlerts map[string]time.Time
+
+func alertKey(situationNumber string, creationTime time.Time) string {
+	return fmt.Sprintf("%s@%d", situationNumber, creationTime.Unix())
+}
+
+// loadStoredAlerts fetches the alerts in the DB which may still be active, so that they
+// aren't stored again after a restart
+func loadStoredAlerts(db *sql.DB, now time.Time) storedAlerts {
+	stored := storedAlerts{}
+	rows, err := db.Query(fmt.Sprintf(
+		"SELECT situation_number, creation_time FROM %s WHERE publication_end IS NULL OR publication_end > $1",
+		database.ServiceAlertTable.Name,
+	), now.Add(-alertRetention))
+	if err != nil {
+		log.Printf("loadStoredAlerts: error querying stored alerts: %s\n", err)
+		return stored
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var situationNumber string
+		var creationTime time.Time
+		if err := rows.Scan(&situationNumber, &creationTime); err != nil {
+			log.Printf("loadStoredAlerts: error scanning stored alert: %s\n", err)
+			return stored
+		}
+		stored[alertKey(situationNumber, creationTime)] = now
+	}
+	if err := rows.Err(); err != nil {
+		log.Printf("loadStoredAlerts: error reading stored alerts: %s\n", err)
+	}
+	return stored
+}
+
+// unseen returns the alerts that haven't been stored yet, and records that
+// the ones which have been stored were seen at `now`
+func (sa storedAlerts) unseen(alerts []bus.ServiceAlert, now time.Time) []bus.ServiceAlert {
+	var fresh []bus.ServiceAlert
+	for _, alert := range alerts {
+		key := alertKey(alert.SituationNumber, alert.CreationTime.Time)
+		if _, stored := sa[key]; stored {
+			sa[key] = now
+		} else {
+			fresh = append(fresh, alert)
+		}
+	}
+	return fresh
+}
+
+// markStored records that the alerts have been committed to the DB
+func (sa storedAlerts) markStored(alerts []bus.ServiceAlert, now time.Time) {
+	for _, alert := range alerts {
+		sa[alertKey(alert.SituationNumber, alert.CreationTime.Time)] = now
+	}
+}
+
+// expire forgets the alerts that haven't been seen within the alertRetention
+func (sa storedAlerts) expire(now time.Time) {
+	for key, lastSeen := range sa {
+		if now.Sub(lastSeen) > alertRetention {
+			delete(sa, key)
+		}
+	}
+}
+
+// Stores the alerts in the DB that haven't been stored before. Alerts are only marked
+// as stored once the transaction has been committed, so failed alerts are retried.
+func storeAlerts(db *sql.DB, stored storedAlerts, alerts []bus.ServiceAlert, now time.Time) {
+	fresh := stored.unseen(alerts, now)
+	stored.expire(now)
+	if len(fresh) == 0 {
+		return
+	}
+	err := copyIntoTable(db, database.ServiceAlertTable, bus.ExtractEntriesFromServiceAlert, bus.ServiceAlertToInterface(fresh))
+	if err != nil {
+		log.Printf("storeAlerts: error whilst storing alerts in db: %s\n", err)
+		return
+	}
+	stored.markStored(fresh, now)
+	log.Printf("Stored %d new service alerts\n", len(fresh))
+}
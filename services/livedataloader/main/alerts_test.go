@@ -0,0 +1,101 @@
+package main
+
+import (
+	"net/url"
+	"testing"
+	"time"
+	"transport/lib/bus"
+	"transport/lib/database"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/guregu/null.v3"
+)
+
+const situationExchangeResponse = `{"Siri": {"ServiceDelivery": {
+	"ResponseTimestamp": "2019-06-03T08:00:00.000-04:00",
+	"VehicleMonitoringDelivery": [{"VehicleActivity": [], "ValidUntil": "2019-06-03T08:00:30.000-04:00"}],
+	"SituationExchangeDelivery": [{"Situations": {"PtSituationElement": [{
+		"PublicationWindow": {"StartTime": "2019-06-03T06:00:00.000-04:00", "EndTime": "2019-06-03T10:00:00.000-04:00"},
+		"Severity": "undefined",
+		"Summary": ["Detour"],
+		"Description": ["B41 buses are detoured from Flatbush Av"],
+		"Affects": {"VehicleJourneys": {"AffectedVehicleJourney": [{"LineRef": "MTA NYCT_B41", "DirectionRef": "1"}]}},
+		"CreationTime": "2019-06-03T05:45:00.000-04:00",
+		"SituationNumber": "MTA NYCT_224082"
+	}]}}]
+}}}`
+
+func TestConvertToIR_ServiceAlerts(t *testing.T) {
+	vm, err := convertToIR([]byte(situationExchangeResponse))
+	assert.Nil(t, err)
+	assert.Len(t, vm.Alerts, 1)
+	alert := vm.Alerts[0]
+	assert.Equal(t, "MTA NYCT_224082", alert.SituationNumber)
+	assert.Equal(t, "Detour", alert.Summary)
+	assert.Equal(t, "B41 buses are detoured from Flatbush Av", alert.Description)
+	assert.Equal(t, []bus.AffectedRoute{{RouteID: "MTA NYCT_B41", DirectionID: 1}}, alert.Affected)
+	assert.True(t, alert.PublicationEnd.Valid)
+	assert.True(t, alert.Active(vm.ResponseTimestamp))
+}
+
+func TestAlertsAt(t *testing.T) {
+	vm, err := convertToIR([]byte(situationExchangeResponse))
+	assert.Nil(t, err)
+	vm.Journeys = []bus.VehicleJourney{
+		{VehicleRef: null.StringFrom("A"), LineRef: null.StringFrom("MTA NYCT_B41"), DirectionRef: null.IntFrom(1)},
+		{VehicleRef: null.StringFrom("B"), LineRef: null.StringFrom("MTA NYCT_B41"), DirectionRef: null.IntFrom(0)},
+	}
+	snap := newSnapshotStore().publish(vm, time.Now())
+	now := vm.ResponseTimestamp
+	later := time.Date(2019, 6, 3, 11, 0, 0, 0, database.TimeLoc)
+
+	tests := []struct {
+		query    string
+		now      time.Time
+		expected int
+	}{
+		{"", now, 1},
+		{"", later, 0},
+		{"all=true", later, 1},
+		{"LineRef=MTA+NYCT_B41", now, 1},
+		{"LineRef=MTA+NYCT_B41&DirectionRef=0", now, 0},
+		{"LineRef=MTA+NYCT_M55", now, 0},
+		{"VehicleRef=A", now, 1},
+		{"VehicleRef=B", now, 0},
+		{"VehicleRef=C", now, 0},
+	}
+	for _, test := range tests {
+		filters, err := url.ParseQuery(test.query)
+		assert.Nil(t, err)
+		alerts, err := alertsAt(snap, filters, test.now)
+		assert.Nil(t, err)
+		assert.Len(t, alerts, test.expected, test.query)
+	}
+
+	_, err = alertsAt(snap, url.Values{"DirectionRef": {"north"}}, now)
+	assert.NotNil(t, err)
+}
+
+func TestStoredAlerts_Unseen(t *testing.T) {
+	vm, err := convertToIR([]byte(situationExchangeResponse))
+	assert.Nil(t, err)
+	now := time.Now()
+	stored := storedAlerts{}
+	// Alerts stay unseen until they have been stored
+	assert.Len(t, stored.unseen(vm.Alerts, now), 1)
+	assert.Len(t, stored.unseen(vm.Alerts, now), 1)
+	stored.markStored(vm.Alerts, now)
+	assert.Empty(t, stored.unseen(vm.Alerts, now))
+
+	updated := vm.Alerts[0]
+	updated.CreationTime = database.Timestamp{Time: updated.CreationTime.Add(time.Hour)}
+	assert.Len(t, stored.unseen([]bus.ServiceAlert{updated}, now), 1)
+
+	// Alerts are forgotten once they haven't been seen for the alertRetention
+	later := now.Add(alertRetention / 2)
+	assert.Empty(t, stored.unseen(vm.Alerts, later))
+	stored.expire(later.Add(alertRetention / 2))
+	assert.Len(t, stored, 1)
+	stored.expire(later.Add(alertRetention * 2))
+	assert.Empty(t, stored)
+}
@@ -0,0 +1,230 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"strconv"
+	"strings"
+	"time"
+	"transport/lib/bus"
+	"transport/lib/busproto"
+	"transport/lib/database"
+	"transport/lib/gtfsrt"
+)
+
+// Formats that vehicle data can be encoded in, chosen using format= or the Accept header
+const (
+	jsonFormat     = "json"
+	protobufFormat = "protobuf"
+	geoJSONFormat  = "geojson"
+	csvFormat      = "csv"
+	gtfsrtFormat   = "gtfsrt"
+)
+
+const (
+	jsonContentType    = "application/json"
+	geoJSONContentType = "application/geo+json"
+	csvContentType     = "text/csv"
+)
+
+// Media types that clients can put in their Accept header to receive protobuf
+var protobufContentTypes = []string{busproto.ContentType, "application/protobuf", "application/vnd.google.protobuf"}
+
+// Maps the media types in Accept headers to the format they select. GTFS-Realtime has no media
+// type of its own (and shares busproto's), so it can only be requested using format=gtfsrt.
+var acceptFormats = map[string]string{
+	jsonContentType:    jsonFormat,
+	geoJSONContentType: geoJSONFormat,
+	csvContentType:     csvFormat,
+}
+
+func init() {
+	for _, contentType := range protobufContentTypes {
+		acceptFormats[contentType] = protobufFormat
+	}
+}
+
+// vehicleEncoder encodes the journeys, returning the encoded data and its Content-Type. Only the
+// named fields of each journey are included, or every field if `fields` is empty. `generated` is
+// when the data was fetched from the MTA.
+type vehicleEncoder func(journeys []bus.VehicleJourney, fields []string, generated time.Time) ([]byte, string, error)
+
+// Every format that vehicle data can be encoded in
+var vehicleEncoders = map[string]vehicleEncoder{
+	jsonFormat:     encodeJSON,
+	protobufFormat: encodeProtobuf,
+	geoJSONFormat:  encodeGeoJSON,
+	csvFormat:      encodeCSV,
+	gtfsrtFormat:   encodeGTFSRealtime,
+}
+
+// Encodes the journeys in the requested `format`, or (if no format was requested) the format
+// preferred by the client's Accept header, returning the encoded data and its Content-Type.
+// Clients that don't ask for a particular format receive JSON.
+// If `fields` isn't empty, only those fields of each journey are included.
+func encodeVehicleData(journeys []bus.VehicleJourney, fields []string, format string, accept string, generated time.Time) ([]byte, string, error) {
+	if format == "" {
+		format = negotiateFormat(accept)
+	}
+	encode, found := vehicleEncoders[format]
+	if !found {
+		return nil, "", fmt.Errorf("encodeVehicleData: unknown format '%s'", format)
+	}
+	return encode(journeys, fields, generated)
+}
+
+// Returns the format of the media type in the Accept header with the highest quality value,
+// preferring the earliest when several are equal. Media types with a quality value of 0 are
+// refused by the client, so they are never chosen. Defaults to JSON.
+func negotiateFormat(accept string) string {
+	format, best := jsonFormat, 0.0
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		f, found := acceptFormats[mediaType]
+		if !found {
+			continue
+		}
+		quality := 1.0
+		if q, found := params["q"]; found {
+			if quality, err = strconv.ParseFloat(q, 64); err != nil {
+				continue
+			}
+		}
+		if quality > best {
+			format, best = f, quality
+		}
+	}
+	return format
+}
+
+func encodeJSON(journeys []bus.VehicleJourney, fields []string, _ time.Time) ([]byte, string, error) {
+	var data []byte
+	var err error
+	if len(fields) > 0 {
+		projected := make([]map[string]interface{}, len(journeys))
+		for i, vj := range journeys {
+			projected[i] = projectJSON(vj, fields)
+		}
+		data, err = json.Marshal(projected)
+	} else {
+		data, err = json.Marshal(journeys)
+	}
+	if err != nil {
+		return nil, "", fmt.Errorf("encodeJSON: error marshalling journeys into JSON: %s", err)
+	}
+	return data, jsonContentType, nil
+}
+
+func encodeProtobuf(journeys []bus.VehicleJourney, fields []string, _ time.Time) ([]byte, string, error) {
+	data, err := busproto.MarshalVehicleJourneys(projectStructs(journeys, fields))
+	return data, busproto.ContentType, err
+}
+
+// Encodes the journeys as a GTFS-Realtime VehiclePositions feed
+func encodeGTFSRealtime(journeys []bus.VehicleJourney, fields []string, generated time.Time) ([]byte, string, error) {
+	data, err := gtfsrt.MarshalVehiclePositions(projectStructs(journeys, fields), generated)
+	return data, gtfsrt.ContentType, err
+}
+
+// geoJSONCollection is a GeoJSON FeatureCollection (RFC 7946) with a feature for each journey
+type geoJSONCollection struct {
+	Type     string           `json:"type"`
+	Features []geoJSONFeature `json:"features"`
+}
+
+// geoJSONFeature is a journey's location, with its fields as the feature's properties
+type geoJSONFeature struct {
+	Type       string                 `json:"type"`
+	Geometry   *geoJSONPoint          `json:"geometry"`
+	Properties map[string]interface{} `json:"properties"`
+}
+
+// geoJSONPoint is a GeoJSON Point, whose coordinates are [longitude, latitude]
+type geoJSONPoint struct {
+	Type        string     `json:"type"`
+	Coordinates [2]float64 `json:"coordinates"`
+}
+
+// Encodes the journeys as a GeoJSON FeatureCollection of points. Journeys without
+// a location are included with a null geometry, as allowed by the GeoJSON spec.
+func encodeGeoJSON(journeys []bus.VehicleJourney, fields []string, _ time.Time) ([]byte, string, error) {
+	if len(fields) == 0 {
+		fields = allFields
+	}
+	collection := geoJSONCollection{Type: "FeatureCollection", Features: make([]geoJSONFeature, len(journeys))}
+	for i, vj := range journeys {
+		feature := geoJSONFeature{Type: "Feature", Properties: projectJSON(vj, fields)}
+		if vj.Latitude.Valid && vj.Longitude.Valid {
+			feature.Geometry = &geoJSONPoint{Type: "Point", Coordinates: [2]float64{vj.Longitude.Float64, vj.Latitude.Float64}}
+		}
+		collection.Features[i] = feature
+	}
+	data, err := json.Marshal(collection)
+	if err != nil {
+		return nil, "", fmt.Errorf("encodeGeoJSON: error marshalling journeys into GeoJSON: %s", err)
+	}
+	return data, geoJSONContentType, nil
+}
+
+// Encodes the journeys as CSV, with a header row of field names. Null fields are left empty,
+// times use the database.TimeFormat and lists (such as SituationRef) are comma separated.
+func encodeCSV(journeys []bus.VehicleJourney, fields []string, _ time.Time) ([]byte, string, error) {
+	if len(fields) == 0 {
+		fields = allFields
+	}
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	if err := w.Write(fields); err != nil {
+		return nil, "", fmt.Errorf("encodeCSV: error writing header: %s", err)
+	}
+	row := make([]string, len(fields))
+	for i := range journeys {
+		for j, name := range fields {
+			field := vehicleFields[name]
+			row[j] = formatCSVValue(field.kind, field.get(&journeys[i]))
+		}
+		if err := w.Write(row); err != nil {
+			return nil, "", fmt.Errorf("encodeCSV: error writing journey: %s", err)
+		}
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return nil, "", fmt.Errorf("encodeCSV: %s", err)
+	}
+	return buf.Bytes(), csvContentType, nil
+}
+
+func formatCSVValue(kind valueKind, value fieldValue) string {
+	if !value.valid {
+		return ""
+	}
+	switch kind {
+	case numberKind:
+		return strconv.FormatFloat(value.num, 'f', -1, 64)
+	case timeKind:
+		return value.time.Format(database.TimeFormat)
+	case listKind:
+		return strings.Join(value.list, ",")
+	default:
+		return value.str
+	}
+}
+
+// Returns the journeys with every field other than the named fields set to null,
+// or the journeys themselves if `fields` is empty
+func projectStructs(journeys []bus.VehicleJourney, fields []string) []bus.VehicleJourney {
+	if len(fields) == 0 {
+		return journeys
+	}
+	projected := make([]bus.VehicleJourney, len(journeys))
+	for i, vj := range journeys {
+		projected[i] = projectStruct(vj, fields)
+	}
+	return projected
+}
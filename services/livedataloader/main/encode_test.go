@@ -0,0 +1,113 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+	"transport/lib/bus"
+	"transport/lib/busproto"
+	"transport/lib/database"
+	"transport/lib/gtfsrt"
+	"transport/lib/nulltypes"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/proto"
+	"gopkg.in/guregu/null.v3"
+)
+
+func TestNegotiateFormat(t *testing.T) {
+	assert.Equal(t, protobufFormat, negotiateFormat("application/x-protobuf"))
+	assert.Equal(t, protobufFormat, negotiateFormat("application/json;q=0.5, application/protobuf"))
+	assert.Equal(t, jsonFormat, negotiateFormat("application/x-protobuf;q=0, application/json"))
+	assert.Equal(t, geoJSONFormat, negotiateFormat("application/geo+json, application/json"))
+	assert.Equal(t, csvFormat, negotiateFormat("text/csv;q=0.9, text/html"))
+	assert.Equal(t, jsonFormat, negotiateFormat("*/*"))
+	assert.Equal(t, jsonFormat, negotiateFormat(""))
+}
+
+func TestLiveDataRequestHandler_ContentNegotiation(t *testing.T) {
+	journeys := []bus.VehicleJourney{
+		{LineRef: null.StringFrom("MTA NYCT_B41"), VehicleRef: null.StringFrom("MTA NYCT_1")},
+		{LineRef: null.StringFrom("MTA NYCT_B59"), VehicleRef: null.StringFrom("MTA NYCT_2")},
+	}
+	snapshots = newSnapshotStore()
+	snapshots.publish(vehicleMonitoring{Journeys: journeys}, time.Now())
+
+	req := httptest.NewRequest("GET", "/api/v1/vehicles?LineRef=MTA+NYCT_B59", nil)
+	req.Header.Set("Accept", busproto.ContentType)
+	rec := httptest.NewRecorder()
+	liveDataRequestHandler(rec, req)
+	assert.Equal(t, busproto.ContentType, rec.Header().Get("Content-Type"))
+	decoded, err := busproto.UnmarshalVehicleJourneys(rec.Body.Bytes())
+	assert.NoError(t, err)
+	assert.Equal(t, journeys[1:], decoded)
+	assert.Equal(t, "1", rec.Header().Get(versionHeader))
+
+	rec = httptest.NewRecorder()
+	liveDataRequestHandler(rec, httptest.NewRequest("GET", "/api/v1/vehicles", nil))
+	assert.Equal(t, jsonContentType, rec.Header().Get("Content-Type"))
+	var response []map[string]interface{}
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &response))
+	assert.Len(t, response, 2)
+}
+
+func TestLiveDataRequestHandler_Formats(t *testing.T) {
+	ts := nulltypes.TimestampFrom(database.Timestamp{Time: time.Date(2019, 6, 3, 8, 0, 0, 0, database.TimeLoc)})
+	journeys := []bus.VehicleJourney{
+		{
+			LineRef:      null.StringFrom("MTA NYCT_B41"),
+			DirectionRef: null.IntFrom(1),
+			SituationRef: nulltypes.StringSliceFrom([]string{"MTA NYCT_1", "MTA NYCT_2"}),
+			Latitude:     null.FloatFrom(40.7),
+			Longitude:    null.FloatFrom(-73.99),
+			VehicleRef:   null.StringFrom("MTA NYCT_1"),
+			Timestamp:    ts,
+		},
+		{LineRef: null.StringFrom("MTA NYCT_B59"), VehicleRef: null.StringFrom("MTA NYCT_2")},
+	}
+	snapshots = newSnapshotStore()
+	snapshots.publish(vehicleMonitoring{Journeys: journeys, ResponseTimestamp: ts.Time}, time.Now())
+
+	request := func(target string, accept string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", target, nil)
+		req.Header.Set("Accept", accept)
+		rec := httptest.NewRecorder()
+		liveDataRequestHandler(rec, req)
+		return rec
+	}
+
+	// GeoJSON, requested using the Accept header
+	rec := request("/api/v1/vehicles?fields=VehicleRef", geoJSONContentType)
+	assert.Equal(t, geoJSONContentType, rec.Header().Get("Content-Type"))
+	assert.JSONEq(t, `{"type": "FeatureCollection", "features": [
+		{"type": "Feature", "geometry": {"type": "Point", "coordinates": [-73.99, 40.7]}, "properties": {"VehicleRef": "MTA NYCT_1"}},
+		{"type": "Feature", "geometry": null, "properties": {"VehicleRef": "MTA NYCT_2"}}
+	]}`, rec.Body.String())
+
+	// CSV, where format= takes priority over the Accept header
+	rec = request("/api/v1/vehicles?format=csv&fields=VehicleRef,DirectionRef,SituationRef,Timestamp", jsonContentType)
+	assert.Equal(t, csvContentType, rec.Header().Get("Content-Type"))
+	assert.Equal(t, "VehicleRef,DirectionRef,SituationRef,Timestamp\n"+
+		"MTA NYCT_1,1,\"MTA NYCT_1,MTA NYCT_2\",2019-06-03 08:00:00\n"+
+		"MTA NYCT_2,,,\n", rec.Body.String())
+
+	rec = request("/api/v1/vehicles?format=csv", "")
+	header := strings.SplitN(rec.Body.String(), "\n", 2)[0]
+	assert.Equal(t, strings.Join(allFields, ","), header)
+	assert.Len(t, allFields, len(vehicleFields))
+
+	// GTFS-Realtime
+	rec = request("/api/v1/vehicles?format=gtfsrt&LineRef=MTA+NYCT_B41", "")
+	assert.Equal(t, gtfsrt.ContentType, rec.Header().Get("Content-Type"))
+	feed := &gtfsrt.FeedMessage{}
+	assert.NoError(t, proto.Unmarshal(rec.Body.Bytes(), feed))
+	assert.Equal(t, uint64(ts.Time.Unix()), feed.GetHeader().GetTimestamp())
+	assert.Len(t, feed.GetEntity(), 1)
+	assert.Equal(t, "B41", feed.GetEntity()[0].GetVehicle().GetTrip().GetRouteId())
+
+	rec = request("/api/v1/vehicles?format=xml", "")
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
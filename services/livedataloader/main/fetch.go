@@ -5,74 +5,187 @@ import (
 	"io/ioutil"
 	"log"
 	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
 	"time"
-	"transport/lib/bus"
 	"transport/lib/iohelper"
 )
 
 // Constants
 const (
 	vehicleMonitoringURL = "http://bustime.mta.info/api/siri/vehicle-monitoring.json"
-	fetchFrequency       = 35 * time.Second
+	// fetchFrequency is how often data is fetched when a response doesn't say how long it is valid for
+	fetchFrequency = 35 * time.Second
+	// lineRefsEnvVar lists routes to poll individually, e.g. POLL_LINE_REFS="MTA NYCT_B41,MTA NYCT_M15".
+	// These routes are polled (each on its own schedule) alongside the whole fleet, so their vehicles
+	// can be refreshed independently, and their vehicles are only stored from their own feeds.
+	// This costs extra quota: every route adds one request per poll on top of the whole-fleet
+	// request, so listing two routes triples the number of requests made with the key.
+	lineRefsEnvVar = "POLL_LINE_REFS"
 )
 
-// Fetches initial data, telling the HTTP server it can start up, and fetches new data
-// at a fixed time interval
-func initialiseDataFetching(key string, dataLocation *[]bus.VehicleJourney, dataWritten chan bool) {
-	URLWithKey := fmt.Sprintf("%s?key=%s&version=2", vehicleMonitoringURL, key)
-	fetchInitialData(URLWithKey, dataLocation, dataWritten)
-	fetchAtInterval(URLWithKey, fetchFrequency, dataLocation, dataWritten)
+// feed is a vehicle monitoring request that is polled on its own schedule,
+// for either the whole fleet or a single route
+type feed struct {
+	// The route requested, or empty for the whole fleet
+	lineRef string
+	URL     string
 }
 
-// Fetches initial data and writes to the dataWritten channel once complete
-func fetchInitialData(URL string, dataLocation *[]bus.VehicleJourney, dataWritten chan bool) {
-	fetch(URL, dataLocation)
-	dataWritten <- true
-	log.Printf("Succesfully fetched initial data from URL (%s)\n", URL)
+func (f feed) String() string {
+	if f.lineRef == "" {
+		return "all routes"
+	}
+	return f.lineRef
 }
 
-/*
-This function fetches the data at URL at intervals of `timeBetweenFetches`.
-
-Implementation:
-Make a time.NewTicker, which returns a channel that will be written to every X seconds.
-   In a new go routine, the infinite for loop:
-	- blocks and waits for the ticker channel to be written to
-	- fetches the data
-	- returns to the start of the loop and blocks on the channel again
-*/
-func fetchAtInterval(URL string, timeBetweenFetches time.Duration, dataLocation *[]bus.VehicleJourney, dataWritten chan bool) {
-	ticker := time.NewTicker(timeBetweenFetches)
-	go func() {
-		for {
-			<-ticker.C
-			fetch(URL, dataLocation)
-			dataWritten <- true
+// vehicleFeeds returns the feed for the whole fleet, followed by a feed for each route in `lineRefs`
+func vehicleFeeds(key string, lineRefs []string) []feed {
+	URLWithKey := fmt.Sprintf("%s?key=%s&version=2", vehicleMonitoringURL, key)
+	feeds := []feed{{URL: URLWithKey}}
+	for _, lineRef := range lineRefs {
+		feeds = append(feeds, feed{lineRef: lineRef, URL: fmt.Sprintf("%s&LineRef=%s", URLWithKey, url.QueryEscape(lineRef))})
+	}
+	return feeds
+}
+
+// lineRefsFromEnv returns the routes listed in POLL_LINE_REFS, if any
+func lineRefsFromEnv() []string {
+	var lineRefs []string
+	for _, lineRef := range strings.Split(iohelper.GetEnvOrDefault(lineRefsEnvVar, ""), ",") {
+		if lineRef = strings.TrimSpace(lineRef); lineRef != "" {
+			lineRefs = append(lineRefs, lineRef)
 		}
-	}()
+	}
+	return lineRefs
 }
 
-// Fetches the JSON object at `URL`, reads it into memory and stores it at `dataLocation`
-func fetch(URL string, dataLocation *[]bus.VehicleJourney) {
+// Fetches initial data from every feed, telling the HTTP server it can start up, and then polls
+// each feed on its own schedule (see nextFetchDelay). The latest data from every feed is merged
+// and published to `snapshots`, and the data from each fetch is sent to `dataWritten`.
+func initialiseDataFetching(key string, lineRefs []string, snapshots *snapshotStore, dataWritten chan *snapshot) {
+	feeds := vehicleFeeds(key, lineRefs)
+	merger := newFeedMerger(feeds)
+	for i, f := range feeds {
+		p := &poller{feed: f, index: i, merger: merger, snapshots: snapshots, dataWritten: dataWritten}
+		delay := fetchInitialData(p)
+		go p.run(delay)
+	}
+}
+
+// Fetches initial data and writes to the dataWritten channel once complete. If the fetch
+// fails, the server still starts, serving the restored snapshot (if any) until a fetch succeeds.
+// Returns how long to wait before fetching again.
+func fetchInitialData(p *poller) time.Duration {
+	delay := p.poll()
+	if p.failures == 0 {
+		log.Printf("Succesfully fetched initial data for %s\n", p.feed)
+	} else {
+		log.Printf("Failed to fetch initial data for %s, will retry in %s\n", p.feed, delay)
+	}
+	return delay
+}
+
+// poller fetches a single feed repeatedly, publishing the merged data from every feed
+type poller struct {
+	feed        feed
+	index       int
+	merger      *feedMerger
+	snapshots   *snapshotStore
+	dataWritten chan *snapshot
+	// The number of fetches in a row that have failed
+	failures int
+}
+
+// run polls the feed forever, waiting `delay` before the first fetch
+func (p *poller) run(delay time.Duration) {
+	for {
+		time.Sleep(delay)
+		delay = p.poll()
+	}
+}
+
+// poll fetches the feed and publishes a new snapshot, which is also saved to disk. If the
+// fetch fails, the previous snapshot is kept (and marked as stale) and nothing is sent
+// to `dataWritten`. Returns how long to wait before fetching again.
+func (p *poller) poll() time.Duration {
+	start := time.Now()
+	vm, err := fetch(p.feed.URL)
+	fetchedAt := time.Now()
+	elapsed := fetchedAt.Sub(start)
+	fetchDuration.Observe(elapsed.Seconds())
+	if err != nil {
+		log.Println(err)
+		fetchErrors.Inc()
+		p.failures++
+		p.snapshots.fetchFailed(p.feed.String())
+	} else {
+		p.failures = 0
+		p.snapshots.fetchSucceeded(p.feed.String())
+		p.publish(vm, fetchedAt)
+	}
+	delay := nextFetchDelay(vm, err, p.failures, elapsed)
+	log.Printf("Next fetch for %s in %s\n", p.feed, delay.Round(time.Millisecond))
+	return delay
+}
+
+// publish merges the fetched data with the latest data from the other feeds, and publishes
+// the result. Only the fetched reports that haven't been stored yet are sent to `dataWritten`,
+// so that no report is stored in the DB twice.
+func (p *poller) publish(vm vehicleMonitoring, fetchedAt time.Time) {
+	merged, mergedFetchedAt, fresh := p.merger.update(p.index, vm, fetchedAt)
+	snap := p.snapshots.publish(merged, mergedFetchedAt)
+	snapshotVehicles.Set(float64(len(snap.Journeys)))
+	log.Printf("Published snapshot version %d with %d vehicles\n", snap.Version, len(snap.Journeys))
+	if err := saveSnapshot(snapshotPath, snap); err != nil {
+		log.Printf("Failed to save snapshot: %s\n", err)
+	}
+	fetched := *snap
+	fetched.Journeys = fresh
+	p.dataWritten <- &fetched
+}
+
+// rateLimitedError is returned when the MTA rejects a request because the key's quota has been
+// used up. `retryAfter` is how long the response asked us to wait, or 0 if it didn't say.
+type rateLimitedError struct {
+	URL        string
+	retryAfter time.Duration
+}
+
+func (e rateLimitedError) Error() string {
+	return fmt.Sprintf("Fetching URL (%s) was rate limited, retry after %s", e.URL, e.retryAfter)
+}
+
+// Fetches the JSON object at `URL`, reads it into memory and converts it into the internal format
+func fetch(URL string) (vehicleMonitoring, error) {
 	log.Printf("Fetching data from URL (%s)\n", URL)
 
 	// Get response from URL
 	resp, err := http.Get(URL)
 	if err != nil {
-		log.Printf("Fetching URL (%s) failed due to: %s\n", URL, err)
-		return
+		return vehicleMonitoring{}, fmt.Errorf("Fetching URL (%s) failed due to: %s", URL, err)
 	}
 	defer iohelper.CloseSafely(resp.Body, URL)
+	if resp.StatusCode == http.StatusTooManyRequests {
+		seconds, _ := strconv.Atoi(resp.Header.Get("Retry-After"))
+		return vehicleMonitoring{}, rateLimitedError{URL: URL, retryAfter: time.Duration(seconds) * time.Second}
+	}
+	if resp.StatusCode != http.StatusOK {
+		return vehicleMonitoring{}, fmt.Errorf("Fetching URL (%s) failed with status: %s", URL, resp.Status)
+	}
 
 	// Load body of response into memory
 	data, err := ioutil.ReadAll(resp.Body)
 	if err != nil {
-		log.Printf("Reading response from URL (%s) failed due to: %s\n", URL, err)
-		return
+		return vehicleMonitoring{}, fmt.Errorf("Reading response from URL (%s) failed due to: %s", URL, err)
 	}
 
-	// Store converted version of response body at dataLocation
-	*dataLocation = convertToIR(data)
+	vm, err := convertToIR(data)
+	if err != nil {
+		return vehicleMonitoring{}, fmt.Errorf("Converting response from URL (%s) failed due to: %s", URL, err)
+	}
 
 	log.Printf("Completed processing of URL (%s)\n", URL)
+	return vm, nil
 }
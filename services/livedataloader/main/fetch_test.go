@@ -0,0 +1,49 @@
+package main
+
+import (
+	"testing"
+	"time"
+	"transport/lib/bustime"
+	"transport/lib/database"
+	"transport/lib/testhelper"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFetch_SimulatedFeed(t *testing.T) {
+	start := time.Date(2019, 6, 3, 8, 0, 0, 0, database.TimeLoc)
+	fs, err := testhelper.NewFleetSimulator(testhelper.FleetConfig{
+		RouteID: "MTA NYCT_B41",
+		Stops: []bustime.BusStop{
+			{ID: "MTA_1", Latitude: 40.7000, Longitude: -73.9900},
+			{ID: "MTA_2", Latitude: 40.7045, Longitude: -73.9900},
+			{ID: "MTA_3", Latitude: 40.7090, Longitude: -73.9900},
+		},
+		SegmentSpeeds: []float64{6},
+		Start:         start,
+		Headway:       time.Minute,
+		Trips:         3,
+		GPSNoise:      5,
+	})
+	assert.NoError(t, err)
+	sim := fs.Run()
+	now := start.Add(150 * time.Second)
+	server := sim.ServeSIRI(func() time.Time { return now })
+	defer server.Close()
+
+	vm, err := fetch(server.URL)
+	assert.NoError(t, err)
+	assert.True(t, now.Equal(vm.ResponseTimestamp))
+	assert.True(t, now.Add(30*time.Second).Equal(vm.ValidUntil))
+	fetched := vm.Journeys
+	expected := sim.At(now)
+	assert.Len(t, fetched, len(expected))
+	for i, vj := range expected {
+		assert.Equal(t, vj.VehicleRef, fetched[i].VehicleRef)
+		assert.Equal(t, vj.TripID, fetched[i].TripID)
+		assert.Equal(t, vj.StopPointRef, fetched[i].StopPointRef)
+		assert.Equal(t, vj.DistanceFromStop, fetched[i].DistanceFromStop)
+		assert.InDelta(t, vj.Latitude.Float64, fetched[i].Latitude.Float64, 1e-9)
+		assert.True(t, vj.Timestamp.Equal(fetched[i].Timestamp.Time))
+	}
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"time"
+	"transport/lib/bus"
+	"transport/lib/nulltypes"
+
+	"gopkg.in/guregu/null.v3"
+)
+
+// valueKind determines which operators can be used on a field, and how it is sorted
+type valueKind int
+
+const (
+	stringKind valueKind = iota
+	numberKind
+	timeKind
+	// Lists of strings, such as SituationRef, which are equal to a value if they contain it
+	listKind
+)
+
+// fieldValue is the value of a single VehicleJourney field, in a form that can be compared
+type fieldValue struct {
+	valid bool
+	str   string
+	num   float64
+	time  time.Time
+	list  []string
+}
+
+// vehicleField describes how to read a VehicleJourney field
+type vehicleField struct {
+	kind valueKind
+	get  func(vj *bus.VehicleJourney) fieldValue
+}
+
+// Every VehicleJourney field that can be queried, sorted on or projected
+var vehicleFields = map[string]vehicleField{
+	"LineRef":                  {stringKind, func(vj *bus.VehicleJourney) fieldValue { return fromString(vj.LineRef) }},
+	"DirectionRef":             {numberKind, func(vj *bus.VehicleJourney) fieldValue { return fromInt(vj.DirectionRef) }},
+	"TripID":                   {stringKind, func(vj *bus.VehicleJourney) fieldValue { return fromString(vj.TripID) }},
+	"PublishedLineName":        {stringKind, func(vj *bus.VehicleJourney) fieldValue { return fromString(vj.PublishedLineName) }},
+	"OperatorRef":              {stringKind, func(vj *bus.VehicleJourney) fieldValue { return fromString(vj.OperatorRef) }},
+	"OriginRef":                {stringKind, func(vj *bus.VehicleJourney) fieldValue { return fromString(vj.OriginRef) }},
+	"DestinationRef":           {stringKind, func(vj *bus.VehicleJourney) fieldValue { return fromString(vj.DestinationRef) }},
+	"OriginAimedDepartureTime": {timeKind, func(vj *bus.VehicleJourney) fieldValue { return fromTimestamp(vj.OriginAimedDepartureTime) }},
+	"SituationRef":             {listKind, func(vj *bus.VehicleJourney) fieldValue { return fromStringSlice(vj.SituationRef) }},
+	"Longitude":                {numberKind, func(vj *bus.VehicleJourney) fieldValue { return fromFloat(vj.Longitude) }},
+	"Latitude":                 {numberKind, func(vj *bus.VehicleJourney) fieldValue { return fromFloat(vj.Latitude) }},
+	"ProgressRate":             {stringKind, func(vj *bus.VehicleJourney) fieldValue { return fromString(vj.ProgressRate) }},
+	"Occupancy":                {stringKind, func(vj *bus.VehicleJourney) fieldValue { return fromString(vj.Occupancy) }},
+	"VehicleRef":               {stringKind, func(vj *bus.VehicleJourney) fieldValue { return fromString(vj.VehicleRef) }},
+	"ExpectedArrivalTime":      {timeKind, func(vj *bus.VehicleJourney) fieldValue { return fromTimestamp(vj.ExpectedArrivalTime) }},
+	"ExpectedDepartureTime":    {timeKind, func(vj *bus.VehicleJourney) fieldValue { return fromTimestamp(vj.ExpectedDepartureTime) }},
+	"DistanceFromStop":         {numberKind, func(vj *bus.VehicleJourney) fieldValue { return fromInt(vj.DistanceFromStop) }},
+	"NumberOfStopsAway":        {numberKind, func(vj *bus.VehicleJourney) fieldValue { return fromInt(vj.NumberOfStopsAway) }},
+	"StopPointRef":             {stringKind, func(vj *bus.VehicleJourney) fieldValue { return fromString(vj.StopPointRef) }},
+	"Timestamp":                {timeKind, func(vj *bus.VehicleJourney) fieldValue { return fromTimestamp(vj.Timestamp) }},
+}
+
+func fromString(s null.String) fieldValue {
+	return fieldValue{valid: s.Valid, str: s.String}
+}
+
+func fromInt(i null.Int) fieldValue {
+	return fieldValue{valid: i.Valid, num: float64(i.Int64)}
+}
+
+func fromFloat(f null.Float) fieldValue {
+	return fieldValue{valid: f.Valid, num: f.Float64}
+}
+
+func fromTimestamp(ts nulltypes.Timestamp) fieldValue {
+	return fieldValue{valid: ts.Valid, time: ts.Time}
+}
+
+func fromStringSlice(ss nulltypes.StringSlice) fieldValue {
+	return fieldValue{valid: ss.Valid, list: ss.StringSlice}
+}
+
+// compareValues returns -1, 0 or 1 if `a` is less than, equal to or greater than `b`.
+// Null values are greater than every other value, so they are sorted last.
+func compareValues(kind valueKind, a fieldValue, b fieldValue) int {
+	switch {
+	case !a.valid && !b.valid:
+		return 0
+	case !a.valid:
+		return 1
+	case !b.valid:
+		return -1
+	}
+	switch kind {
+	case numberKind:
+		return compareFloats(a.num, b.num)
+	case timeKind:
+		return compareFloats(float64(a.time.UnixNano()), float64(b.time.UnixNano()))
+	case listKind:
+		return strings.Compare(strings.Join(a.list, ","), strings.Join(b.list, ","))
+	default:
+		return strings.Compare(a.str, b.str)
+	}
+}
+
+func compareFloats(a float64, b float64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// allFields lists every VehicleJourney field in the order they are declared, for formats
+// (such as CSV) that need the fields in a fixed order
+var allFields = func() []string {
+	t := reflect.TypeOf(bus.VehicleJourney{})
+	var names []string
+	for i := 0; i < t.NumField(); i++ {
+		if _, found := vehicleFields[t.Field(i).Name]; found {
+			names = append(names, t.Field(i).Name)
+		}
+	}
+	return names
+}()
+
+// projectJSON returns the named fields of the journey, ready to be marshalled into a JSON object
+func projectJSON(vj bus.VehicleJourney, fields []string) map[string]interface{} {
+	v := reflect.ValueOf(&vj).Elem()
+	projected := make(map[string]interface{}, len(fields))
+	for _, field := range fields {
+		// Use a pointer so that the field's JSON marshalling methods are used
+		projected[field] = v.FieldByName(field).Addr().Interface()
+	}
+	return projected
+}
+
+// projectStruct returns a copy of the journey in which every field other than the named
+// fields is null, for formats (such as protobuf) which always contain every field
+func projectStruct(vj bus.VehicleJourney, fields []string) bus.VehicleJourney {
+	keep := map[string]bool{}
+	for _, field := range fields {
+		keep[field] = true
+	}
+	v := reflect.ValueOf(&vj).Elem()
+	for i := 0; i < v.NumField(); i++ {
+		if !keep[v.Type().Field(i).Name] {
+			v.Field(i).Set(reflect.Zero(v.Field(i).Type()))
+		}
+	}
+	return vj
+}
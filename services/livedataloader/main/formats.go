@@ -26,6 +26,25 @@ type MTAVehicleMonitoringDelivery struct {
 	VehicleActivity   []MTAVehicleActivity
 	ResponseTimestamp database.Timestamp
 	ValidUntil        database.Timestamp
+	// Set instead of VehicleActivity when the request failed, e.g. due to an unknown LineRef
+	ErrorCondition *MTAErrorCondition
+}
+
+type MTAErrorCondition struct {
+	OtherError  MTAOtherError
+	Description string
+}
+
+type MTAOtherError struct {
+	ErrorText string
+}
+
+// String returns the error text, falling back to the description
+func (ec MTAErrorCondition) String() string {
+	if ec.OtherError.ErrorText != "" {
+		return ec.OtherError.ErrorText
+	}
+	return ec.Description
 }
 
 type MTAVehicleActivity struct {
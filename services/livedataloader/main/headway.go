@@ -0,0 +1,153 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"transport/lib/bus"
+	"transport/lib/database"
+)
+
+// headwayMonitor tracks the arrivals of vehicles at stops across successive
+// fetches, and the headways between them
+type headwayMonitor struct {
+	mutex    sync.Mutex
+	tracker  *bus.StopPassageTracker
+	analyser *bus.HeadwayAnalyser
+	headways []bus.Headway
+}
+
+// Headways between vehicles within the last DefaultPassageWindow
+var headways = newHeadwayMonitor()
+
+func newHeadwayMonitor(options ...func(*bus.StopPassageTracker)) *headwayMonitor {
+	return &headwayMonitor{
+		tracker:  bus.NewStopPassageTracker(bus.DefaultPassageWindow, options...),
+		analyser: bus.NewHeadwayAnalyser(),
+	}
+}
+
+// Fetches the distances between stops from the DB, so that arrivals at stops which vehicles
+// go past between reports can be interpolated. Returns no distances (so that the average
+// distance between stops is used instead) if they can't be fetched.
+func loadStopDistances(db *sql.DB) []bus.StopDistance {
+	rows, err := db.Query(fmt.Sprintf("SELECT * FROM %s", database.StopDistanceTable.Name))
+	if err != nil {
+		log.Printf("loadStopDistances: error querying stop distances, using the average distance between stops: %s\n", err)
+		return nil
+	}
+	defer rows.Close()
+	distances, err := bus.ScanStopDistanceRows(rows)
+	if err != nil {
+		log.Printf("loadStopDistances: using the average distance between stops: %s\n", err)
+		return nil
+	}
+	log.Printf("Loaded %d stop distances for interpolating arrivals\n", len(distances))
+	return distances
+}
+
+// arrivalKey identifies a single vehicle arriving at a stop
+type arrivalKey struct {
+	vehicleRef string
+	stopID     string
+	arrival    int64
+}
+
+// observe updates the headways using a new snapshot of live data,
+// returning the headways that end with an arrival in this snapshot
+func (hm *headwayMonitor) observe(snapshot []bus.VehicleJourney) []bus.Headway {
+	hm.mutex.Lock()
+	defer hm.mutex.Unlock()
+	newArrivals := map[arrivalKey]bool{}
+	for _, event := range hm.tracker.Observe(snapshot) {
+		newArrivals[arrivalKey{event.VehicleRef, event.StopID, event.Arrival.UnixNano()}] = true
+	}
+	hm.headways = hm.analyser.Headways(hm.tracker.Events())
+	var newHeadways []bus.Headway
+	for _, h := range hm.headways {
+		if newArrivals[arrivalKey{h.VehicleRef, h.StopID, h.Arrival.UnixNano()}] {
+			newHeadways = append(newHeadways, h)
+		}
+	}
+	return newHeadways
+}
+
+// matching returns the current headways that satisfy the filters, which can be any of
+// LineRef, DirectionRef, StopPointRef and Status. As with vehicle queries, a filter given
+// several values (e.g. LineRef=A,B or LineRef=A&LineRef=B) matches any of them.
+func (hm *headwayMonitor) matching(filters url.Values) []bus.Headway {
+	hm.mutex.Lock()
+	defer hm.mutex.Unlock()
+	matches := []bus.Headway{}
+	for _, h := range hm.headways {
+		if satisfiesHeadwayFilters(h, filters) {
+			matches = append(matches, h)
+		}
+	}
+	return matches
+}
+
+func satisfiesHeadwayFilters(h bus.Headway, filters url.Values) bool {
+	fields := map[string]string{
+		"LineRef":      h.RouteID,
+		"DirectionRef": strconv.Itoa(h.DirectionID),
+		"StopPointRef": h.StopID,
+		"Status":       string(h.Status),
+	}
+	for filter, expectedVals := range filters {
+		if actual, found := fields[filter]; found && !matchesAnyValue(actual, expectedVals) {
+			return false
+		}
+	}
+	return true
+}
+
+// matchesAnyValue returns true if `actual` is one of the (comma separated) values
+func matchesAnyValue(actual string, values []string) bool {
+	for _, value := range values {
+		for _, v := range strings.Split(value, ",") {
+			if actual == v {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func headwayRequestHandler(w http.ResponseWriter, req *http.Request) {
+	snap := snapshots.load()
+	if writeUnavailable(w, snap) {
+		return
+	}
+	response, err := json.Marshal(headways.matching(req.URL.Query()))
+	if err != nil {
+		log.Printf("headwayRequestHandler: error marshalling headways into JSON: %s\n", err)
+		http.Error(w, "Failed to create response", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	snapshots.writeHeaders(w, snap, time.Now())
+	_, err = w.Write(response)
+	if err != nil {
+		log.Printf("error occurred whilst writing response in headwayRequestHandler: %s\n", err)
+	}
+}
+
+// Updates the headways from the latest vehicle data and stores the new ones in the DB
+func storeHeadways(db *sql.DB, vehicleJourneys []bus.VehicleJourney) {
+	newHeadways := headways.observe(vehicleJourneys)
+	if len(newHeadways) == 0 {
+		return
+	}
+	err := copyIntoTable(db, database.HeadwayTable, bus.ExtractEntriesFromHeadway, bus.HeadwayToInterface(newHeadways))
+	if err != nil {
+		log.Printf("storeHeadways: error whilst storing headways in db: %s\n", err)
+	}
+}
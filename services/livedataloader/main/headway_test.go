@@ -0,0 +1,76 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+	"transport/lib/bus"
+	"transport/lib/bustime"
+	"transport/lib/database"
+	"transport/lib/testhelper"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHeadwayMonitor_SimulatedFeed(t *testing.T) {
+	start := time.Date(2019, 6, 3, 8, 0, 0, 0, database.TimeLoc)
+	fs, err := testhelper.NewFleetSimulator(testhelper.FleetConfig{
+		RouteID: "MTA NYCT_B41",
+		Stops: []bustime.BusStop{
+			{ID: "MTA_1", Latitude: 40.7000, Longitude: -73.9900},
+			{ID: "MTA_2", Latitude: 40.7045, Longitude: -73.9900},
+			{ID: "MTA_3", Latitude: 40.7090, Longitude: -73.9900},
+		},
+		SegmentSpeeds: []float64{6},
+		Start:         start,
+		Headway:       2 * time.Minute,
+		Trips:         4,
+	})
+	assert.NoError(t, err)
+	sim := fs.Run()
+
+	hm := newHeadwayMonitor()
+	var stored []bus.Headway
+	for now := start; now.Before(start.Add(30 * time.Minute)); now = now.Add(30 * time.Second) {
+		stored = append(stored, hm.observe(sim.At(now))...)
+	}
+	assert.NotEmpty(t, stored)
+	for _, h := range stored {
+		assert.InDelta(t, 120, h.HeadwaySeconds, 35)
+		assert.Equal(t, bus.HeadwayNormal, h.Status)
+	}
+
+	previousHeadways, previousSnapshots := headways, snapshots
+	defer func() { headways, snapshots = previousHeadways, previousSnapshots }()
+	headways = hm
+	snapshots = newSnapshotStore()
+
+	// Headways aren't served until vehicle data has been fetched
+	rec := httptest.NewRecorder()
+	headwayRequestHandler(rec, httptest.NewRequest("GET", "/api/v1/headways", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+
+	snapshots.publish(vehicleMonitoring{Journeys: sim.At(start.Add(30 * time.Minute))}, time.Now())
+	rec = httptest.NewRecorder()
+	headwayRequestHandler(rec, httptest.NewRequest("GET", "/api/v1/headways?StopPointRef=MTA_2", nil))
+	assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+	assert.Equal(t, "1", rec.Header().Get(versionHeader))
+	assert.Equal(t, "false", rec.Header().Get(staleHeader))
+	var response []bus.Headway
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &response))
+	assert.NotEmpty(t, response)
+	for _, h := range response {
+		assert.Equal(t, "MTA_2", h.StopID)
+	}
+}
+
+func TestSatisfiesHeadwayFilters(t *testing.T) {
+	h := bus.Headway{RouteID: "MTA NYCT_B59", DirectionID: 1, StopID: "MTA_2", Status: bus.HeadwayNormal}
+	assert.True(t, satisfiesHeadwayFilters(h, url.Values{"LineRef": {"MTA NYCT_B41", "MTA NYCT_B59"}}))
+	assert.True(t, satisfiesHeadwayFilters(h, url.Values{"LineRef": {"MTA NYCT_B41,MTA NYCT_B59"}, "DirectionRef": {"1"}}))
+	assert.False(t, satisfiesHeadwayFilters(h, url.Values{"LineRef": {"MTA NYCT_B59"}, "DirectionRef": {"0"}}))
+	assert.False(t, satisfiesHeadwayFilters(h, url.Values{"StopPointRef": {"MTA_1", "MTA_3"}}))
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"fmt"
+	"sync"
+	"time"
+	"transport/lib/health"
+)
+
+const (
+	// readyFetchIntervals is how many fetch intervals can pass without a successful
+	// fetch before the service is no longer ready
+	readyFetchIntervals = 3
+	// maxWriterLag is how long a snapshot can wait to be stored in the DB before
+	// the service is no longer ready
+	maxWriterLag = 2 * fetchFrequency
+)
+
+// writerStatus records the progress of the goroutine storing snapshots in the DB
+type writerStatus struct {
+	mutex   sync.Mutex
+	version uint64
+	err     error
+}
+
+// Progress of the DB writer started in main.go
+var writer = &writerStatus{}
+
+// stored records the outcome of storing a snapshot, `err` being nil if it succeeded
+func (ws *writerStatus) stored(snap *snapshot, err error) {
+	ws.mutex.Lock()
+	defer ws.mutex.Unlock()
+	ws.version, ws.err = snap.Version, err
+}
+
+// check fails if the last insert failed, or if the current snapshot has been waiting to be
+// stored for longer than maxWriterLag. Restored snapshots were stored by the previous run.
+func (ws *writerStatus) check(snapshots *snapshotStore, now time.Time) error {
+	ws.mutex.Lock()
+	defer ws.mutex.Unlock()
+	if ws.err != nil {
+		return fmt.Errorf("storing snapshot version %d failed: %s", ws.version, ws.err)
+	}
+	current := snapshots.load()
+	if current.Restored || current.Version == 0 || current.Version <= ws.version {
+		return nil
+	}
+	if lag := current.age(now); lag > maxWriterLag {
+		return fmt.Errorf("snapshot version %d has been waiting to be stored for %s", current.Version, lag.Round(time.Second))
+	}
+	return nil
+}
+
+// lastFetch returns when the current snapshot was fetched, or the zero time
+// if nothing has been fetched since startup
+func lastFetch(snapshots *snapshotStore) time.Time {
+	snap := snapshots.load()
+	if snap.Restored {
+		return time.Time{}
+	}
+	return snap.FetchedAt
+}
+
+// newReadinessChecker creates the checks behind /readyz. The service is ready once data has been
+// loaded (fetched, or restored from disk), the last successful fetch was recent, and (unless
+// `checkWriter` is false, e.g. when replaying) the DB writer is keeping up.
+func newReadinessChecker(snapshots *snapshotStore, checkWriter bool) *health.Checker {
+	ready := health.NewChecker()
+	ready.Register("snapshot", func() error {
+		if snapshots.load().Version == 0 {
+			return fmt.Errorf("no vehicle data has been loaded yet")
+		}
+		return nil
+	})
+	ready.Register("fetch", health.MaxAgeCheck("successful fetch", func() time.Time {
+		return lastFetch(snapshots)
+	}, readyFetchIntervals*fetchFrequency))
+	if checkWriter {
+		ready.Register("db_writer", func() error {
+			return writer.check(snapshots, time.Now())
+		})
+	}
+	return ready
+}
+
+// Readiness checks served at /readyz, which are replaced in main.go depending on the mode
+var readiness = health.NewChecker()
+
+// Liveness checks served at /healthz. The process is alive if it can respond at all.
+var liveness = health.NewChecker()
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"errors"
+	"testing"
+	"time"
+	"transport/lib/health"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWriterStatus_Check(t *testing.T) {
+	ss := newSnapshotStore()
+	ws := &writerStatus{}
+	now := time.Now()
+	assert.Nil(t, ws.check(ss, now))
+
+	// A snapshot waiting to be stored is fine, until it has waited too long
+	snap := ss.publish(vehicleMonitoring{}, now)
+	assert.Nil(t, ws.check(ss, now.Add(time.Second)))
+	assert.NotNil(t, ws.check(ss, now.Add(maxWriterLag+time.Second)))
+
+	ws.stored(snap, nil)
+	assert.Nil(t, ws.check(ss, now.Add(maxWriterLag+time.Second)))
+
+	ws.stored(ss.publish(vehicleMonitoring{}, now), errors.New("connection reset"))
+	assert.EqualError(t, ws.check(ss, now), "storing snapshot version 2 failed: connection reset")
+}
+
+func TestReadinessChecker(t *testing.T) {
+	ss := newSnapshotStore()
+	report := newReadinessChecker(ss, false).Report()
+	assert.Equal(t, health.Down, report.Status)
+	assert.Equal(t, health.Down, report.Components["snapshot"].Status)
+	assert.Equal(t, health.Down, report.Components["fetch"].Status)
+	assert.NotContains(t, report.Components, "db_writer")
+
+	// Data restored from disk is served, but isn't a successful fetch
+	ss.restore(&snapshot{Version: 4, FetchedAt: time.Now().Add(-time.Hour)})
+	report = newReadinessChecker(ss, true).Report()
+	assert.Equal(t, health.Up, report.Components["snapshot"].Status)
+	assert.Equal(t, health.Down, report.Components["fetch"].Status)
+	assert.Equal(t, health.Up, report.Components["db_writer"].Status)
+
+	ss.publish(vehicleMonitoring{}, time.Now())
+	assert.Equal(t, health.Up, newReadinessChecker(ss, false).Report().Status)
+}
@@ -1,20 +1,55 @@
 package main
 
 import (
+	"log"
+	"os"
 	"transport/lib/bus"
+	"transport/lib/database"
 	"transport/lib/iohelper"
 )
 
 // Currently cached data from MTA
-var vehicleData []bus.VehicleJourney
+var snapshots = newSnapshotStore()
 
 func main() {
-	// Create a channel which is written to when new data is finished being written
-	dataIncoming := make(chan bool)
-	// When new data arrives, store it in the historical DB
-	go store(&vehicleData, dataIncoming)
-	// Set up data polling
-	initialiseDataFetching(iohelper.GetEnv("MTA_API_KEY"), &vehicleData, dataIncoming)
+	// Create a channel which is written to when a new snapshot has been published
+	dataIncoming := make(chan *snapshot)
+	if len(os.Args) > 1 && os.Args[1] == "replay" {
+		initialiseReplayMode(os.Args[2:], dataIncoming)
+	} else {
+		readiness = newReadinessChecker(snapshots, true)
+		// Serve the last snapshot from the previous run until new data is fetched
+		warmStart(snapshotPath, snapshots)
+		db := database.OpenDBConnection()
+		headways = newHeadwayMonitor(bus.StopDistancesOption(loadStopDistances(db)))
+		// When new data arrives, store it in the historical DB
+		go store(db, dataIncoming)
+		// Set up data polling
+		initialiseDataFetching(iohelper.GetEnv("MTA_API_KEY"), lineRefsFromEnv(), snapshots, dataIncoming)
+	}
 	// Start HTTP server
 	initialiseServer()
 }
+
+// Serves recorded data instead of live data. Replayed snapshots are already in the
+// historical DB, so they are only used to track headways.
+func initialiseReplayMode(args []string, dataIncoming chan *snapshot) {
+	readiness = newReadinessChecker(snapshots, false)
+	config, err := parseReplayConfig(args)
+	if err != nil {
+		log.Fatalf("Invalid replay arguments: %s", err)
+	}
+	source, err := loadReplaySource(config.Source)
+	if err != nil {
+		log.Fatalf("Failed to open replay source: %s", err)
+	}
+	if ds, ok := source.(dbSource); ok {
+		headways = newHeadwayMonitor(bus.StopDistancesOption(loadStopDistances(ds.db)))
+	}
+	go func() {
+		for snap := range dataIncoming {
+			headways.observe(snap.Journeys)
+		}
+	}()
+	initialiseReplay(source, config, snapshots, dataIncoming)
+}
@@ -0,0 +1,32 @@
+package main
+
+import (
+	"math"
+	"time"
+	"transport/lib/metrics"
+)
+
+// Metrics describing the fetching and storing of live data, served at /metrics
+var (
+	fetchDuration    = metrics.NewHistogram("vehicle_fetch_duration_seconds", "Time taken to fetch and parse vehicle data from the MTA.", nil)
+	fetchErrors      = metrics.NewCounter("vehicle_fetch_errors_total", "Fetches of vehicle data from the MTA that failed.")
+	snapshotVehicles = metrics.NewGauge("snapshot_vehicles", "Number of vehicles in the current snapshot.")
+)
+
+func init() {
+	metrics.NewGaugeFunc("snapshot_age_seconds", "Seconds since the current snapshot was fetched, +Inf if nothing has been fetched yet.", snapshotAge)
+	metrics.NewGaugeFunc("active_subscriptions", "Clients currently streaming vehicle updates.", func() float64 {
+		return float64(snapshots.subscriberCount())
+	})
+}
+
+// snapshotAge returns the age of the current snapshot in seconds. Until this process has
+// fetched data (including while serving a snapshot restored from disk), the age is +Inf,
+// so that alerts on the age fire rather than seeing a fresh snapshot.
+func snapshotAge() float64 {
+	snap := snapshots.load()
+	if snap.Version == 0 || snap.Restored {
+		return math.Inf(1)
+	}
+	return snap.age(time.Now()).Seconds()
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+	"transport/lib/metrics"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMetrics_Snapshot(t *testing.T) {
+	previous := snapshots
+	defer func() { snapshots = previous }()
+	snapshots = newSnapshotStore()
+
+	// Nothing has been fetched yet, so the snapshot is infinitely old
+	rec := httptest.NewRecorder()
+	metrics.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	assert.Contains(t, rec.Body.String(), "snapshot_age_seconds +Inf\n")
+
+	// As is a snapshot restored from disk, until the first fetch succeeds
+	snapshots.restore(&snapshot{Version: 3, FetchedAt: time.Now()})
+	rec = httptest.NewRecorder()
+	metrics.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	assert.Contains(t, rec.Body.String(), "snapshot_age_seconds +Inf\n")
+
+	snapshots.publish(vehicleMonitoring{}, time.Now().Add(-time.Minute))
+	_, cancel := snapshots.subscribe()
+	defer cancel()
+
+	rec = httptest.NewRecorder()
+	metrics.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	body := rec.Body.String()
+	assert.Contains(t, body, "# TYPE snapshot_age_seconds gauge\nsnapshot_age_seconds 60")
+	assert.Contains(t, body, "active_subscriptions 1\n")
+	assert.Contains(t, body, "# TYPE vehicle_fetch_duration_seconds histogram")
+}
@@ -2,7 +2,8 @@ package main
 
 import (
 	"encoding/json"
-	"log"
+	"fmt"
+	"strings"
 	"transport/lib/bus"
 	"transport/lib/database"
 	"transport/lib/nulltypes"
@@ -10,29 +11,37 @@ import (
 	"gopkg.in/guregu/null.v3"
 )
 
-// Takes a JSON string representing an MTAVehicleMonitoringResponse and return
-// a slice containing the same data in the internal VehicleJourney format
-func convertToIR(jsonString []byte) []bus.VehicleJourney {
+// Takes a JSON string representing an MTAVehicleMonitoringResponse and returns the same
+// data in the internal VehicleJourney and ServiceAlert formats, along with the response's timestamps.
+// Returns an error if the response has no VehicleMonitoringDelivery, or the delivery reports
+// an ErrorCondition, so that an error is never mistaken for an empty fleet.
+func convertToIR(jsonString []byte) (vehicleMonitoring, error) {
 	var response MTAVehicleMonitoringResponse
 	err := json.Unmarshal(jsonString, &response)
 	if err != nil {
-		log.Printf("error parsing JSON: %v", err)
-		return []bus.VehicleJourney{}
+		return vehicleMonitoring{}, fmt.Errorf("error parsing JSON: %s", err)
 	}
 
-	delivery := response.Siri.ServiceDelivery.VehicleMonitoringDelivery
+	serviceDelivery := response.Siri.ServiceDelivery
+	vm := vehicleMonitoring{Journeys: []bus.VehicleJourney{}, ResponseTimestamp: serviceDelivery.ResponseTimestamp.Time}
+	vm.Alerts = getServiceAlerts(serviceDelivery.SituationExchangeDelivery)
+	delivery := serviceDelivery.VehicleMonitoringDelivery
 	if len(delivery) == 0 {
-		return []bus.VehicleJourney{}
+		return vehicleMonitoring{}, fmt.Errorf("response has no VehicleMonitoringDelivery")
 	}
+	if delivery[0].ErrorCondition != nil {
+		return vehicleMonitoring{}, fmt.Errorf("response has an ErrorCondition: %s", delivery[0].ErrorCondition)
+	}
+	vm.ValidUntil = delivery[0].ValidUntil.Time
 	externalJourneys := delivery[0].VehicleActivity
-	var internalJourneys = make([]bus.VehicleJourney, len(externalJourneys))
+	vm.Journeys = make([]bus.VehicleJourney, len(externalJourneys))
 
 	for i, jrny := range externalJourneys {
 		mvj, ts := jrny.MonitoredVehicleJourney, jrny.RecordedAtTime
-		internalJourneys[i] = getVehicleJourney(mvj, ts)
+		vm.Journeys[i] = getVehicleJourney(mvj, ts)
 	}
 
-	return internalJourneys
+	return vm, nil
 }
 
 // Converts an MTAMonitoredVehicleJourney into the internal VehicleJourney format
@@ -61,6 +70,34 @@ func getVehicleJourney(mvj MTAMonitoredVehicleJourney, timestamp database.Timest
 	}
 }
 
+// Converts every situation in the SituationExchange deliveries into the internal ServiceAlert format
+func getServiceAlerts(deliveries []MTASituationExchangeDelivery) []bus.ServiceAlert {
+	alerts := []bus.ServiceAlert{}
+	for _, delivery := range deliveries {
+		for _, situation := range delivery.Situations.PtSituationElement {
+			alerts = append(alerts, getServiceAlert(situation))
+		}
+	}
+	return alerts
+}
+
+// Converts an MTAPTSituationElement into the internal ServiceAlert format
+func getServiceAlert(situation MTAPTSituationElement) bus.ServiceAlert {
+	alert := bus.ServiceAlert{
+		SituationNumber:  situation.SituationNumber,
+		Severity:         situation.Severity,
+		Summary:          strings.Join(situation.Summary, "\n"),
+		Description:      strings.Join(situation.Description, "\n"),
+		CreationTime:     situation.CreationTime,
+		PublicationStart: situation.PublicationWindow.StartTime,
+		PublicationEnd:   nulltypes.TimestampFrom(situation.PublicationWindow.EndTime),
+	}
+	for _, affected := range situation.Affects.VehicleJourneys.AffectedVehicleJourney {
+		alert.Affected = append(alert.Affected, bus.AffectedRoute{RouteID: affected.LineRef, DirectionID: affected.DirectionRef})
+	}
+	return alert
+}
+
 // Converts a slice of MTASituationRef into a slice of strings
 // representing just the IDs found in MTASituationRef
 func flattenSituationRef(refs []MTASituationRef) []string {
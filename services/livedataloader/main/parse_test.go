@@ -0,0 +1,38 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConvertToIR_Errors(t *testing.T) {
+	tests := []struct {
+		name     string
+		response string
+		err      string
+	}{
+		{"empty object", `{}`, "response has no VehicleMonitoringDelivery"},
+		{"error body", `{"Siri": {"ServiceDelivery": {"ResponseTimestamp": "2019-06-03T08:00:00.000-04:00"}}}`, "response has no VehicleMonitoringDelivery"},
+		{"error condition", `{"Siri": {"ServiceDelivery": {"VehicleMonitoringDelivery": [{
+			"ResponseTimestamp": "2019-06-03T08:00:00.000-04:00",
+			"ErrorCondition": {"OtherError": {"ErrorText": "No such route: MTA NYCT_X1"}, "Description": "Invalid LineRef"}
+		}]}}}`, "response has an ErrorCondition: No such route: MTA NYCT_X1"},
+		{"error description", `{"Siri": {"ServiceDelivery": {"VehicleMonitoringDelivery": [{
+			"ErrorCondition": {"Description": "API key is not authorized"}
+		}]}}}`, "response has an ErrorCondition: API key is not authorized"},
+	}
+	for _, test := range tests {
+		vm, err := convertToIR([]byte(test.response))
+		if assert.Error(t, err, test.name) {
+			assert.Equal(t, test.err, err.Error(), test.name)
+		}
+		assert.Nil(t, vm.Journeys, test.name)
+	}
+
+	// Whereas a delivery without any vehicles is a genuinely empty fleet
+	vm, err := convertToIR([]byte(`{"Siri": {"ServiceDelivery": {"VehicleMonitoringDelivery": [{"VehicleActivity": []}]}}}`))
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(vm.Journeys))
+	assert.NotNil(t, vm.Journeys)
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"transport/lib/iohelper"
+)
+
+// The last good snapshot is saved to the file at SNAPSHOT_PATH, so that it can be
+// served straight away after a restart, even if the MTA can't be reached
+const (
+	snapshotPathEnvVar  = "SNAPSHOT_PATH"
+	defaultSnapshotPath = "snapshot.json"
+)
+
+// Path of the file the last good snapshot is saved to
+var snapshotPath = iohelper.GetEnvOrDefault(snapshotPathEnvVar, defaultSnapshotPath)
+
+// saveSnapshot writes the snapshot to `path` as JSON. The snapshot is written to a temporary
+// file which then replaces the previous one, so a crash part way through never leaves a
+// partially written snapshot behind.
+func saveSnapshot(path string, snap *snapshot) error {
+	data, err := json.Marshal(snap)
+	if err != nil {
+		return fmt.Errorf("saveSnapshot: error encoding snapshot version %d: %s", snap.Version, err)
+	}
+	tmp, err := ioutil.TempFile(filepath.Dir(path), filepath.Base(path)+".tmp")
+	if err != nil {
+		return fmt.Errorf("saveSnapshot: error creating temporary file: %s", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("saveSnapshot: error writing %s: %s", tmp.Name(), err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("saveSnapshot: error closing %s: %s", tmp.Name(), err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("saveSnapshot: error replacing %s: %s", path, err)
+	}
+	return nil
+}
+
+// loadSnapshot reads a snapshot previously written by saveSnapshot
+func loadSnapshot(path string) (*snapshot, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("loadSnapshot: error reading %s: %s", path, err)
+	}
+	var snap snapshot
+	if err := json.Unmarshal(data, &snap); err != nil {
+		return nil, fmt.Errorf("loadSnapshot: error parsing %s: %s", path, err)
+	}
+	return &snap, nil
+}
+
+// Restores the snapshot saved at `path` into `snapshots`, so it is served until the first
+// successful fetch. Starts with no data if there isn't a saved snapshot.
+func warmStart(path string, snapshots *snapshotStore) {
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		log.Printf("No saved snapshot found at %s, starting without data\n", path)
+		return
+	}
+	snap, err := loadSnapshot(path)
+	if err != nil {
+		log.Printf("Failed to restore saved snapshot, starting without data: %s\n", err)
+		return
+	}
+	snapshots.restore(snap)
+	log.Printf("Restored snapshot version %d with %d vehicles, fetched at %s\n", snap.Version, len(snap.Journeys), snap.FetchedAt)
+}
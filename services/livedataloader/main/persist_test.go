@@ -0,0 +1,73 @@
+package main
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+	"transport/lib/bus"
+	"transport/lib/database"
+	"transport/lib/nulltypes"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/guregu/null.v3"
+)
+
+func TestWarmStart(t *testing.T) {
+	dir, err := ioutil.TempDir("", "snapshot")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "snapshot.json")
+
+	// Nothing is restored if a snapshot hasn't been saved yet
+	ss := newSnapshotStore()
+	warmStart(path, ss)
+	assert.Equal(t, uint64(0), ss.load().Version)
+
+	fetchedAt := time.Date(2019, 6, 3, 8, 0, 0, 0, database.TimeLoc)
+	vj := bus.VehicleJourney{
+		VehicleRef: null.StringFrom("MTA NYCT_3814"),
+		Latitude:   null.FloatFrom(40.721857),
+		Timestamp:  nulltypes.TimestampFrom(database.Timestamp{Time: fetchedAt.Add(-time.Minute)}),
+	}
+	previousRun := newSnapshotStore()
+	previousRun.publish(vehicleMonitoring{}, fetchedAt.Add(-time.Minute))
+	saved := previousRun.publish(vehicleMonitoring{Journeys: []bus.VehicleJourney{vj}}, fetchedAt)
+	assert.Nil(t, saveSnapshot(path, saved))
+
+	warmStart(path, ss)
+	restored := ss.load()
+	assert.Equal(t, uint64(2), restored.Version)
+	assert.True(t, restored.Restored)
+	assert.True(t, fetchedAt.Equal(restored.FetchedAt))
+	assert.Equal(t, "MTA NYCT_3814", restored.Journeys[0].VehicleRef.String)
+	assert.True(t, vj.Timestamp.Equal(restored.Journeys[0].Timestamp.Time))
+	assert.True(t, ss.stale(restored, fetchedAt))
+
+	// Versions carry on from the restored snapshot
+	assert.Equal(t, uint64(3), ss.publish(vehicleMonitoring{}, time.Now()).Version)
+}
+
+func TestLiveDataRequestHandler_NoData(t *testing.T) {
+	previous := snapshots
+	defer func() { snapshots = previous }()
+
+	// Before any data has been fetched, clients are told the data is unavailable
+	snapshots = newSnapshotStore()
+	snapshots.fetchFailed("all routes")
+	rec := httptest.NewRecorder()
+	liveDataRequestHandler(rec, httptest.NewRequest(http.MethodGet, "/api/v1/vehicles", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+
+	// Whereas a fetch that found no vehicles returns an empty list
+	snapshots.fetchSucceeded("all routes")
+	snapshots.publish(vehicleMonitoring{Journeys: []bus.VehicleJourney{}}, time.Now())
+	rec = httptest.NewRecorder()
+	liveDataRequestHandler(rec, httptest.NewRequest(http.MethodGet, "/api/v1/vehicles", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "[]", rec.Body.String())
+	assert.Equal(t, "false", rec.Header().Get(staleHeader))
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"sync"
+	"time"
+	"transport/lib/bus"
+)
+
+const (
+	// minFetchInterval is the shortest time between fetches of a feed, as the MTA asks
+	// clients not to poll more than once every 30 seconds
+	minFetchInterval = 30 * time.Second
+	// maxFetchInterval is the longest time between successful fetches of a feed, in case a
+	// response says it is valid for much longer than usual. It is kept below staleAfter,
+	// so that data isn't marked as stale between successful fetches.
+	maxFetchInterval = 90 * time.Second
+	// validityMargin is how long after a response stops being valid to fetch
+	// again, so that the MTA has had time to publish new data
+	validityMargin = time.Second
+	// maxBackoff is the longest time to wait between fetches when they are failing
+	maxBackoff = 5 * time.Minute
+)
+
+// nextFetchDelay returns how long to wait before fetching a feed again, given the result of the
+// last fetch, the number of failures in a row and how long the fetch took. After a successful
+// fetch, the next one is timed for just after the data stops being valid (according to its
+// ResponseTimestamp and ValidUntil), so new data is picked up as soon as it is published without
+// polling any more often. Failures back off exponentially, and rate limited requests wait for at
+// least as long as the MTA asks.
+func nextFetchDelay(vm vehicleMonitoring, err error, failures int, elapsed time.Duration) time.Duration {
+	if err != nil {
+		backoff := fetchFrequency
+		for i := 1; i < failures && backoff < maxBackoff; i++ {
+			backoff *= 2
+		}
+		if backoff > maxBackoff {
+			backoff = maxBackoff
+		}
+		if rateLimited, ok := err.(rateLimitedError); ok && rateLimited.retryAfter > backoff {
+			return rateLimited.retryAfter
+		}
+		return backoff
+	}
+	validFor := vm.ValidUntil.Sub(vm.ResponseTimestamp)
+	if vm.ValidUntil.IsZero() || vm.ResponseTimestamp.IsZero() || validFor <= 0 {
+		return fetchFrequency
+	}
+	// The response was created around the time the fetch started, so part of the validity
+	// window has already passed. Using durations (rather than comparing ValidUntil with the
+	// local clock) means differences between our clock and the MTA's don't matter.
+	delay := validFor + validityMargin - elapsed
+	if delay < minFetchInterval {
+		return minFetchInterval
+	}
+	if delay > maxFetchInterval {
+		return maxFetchInterval
+	}
+	return delay
+}
+
+// feedMerger combines the latest data from each feed into a single vehicleMonitoring,
+// and keeps track of which reports have already been sent to the DB
+type feedMerger struct {
+	mutex     sync.Mutex
+	feeds     []feed
+	latest    []*vehicleMonitoring
+	fetchedAt []time.Time
+	// The routes that have their own feed
+	routeFeeds map[string]bool
+	// The Timestamp of the latest report stored for each VehicleRef
+	stored map[string]time.Time
+}
+
+func newFeedMerger(feeds []feed) *feedMerger {
+	fm := &feedMerger{
+		feeds:      feeds,
+		latest:     make([]*vehicleMonitoring, len(feeds)),
+		fetchedAt:  make([]time.Time, len(feeds)),
+		routeFeeds: map[string]bool{},
+		stored:     map[string]time.Time{},
+	}
+	for _, f := range feeds {
+		if f.lineRef != "" {
+			fm.routeFeeds[f.lineRef] = true
+		}
+	}
+	return fm
+}
+
+// update replaces the data from the feed at `index`, fetched at `fetchedAt`, and returns the
+// merged data from every feed along with when it was fetched. If a vehicle appears in several
+// feeds (e.g. it changed route between fetches), its most recent report is kept. The merged data
+// is only as fresh as the oldest feed, so it takes the earliest ResponseTimestamp, ValidUntil and
+// fetch time. This means the snapshot's age shows when any one feed has stopped updating.
+// The reports from `vm` that should be stored in the DB are also returned (see unstored).
+func (fm *feedMerger) update(index int, vm vehicleMonitoring, fetchedAt time.Time) (vehicleMonitoring, time.Time, []bus.VehicleJourney) {
+	fm.mutex.Lock()
+	defer fm.mutex.Unlock()
+	fm.latest[index] = &vm
+	fm.fetchedAt[index] = fetchedAt
+	fresh := fm.unstored(index, vm.Journeys)
+	if len(fm.latest) == 1 {
+		return vm, fetchedAt, fresh
+	}
+	merged := vehicleMonitoring{Journeys: []bus.VehicleJourney{}, Alerts: []bus.ServiceAlert{}}
+	var mergedFetchedAt time.Time
+	vehicles, alerts := map[string]int{}, map[string]bool{}
+	for f, latest := range fm.latest {
+		if latest == nil {
+			continue
+		}
+		for _, vj := range latest.Journeys {
+			i, found := vehicles[vj.VehicleRef.String]
+			if !found {
+				vehicles[vj.VehicleRef.String] = len(merged.Journeys)
+				merged.Journeys = append(merged.Journeys, vj)
+			} else if vj.Timestamp.After(merged.Journeys[i].Timestamp.Time) {
+				merged.Journeys[i] = vj
+			}
+		}
+		for _, alert := range latest.Alerts {
+			if !alerts[alert.SituationNumber] {
+				alerts[alert.SituationNumber] = true
+				merged.Alerts = append(merged.Alerts, alert)
+			}
+		}
+		merged.ResponseTimestamp = earliest(merged.ResponseTimestamp, latest.ResponseTimestamp)
+		merged.ValidUntil = earliest(merged.ValidUntil, latest.ValidUntil)
+		mergedFetchedAt = earliest(mergedFetchedAt, fm.fetchedAt[f])
+	}
+	return merged, mergedFetchedAt, fresh
+}
+
+// unstored returns the reports from the feed at `index` that are newer than the last report
+// stored for the same vehicle, and records them as stored. Vehicles on routes with their own
+// feed are only stored from that feed, so the whole-fleet feed skips them. Without this, the
+// same report would be stored once per feed, and again whenever a vehicle hasn't reported
+// since the last fetch.
+func (fm *feedMerger) unstored(index int, journeys []bus.VehicleJourney) []bus.VehicleJourney {
+	fresh := []bus.VehicleJourney{}
+	for _, vj := range journeys {
+		if fm.feeds[index].lineRef == "" && fm.routeFeeds[vj.LineRef.String] {
+			continue
+		}
+		if last, found := fm.stored[vj.VehicleRef.String]; found && !vj.Timestamp.After(last) {
+			continue
+		}
+		fm.stored[vj.VehicleRef.String] = vj.Timestamp.Time
+		fresh = append(fresh, vj)
+	}
+	return fresh
+}
+
+// earliest returns the earlier of two times, ignoring zero times
+func earliest(a time.Time, b time.Time) time.Time {
+	if a.IsZero() || (!b.IsZero() && b.Before(a)) {
+		return b
+	}
+	return a
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+	"transport/lib/bus"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNextFetchDelay(t *testing.T) {
+	now := time.Date(2019, 6, 3, 8, 0, 0, 0, time.UTC)
+	validFor := func(d time.Duration) vehicleMonitoring {
+		return vehicleMonitoring{ResponseTimestamp: now, ValidUntil: now.Add(d)}
+	}
+	failed := errors.New("connection refused")
+	tests := []struct {
+		name     string
+		vm       vehicleMonitoring
+		err      error
+		failures int
+		elapsed  time.Duration
+		expected time.Duration
+	}{
+		{"no validity window", vehicleMonitoring{}, nil, 0, 0, fetchFrequency},
+		{"just after the data expires", validFor(50 * time.Second), nil, 0, 2 * time.Second, 49 * time.Second},
+		{"never more often than the minimum", validFor(10 * time.Second), nil, 0, 0, minFetchInterval},
+		{"never less often than the maximum", validFor(time.Hour), nil, 0, 0, maxFetchInterval},
+		{"first failure", vehicleMonitoring{}, failed, 1, 0, fetchFrequency},
+		{"third failure", vehicleMonitoring{}, failed, 3, 0, 4 * fetchFrequency},
+		{"many failures", vehicleMonitoring{}, failed, 20, 0, maxBackoff},
+		{"rate limited", vehicleMonitoring{}, rateLimitedError{retryAfter: 10 * time.Minute}, 1, 0, 10 * time.Minute},
+		{"rate limited without retry after", vehicleMonitoring{}, rateLimitedError{}, 2, 0, 2 * fetchFrequency},
+	}
+	for _, test := range tests {
+		assert.Equal(t, test.expected, nextFetchDelay(test.vm, test.err, test.failures, test.elapsed), test.name)
+	}
+}
+
+func TestFetch_RateLimited(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Retry-After", "120")
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer server.Close()
+	_, err := fetch(server.URL)
+	assert.Equal(t, rateLimitedError{URL: server.URL, retryAfter: 2 * time.Minute}, err)
+}
+
+func TestVehicleFeeds(t *testing.T) {
+	feeds := vehicleFeeds("KEY", nil)
+	assert.Len(t, feeds, 1)
+	assert.Equal(t, "all routes", feeds[0].String())
+
+	// Routes are polled alongside the whole fleet, not instead of it
+	feeds = vehicleFeeds("KEY", []string{"MTA NYCT_B41", "MTA NYCT_M15+"})
+	assert.Len(t, feeds, 3)
+	assert.Equal(t, "all routes", feeds[0].String())
+	assert.Equal(t, vehicleMonitoringURL+"?key=KEY&version=2&LineRef=MTA+NYCT_M15%2B", feeds[2].URL)
+}
+
+// mergerReport creates a report from vehicleRef on lineRef, `seconds` after `now`
+func mergerReport(now time.Time, vehicleRef string, lineRef string, seconds int) bus.VehicleJourney {
+	return bus.ExampleJourney(
+		bus.VehicleRefOption(vehicleRef), bus.LineRefOption(lineRef),
+		bus.TimestampOption(now.Add(time.Duration(seconds)*time.Second)),
+	)
+}
+
+func TestFeedMerger_Update(t *testing.T) {
+	now := time.Date(2019, 6, 3, 8, 0, 0, 0, time.UTC)
+	report := func(vehicleRef string, lineRef string, seconds int) bus.VehicleJourney {
+		return mergerReport(now, vehicleRef, lineRef, seconds)
+	}
+	fm := newFeedMerger([]feed{{lineRef: "B41"}, {lineRef: "M15"}})
+	merged, fetchedAt, _ := fm.update(1, vehicleMonitoring{
+		Journeys:          []bus.VehicleJourney{report("A", "M15", 0), report("B", "M15", 0)},
+		Alerts:            []bus.ServiceAlert{{SituationNumber: "1"}},
+		ResponseTimestamp: now,
+		ValidUntil:        now.Add(30 * time.Second),
+	}, now)
+	assert.Len(t, merged.Journeys, 2)
+	assert.Equal(t, now, fetchedAt)
+
+	// Vehicle B has moved onto the B41, so its newer report from that feed replaces the old one
+	merged, fetchedAt, _ = fm.update(0, vehicleMonitoring{
+		Journeys:          []bus.VehicleJourney{report("B", "B41", 20), report("C", "B41", 20)},
+		Alerts:            []bus.ServiceAlert{{SituationNumber: "1"}, {SituationNumber: "2"}},
+		ResponseTimestamp: now.Add(20 * time.Second),
+		ValidUntil:        now.Add(50 * time.Second),
+	}, now.Add(20*time.Second))
+	assert.Equal(t, []string{"B", "C", "A"}, replayedRefs(merged.Journeys))
+	assert.Equal(t, "B41", merged.Journeys[0].LineRef.String)
+	assert.Len(t, merged.Alerts, 2)
+	assert.Equal(t, now, merged.ResponseTimestamp)
+	assert.Equal(t, now.Add(30*time.Second), merged.ValidUntil)
+	// The merged data is as old as the feed that was fetched longest ago
+	assert.Equal(t, now, fetchedAt)
+}
+
+func TestFeedMerger_Unstored(t *testing.T) {
+	now := time.Date(2019, 6, 3, 8, 0, 0, 0, time.UTC)
+	fm := newFeedMerger(vehicleFeeds("KEY", []string{"B41"}))
+	fetch := func(index int, journeys ...bus.VehicleJourney) []string {
+		_, _, fresh := fm.update(index, vehicleMonitoring{Journeys: journeys}, now)
+		return replayedRefs(fresh)
+	}
+
+	// Vehicles on the B41 are only stored from the B41's own feed
+	assert.Equal(t, []string{"A"}, fetch(0, mergerReport(now, "A", "M15", 0), mergerReport(now, "B", "B41", 0)))
+	assert.Equal(t, []string{"B"}, fetch(1, mergerReport(now, "B", "B41", 0)))
+
+	// Reports are only stored once, even if they are fetched again
+	assert.Empty(t, fetch(0, mergerReport(now, "A", "M15", 0), mergerReport(now, "B", "B41", 0)))
+	assert.Equal(t, []string{"A"}, fetch(0, mergerReport(now, "A", "M15", 30), mergerReport(now, "B", "B41", 30)))
+}
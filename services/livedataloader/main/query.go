@@ -0,0 +1,399 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"transport/lib/bus"
+	"transport/lib/database"
+	"transport/lib/geo"
+)
+
+/*
+Vehicle queries are written as URL query parameters, e.g.
+/api/v1/vehicles?LineRef=MTA NYCT_B41,MTA NYCT_B59&NumberOfStopsAway<=3&sort=-Timestamp&limit=10
+
+- Field=a,b (or Field=a&Field=b) matches journeys where the field is any of the values
+- Field!=a,b matches journeys where the field is none of the values
+- Field<x, Field<=x, Field>x and Field>=x compare numbers, times and strings
+- Field=abc* matches strings that start with "abc"
+- Field=null matches journeys where the field is missing
+- bbox=minLon,minLat,maxLon,maxLat matches journeys inside the bounding box
+- near=lat,lon&radius=metres matches journeys within the radius (defaults to 500m) of the point
+- fields=VehicleRef,Latitude only returns the listed fields of each journey
+- sort=Field,-Field sorts by each field in turn, descending if prefixed with '-'
+- offset=n&limit=m skips the first n matches and returns at most m of the rest
+- format=json|protobuf|geojson|csv|gtfsrt chooses the response format, overriding the Accept header
+
+GTFS-Realtime feeds must be requested with format=gtfsrt. They share the application/x-protobuf
+media type with our own protobuf format, so an Accept header of application/x-protobuf always
+returns a busproto VehicleJourneyList, which GTFS-Realtime consumers can't decode.
+
+Different fields (or operators) must all match, whereas multiple values for the same field
+and operator only need one to match. Times can be given in RFC3339 or database.TimeFormat.
+*/
+
+// Query parameters with special meanings, rather than filtering on a field
+const (
+	fieldsParam = "fields"
+	sortParam   = "sort"
+	limitParam  = "limit"
+	offsetParam = "offset"
+	bboxParam   = "bbox"
+	nearParam   = "near"
+	radiusParam = "radius"
+	formatParam = "format"
+)
+
+// defaultRadius is the radius (in metres) used by `near` if no radius is given
+const defaultRadius = 500.0
+
+// Matches a single query term, such as NumberOfStopsAway<=3
+var termPattern = regexp.MustCompile(`^([A-Za-z]+)(<=|>=|!=|<|>|=)(.*)$`)
+
+// operand is a single value that a field is compared against
+type operand struct {
+	null   bool
+	prefix bool
+	value  fieldValue
+}
+
+// condition is a field, operator and the values to compare the field against
+type condition struct {
+	field    vehicleField
+	op       string
+	operands []operand
+}
+
+// sortKey is a field to sort by
+type sortKey struct {
+	field      vehicleField
+	descending bool
+}
+
+// vehicleQuery is a parsed vehicle query
+type vehicleQuery struct {
+	conditions []condition
+	areas      []func(geo.Point) bool
+	fields     []string
+	sortKeys   []sortKey
+	offset     int
+	limit      int
+	format     string
+}
+
+// parseVehicleQuery parses the raw (still escaped) query string of a request. Query strings
+// are parsed by hand rather than using url.ParseQuery, which splits terms such as
+// NumberOfStopsAway<=3 on the '=' and so loses the operator.
+func parseVehicleQuery(rawQuery string) (*vehicleQuery, error) {
+	q := &vehicleQuery{}
+	conditions := map[string]*condition{}
+	var order []string
+	var near *geo.Point
+	radius := defaultRadius
+	for _, rawTerm := range strings.Split(rawQuery, "&") {
+		if rawTerm == "" {
+			continue
+		}
+		term, err := url.QueryUnescape(rawTerm)
+		if err != nil {
+			return nil, fmt.Errorf("invalid query term '%s': %s", rawTerm, err)
+		}
+		match := termPattern.FindStringSubmatch(term)
+		if match == nil {
+			return nil, fmt.Errorf("invalid query term '%s', expected a field, operator and value (e.g. LineRef=MTA NYCT_B41)", term)
+		}
+		name, op, value := match[1], match[2], match[3]
+		switch name {
+		case fieldsParam, sortParam, limitParam, offsetParam, bboxParam, nearParam, radiusParam, formatParam:
+			if op != "=" {
+				return nil, fmt.Errorf("'%s' only supports '=', got '%s'", name, op)
+			}
+		}
+		switch name {
+		case fieldsParam:
+			for _, field := range strings.Split(value, ",") {
+				if _, found := vehicleFields[field]; !found {
+					return nil, fmt.Errorf("unknown field '%s' in %s", field, fieldsParam)
+				}
+				q.fields = append(q.fields, field)
+			}
+		case sortParam:
+			for _, key := range strings.Split(value, ",") {
+				descending := strings.HasPrefix(key, "-")
+				field, found := vehicleFields[strings.TrimPrefix(key, "-")]
+				if !found {
+					return nil, fmt.Errorf("unknown field '%s' in %s", key, sortParam)
+				}
+				q.sortKeys = append(q.sortKeys, sortKey{field, descending})
+			}
+		case limitParam, offsetParam:
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 0 {
+				return nil, fmt.Errorf("%s must be a non-negative integer, got '%s'", name, value)
+			}
+			if name == limitParam {
+				q.limit = n
+			} else {
+				q.offset = n
+			}
+		case bboxParam:
+			area, err := parseBoundingBox(value)
+			if err != nil {
+				return nil, err
+			}
+			q.areas = append(q.areas, area)
+		case nearParam:
+			coords, err := parseFloats(value, 2)
+			if err != nil {
+				return nil, fmt.Errorf("%s must be 'lat,lon': %s", nearParam, err)
+			}
+			near = &geo.Point{Latitude: coords[0], Longitude: coords[1]}
+		case radiusParam:
+			radius, err = strconv.ParseFloat(value, 64)
+			if err != nil || radius < 0 {
+				return nil, fmt.Errorf("%s must be a non-negative number of metres, got '%s'", radiusParam, value)
+			}
+		case formatParam:
+			if _, found := vehicleEncoders[value]; !found {
+				return nil, fmt.Errorf("unknown %s '%s', expected one of json, protobuf, geojson, csv or gtfsrt", formatParam, value)
+			}
+			q.format = value
+		default:
+			field, found := vehicleFields[name]
+			if !found {
+				return nil, fmt.Errorf("unknown field '%s'", name)
+			}
+			operands, err := parseOperands(field.kind, op, value)
+			if err != nil {
+				return nil, fmt.Errorf("invalid value for %s%s: %s", name, op, err)
+			}
+			// Repeated fields with the same operator are combined, so that any of their values can match
+			key := name + op
+			if c, found := conditions[key]; found {
+				c.operands = append(c.operands, operands...)
+			} else {
+				conditions[key] = &condition{field: field, op: op, operands: operands}
+				order = append(order, key)
+			}
+		}
+	}
+	for _, key := range order {
+		q.conditions = append(q.conditions, *conditions[key])
+	}
+	if near != nil {
+		centre := *near
+		q.areas = append(q.areas, func(p geo.Point) bool { return geo.Haversine(centre, p) <= radius })
+	}
+	return q, nil
+}
+
+// parseOperands parses the comma separated values compared against a field of the given kind
+func parseOperands(kind valueKind, op string, value string) ([]operand, error) {
+	var operands []operand
+	for _, v := range strings.Split(value, ",") {
+		if v == "null" {
+			if op != "=" && op != "!=" {
+				return nil, fmt.Errorf("null can only be used with '=' and '!='")
+			}
+			operands = append(operands, operand{null: true})
+			continue
+		}
+		o := operand{value: fieldValue{valid: true}}
+		if (kind == stringKind || kind == listKind) && strings.HasSuffix(v, "*") {
+			if op != "=" && op != "!=" {
+				return nil, fmt.Errorf("prefixes can only be used with '=' and '!='")
+			}
+			o.prefix, v = true, strings.TrimSuffix(v, "*")
+		}
+		switch kind {
+		case numberKind:
+			num, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				return nil, fmt.Errorf("'%s' is not a number", v)
+			}
+			o.value.num = num
+		case timeKind:
+			t, err := parseTime(v)
+			if err != nil {
+				return nil, err
+			}
+			o.value.time = t
+		case listKind:
+			if op != "=" && op != "!=" {
+				return nil, fmt.Errorf("lists can only be used with '=' and '!='")
+			}
+			o.value.str = v
+		default:
+			o.value.str = v
+		}
+		operands = append(operands, o)
+	}
+	return operands, nil
+}
+
+// parseTime parses times in RFC3339 or the database.TimeFormat (in New York time)
+func parseTime(v string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, v); err == nil {
+		return t, nil
+	}
+	if t, err := time.ParseInLocation(database.TimeFormat, v, database.TimeLoc); err == nil {
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("'%s' is not a time, use RFC3339 or '%s'", v, database.TimeFormat)
+}
+
+// parseBoundingBox parses a bounding box in the form minLon,minLat,maxLon,maxLat
+func parseBoundingBox(value string) (func(geo.Point) bool, error) {
+	coords, err := parseFloats(value, 4)
+	if err != nil || coords[0] > coords[2] || coords[1] > coords[3] {
+		return nil, fmt.Errorf("%s must be 'minLon,minLat,maxLon,maxLat', got '%s'", bboxParam, value)
+	}
+	return func(p geo.Point) bool {
+		return p.Longitude >= coords[0] && p.Latitude >= coords[1] && p.Longitude <= coords[2] && p.Latitude <= coords[3]
+	}, nil
+}
+
+// parseFloats parses exactly `count` comma separated numbers
+func parseFloats(value string, count int) ([]float64, error) {
+	parts := strings.Split(value, ",")
+	if len(parts) != count {
+		return nil, fmt.Errorf("expected %d numbers, got %d", count, len(parts))
+	}
+	nums := make([]float64, count)
+	for i, part := range parts {
+		num, err := strconv.ParseFloat(strings.TrimSpace(part), 64)
+		if err != nil {
+			return nil, fmt.Errorf("'%s' is not a number", part)
+		}
+		nums[i] = num
+	}
+	return nums, nil
+}
+
+// matches returns true if the journey satisfies every condition and is inside every area
+func (q *vehicleQuery) matches(vj *bus.VehicleJourney) bool {
+	for _, c := range q.conditions {
+		if !c.matches(vj) {
+			return false
+		}
+	}
+	if len(q.areas) > 0 {
+		if !vj.Latitude.Valid || !vj.Longitude.Valid {
+			return false
+		}
+		p := geo.Point{Latitude: vj.Latitude.Float64, Longitude: vj.Longitude.Float64}
+		for _, inArea := range q.areas {
+			if !inArea(p) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// apply returns the page of matching journeys requested by the query,
+// and the total number of journeys that matched
+func (q *vehicleQuery) apply(journeys []bus.VehicleJourney) (page []bus.VehicleJourney, total int) {
+	matches := []bus.VehicleJourney{}
+	for i := range journeys {
+		if q.matches(&journeys[i]) {
+			matches = append(matches, journeys[i])
+		}
+	}
+	if len(q.sortKeys) > 0 {
+		sort.SliceStable(matches, func(i, j int) bool {
+			for _, key := range q.sortKeys {
+				a, b := key.field.get(&matches[i]), key.field.get(&matches[j])
+				// Null values are always last, whichever direction the field is sorted in
+				if a.valid != b.valid {
+					return a.valid
+				}
+				c := compareValues(key.field.kind, a, b)
+				if key.descending {
+					c = -c
+				}
+				if c != 0 {
+					return c < 0
+				}
+			}
+			return false
+		})
+	}
+	total = len(matches)
+	if q.offset >= total {
+		return []bus.VehicleJourney{}, total
+	}
+	matches = matches[q.offset:]
+	if q.limit > 0 && q.limit < len(matches) {
+		matches = matches[:q.limit]
+	}
+	return matches, total
+}
+
+// matches returns true if the field satisfies the condition for any of its operands
+// (or for '!=', if the field is equal to none of them)
+func (c condition) matches(vj *bus.VehicleJourney) bool {
+	v := c.field.get(vj)
+	if c.op == "!=" {
+		for _, o := range c.operands {
+			if c.equal(v, o) {
+				return false
+			}
+		}
+		return true
+	}
+	for _, o := range c.operands {
+		if c.op == "=" && c.equal(v, o) {
+			return true
+		}
+		if c.op != "=" && v.valid && c.compare(v, o) {
+			return true
+		}
+	}
+	return false
+}
+
+func (c condition) equal(v fieldValue, o operand) bool {
+	if o.null || !v.valid {
+		return o.null == !v.valid
+	}
+	switch c.field.kind {
+	case listKind:
+		for _, s := range v.list {
+			if stringsEqual(s, o) {
+				return true
+			}
+		}
+		return false
+	case stringKind:
+		return stringsEqual(v.str, o)
+	default:
+		return compareValues(c.field.kind, v, o.value) == 0
+	}
+}
+
+func stringsEqual(s string, o operand) bool {
+	if o.prefix {
+		return strings.HasPrefix(s, o.value.str)
+	}
+	return s == o.value.str
+}
+
+func (c condition) compare(v fieldValue, o operand) bool {
+	cmp := compareValues(c.field.kind, v, o.value)
+	switch c.op {
+	case "<":
+		return cmp < 0
+	case "<=":
+		return cmp <= 0
+	case ">":
+		return cmp > 0
+	default:
+		return cmp >= 0
+	}
+}
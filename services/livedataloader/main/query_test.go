@@ -0,0 +1,111 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+	"time"
+	"transport/lib/bus"
+	"transport/lib/database"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/guregu/null.v3"
+)
+
+var queryStart = time.Date(2019, 6, 3, 8, 0, 0, 0, database.TimeLoc)
+
+func queryJourney(vehicleRef string, lineRef string, stopsAway int64, lat float64, lon float64, minutes int) bus.VehicleJourney {
+	return bus.ExampleJourney(
+		bus.VehicleRefOption(vehicleRef), bus.LineRefOption(lineRef), bus.NumberOfStopsAwayOption(stopsAway),
+		bus.StopPointRefOption("MTA_30"+vehicleRef), bus.PositionOption(lat, lon),
+		bus.SituationRefOption("MTA NYCT_"+vehicleRef),
+		bus.TimestampOption(queryStart.Add(time.Duration(minutes)*time.Minute)),
+	)
+}
+
+var queryJourneys = []bus.VehicleJourney{
+	queryJourney("1", "MTA NYCT_B41", 1, 40.700, -73.990, 3),
+	queryJourney("2", "MTA NYCT_B59", 5, 40.710, -73.980, 2),
+	queryJourney("3", "MTA NYCT_M55", 3, 40.750, -73.990, 1),
+	{VehicleRef: null.StringFrom("4")},
+}
+
+func vehicleRefs(t *testing.T, rawQuery string) []string {
+	q, err := parseVehicleQuery(rawQuery)
+	assert.NoError(t, err)
+	page, _ := q.apply(queryJourneys)
+	refs := []string{}
+	for _, vj := range page {
+		refs = append(refs, vj.VehicleRef.String)
+	}
+	return refs
+}
+
+func TestVehicleQuery_Conditions(t *testing.T) {
+	assert.Equal(t, []string{"1", "2"}, vehicleRefs(t, "LineRef=MTA+NYCT_B41,MTA+NYCT_B59"))
+	assert.Equal(t, []string{"1", "3"}, vehicleRefs(t, "LineRef=MTA%20NYCT_B41&LineRef=MTA%20NYCT_M55"))
+	assert.Equal(t, []string{"2", "3", "4"}, vehicleRefs(t, "LineRef!=MTA+NYCT_B41"))
+	assert.Equal(t, []string{"1", "3"}, vehicleRefs(t, "NumberOfStopsAway<=3"))
+	assert.Equal(t, []string{"3"}, vehicleRefs(t, "NumberOfStopsAway>1&NumberOfStopsAway%3C5"))
+	assert.Equal(t, []string{"1", "2", "3"}, vehicleRefs(t, "StopPointRef=MTA_30*"))
+	assert.Equal(t, []string{"2"}, vehicleRefs(t, "SituationRef=MTA+NYCT_2"))
+	assert.Equal(t, []string{"4"}, vehicleRefs(t, "LineRef=null"))
+	assert.Equal(t, []string{"1", "2"}, vehicleRefs(t, "Timestamp>=2019-06-03+08:02:00"))
+	assert.Equal(t, []string{"3"}, vehicleRefs(t, "Timestamp<2019-06-03T12:02:00Z"))
+}
+
+func TestVehicleQuery_Areas(t *testing.T) {
+	assert.Equal(t, []string{"1", "2"}, vehicleRefs(t, "bbox=-74,40.69,-73.97,40.72"))
+	// Vehicle 2 is about 1.4km from vehicle 1
+	assert.Equal(t, []string{"1"}, vehicleRefs(t, "near=40.7,-73.99"))
+	assert.Equal(t, []string{"1", "2"}, vehicleRefs(t, "near=40.7,-73.99&radius=1500"))
+}
+
+func TestVehicleQuery_SortAndPaginate(t *testing.T) {
+	assert.Equal(t, []string{"3", "2", "1", "4"}, vehicleRefs(t, "sort=Timestamp"))
+	assert.Equal(t, []string{"2", "3", "1", "4"}, vehicleRefs(t, "sort=-NumberOfStopsAway"))
+	assert.Equal(t, []string{"2", "1"}, vehicleRefs(t, "sort=Timestamp&offset=1&limit=2"))
+	assert.Equal(t, []string{}, vehicleRefs(t, "offset=10"))
+
+	q, err := parseVehicleQuery("LineRef!=null&limit=1")
+	assert.NoError(t, err)
+	page, total := q.apply(queryJourneys)
+	assert.Len(t, page, 1)
+	assert.Equal(t, 3, total)
+}
+
+func TestVehicleQuery_Errors(t *testing.T) {
+	for _, rawQuery := range []string{
+		"Colour=red",
+		"NumberOfStopsAway<=three",
+		"SituationRef>1",
+		"Timestamp=yesterday",
+		"fields=VehicleRef,Colour",
+		"sort=-Colour",
+		"limit=-1",
+		"bbox=1,2,3",
+		"near=40.7",
+		"format=xml",
+		"format!=csv",
+		"LineRef",
+	} {
+		_, err := parseVehicleQuery(rawQuery)
+		assert.Error(t, err, rawQuery)
+	}
+}
+
+func TestLiveDataRequestHandler_Projection(t *testing.T) {
+	snapshots = newSnapshotStore()
+	snapshots.publish(vehicleMonitoring{Journeys: queryJourneys}, time.Now())
+
+	rec := httptest.NewRecorder()
+	liveDataRequestHandler(rec, httptest.NewRequest("GET", "/api/v1/vehicles?fields=VehicleRef,Latitude&LineRef=MTA+NYCT_B41", nil))
+	assert.Equal(t, "1", rec.Header().Get(totalCountHeader))
+	var response []map[string]interface{}
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &response))
+	assert.Equal(t, []map[string]interface{}{{"VehicleRef": "1", "Latitude": 40.7}}, response)
+
+	rec = httptest.NewRecorder()
+	liveDataRequestHandler(rec, httptest.NewRequest("GET", "/api/v1/vehicles?Colour=red", nil))
+	assert.Equal(t, 400, rec.Code)
+}
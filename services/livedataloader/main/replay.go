@@ -0,0 +1,246 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+	"transport/lib/bus"
+	"transport/lib/database"
+)
+
+const (
+	// replayWindow is how much recorded data is loaded from the source at a time
+	replayWindow = time.Hour
+	// replayStaleAfter is how long a vehicle stays in the replayed feed after its last report,
+	// which matches how long the MTA keeps vehicles in the live feed
+	replayStaleAfter = 2 * time.Minute
+	// dbReplaySource is the value of the -source flag that replays the vehicle_journey table
+	dbReplaySource = "db"
+	// minReplayInterval is the shortest (real) time between replayed snapshots, which limits -speed
+	minReplayInterval = 100 * time.Millisecond
+)
+
+// replayConfig holds the arguments of the replay mode
+type replayConfig struct {
+	Source string
+	Start  time.Time
+	End    time.Time
+	Speed  float64
+}
+
+// parseReplayConfig reads the arguments passed after the replay mode, e.g.
+// livedataloader replay -start "2019-06-03 07:00:00" -speed 10 -source /data/snapshots
+func parseReplayConfig(args []string) (replayConfig, error) {
+	replayFlags := flag.NewFlagSet("replay", flag.ExitOnError)
+	source := replayFlags.String("source", dbReplaySource, "'db' to replay the vehicle_journey table, or a directory of snapshot files")
+	start := replayFlags.String("start", "", fmt.Sprintf("time to start replaying from (%s, New York time)", database.TimeFormat))
+	end := replayFlags.String("end", "", "optional time to stop replaying at, in the same format as -start")
+	speed := replayFlags.Float64("speed", 1, "how many times faster than real time to replay")
+	if err := replayFlags.Parse(args); err != nil {
+		return replayConfig{}, err
+	}
+	config := replayConfig{Source: *source, Speed: *speed}
+	var err error
+	if config.Start, err = time.ParseInLocation(database.TimeFormat, *start, database.TimeLoc); err != nil {
+		return replayConfig{}, fmt.Errorf("invalid -start time '%s': %s", *start, err)
+	}
+	if *end != "" {
+		if config.End, err = time.ParseInLocation(database.TimeFormat, *end, database.TimeLoc); err != nil {
+			return replayConfig{}, fmt.Errorf("invalid -end time '%s': %s", *end, err)
+		}
+	}
+	if config.Speed <= 0 {
+		return replayConfig{}, fmt.Errorf("-speed must be positive, got %f", config.Speed)
+	}
+	if config.interval() < minReplayInterval {
+		maxSpeed := float64(fetchFrequency) / float64(minReplayInterval)
+		return replayConfig{}, fmt.Errorf("-speed must be at most %.0f, got %f", maxSpeed, config.Speed)
+	}
+	return config, nil
+}
+
+// interval returns the real time between replayed snapshots, which are fetchFrequency apart
+// in replayed time
+func (c replayConfig) interval() time.Duration {
+	return time.Duration(float64(fetchFrequency) / c.Speed)
+}
+
+// replaySource loads recorded movements, so that they can be replayed
+type replaySource interface {
+	// Load returns the movements recorded in the half-open interval [from, to), ordered by timestamp
+	Load(from time.Time, to time.Time) ([]bus.VehicleJourney, error)
+}
+
+// dbSource replays the movements stored in the vehicle_journey table
+type dbSource struct {
+	db *sql.DB
+}
+
+func (s dbSource) Load(from time.Time, to time.Time) ([]bus.VehicleJourney, error) {
+	q := fmt.Sprintf(
+		`SELECT * FROM %s WHERE TIMESTAMP >= '%s' AND TIMESTAMP < '%s' ORDER BY TIMESTAMP ASC`,
+		database.VehicleJourneyTable.Name,
+		from.In(database.TimeLoc).Format(database.TimeFormat),
+		to.In(database.TimeLoc).Format(database.TimeFormat),
+	)
+	rows, err := s.db.Query(q)
+	if err != nil {
+		return nil, fmt.Errorf("dbSource.Load: error querying movements: %s", err)
+	}
+	defer rows.Close()
+	return bus.ScanVehicleJourneyRows(rows)
+}
+
+// fileSource replays a directory of archived snapshot files, each containing a
+// JSON encoded snapshot. Every file is read up front, as a day of snapshots fits in memory.
+type fileSource struct {
+	snapshots []*snapshot
+}
+
+func newFileSource(dir string) (*fileSource, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, fmt.Errorf("newFileSource: error listing %s: %s", dir, err)
+	}
+	source := &fileSource{}
+	for _, path := range paths {
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("newFileSource: error reading %s: %s", path, err)
+		}
+		var snap snapshot
+		if err := json.Unmarshal(data, &snap); err != nil {
+			return nil, fmt.Errorf("newFileSource: error parsing %s: %s", path, err)
+		}
+		source.snapshots = append(source.snapshots, &snap)
+	}
+	if len(source.snapshots) == 0 {
+		return nil, fmt.Errorf("newFileSource: no snapshot files found in %s", dir)
+	}
+	log.Printf("Loaded %d snapshot files from %s\n", len(source.snapshots), dir)
+	return source, nil
+}
+
+func (s *fileSource) Load(from time.Time, to time.Time) ([]bus.VehicleJourney, error) {
+	var mvmts []bus.VehicleJourney
+	for _, snap := range s.snapshots {
+		for _, vj := range snap.Journeys {
+			if !vj.Timestamp.Before(from) && vj.Timestamp.Before(to) {
+				mvmts = append(mvmts, vj)
+			}
+		}
+	}
+	sort.SliceStable(mvmts, func(i, j int) bool {
+		return mvmts[i].Timestamp.Before(mvmts[j].Timestamp.Time)
+	})
+	return mvmts, nil
+}
+
+// replayer rebuilds the live feed at any point in time from recorded movements. Times passed
+// to `at` must not decrease, as the replayer only reads forwards through the source.
+type replayer struct {
+	source      replaySource
+	loadedUntil time.Time
+	pending     []bus.VehicleJourney
+	latest      map[string]bus.VehicleJourney
+}
+
+func newReplayer(source replaySource, start time.Time) *replayer {
+	// Start loading before the start time, so that vehicles which reported
+	// shortly before the start are in the first snapshot
+	return &replayer{source: source, loadedUntil: start.Add(-replayStaleAfter), latest: map[string]bus.VehicleJourney{}}
+}
+
+// at returns the latest movement of every vehicle that had reported within
+// replayStaleAfter of `t`, ordered by VehicleRef
+func (r *replayer) at(t time.Time) ([]bus.VehicleJourney, error) {
+	for !r.loadedUntil.After(t) {
+		mvmts, err := r.source.Load(r.loadedUntil, r.loadedUntil.Add(replayWindow))
+		if err != nil {
+			return nil, err
+		}
+		r.pending = append(r.pending, mvmts...)
+		r.loadedUntil = r.loadedUntil.Add(replayWindow)
+	}
+	applied := 0
+	for ; applied < len(r.pending) && !r.pending[applied].Timestamp.After(t); applied++ {
+		mvmt := r.pending[applied]
+		r.latest[mvmt.VehicleRef.String] = mvmt
+	}
+	r.pending = r.pending[applied:]
+	journeys := []bus.VehicleJourney{}
+	for vehicleRef, mvmt := range r.latest {
+		if t.Sub(mvmt.Timestamp.Time) > replayStaleAfter {
+			delete(r.latest, vehicleRef)
+			continue
+		}
+		journeys = append(journeys, mvmt)
+	}
+	sort.Slice(journeys, func(i, j int) bool {
+		return journeys[i].VehicleRef.String < journeys[j].VehicleRef.String
+	})
+	return journeys, nil
+}
+
+// replayClock maps real time onto the replayed time, which starts at `start`
+// and runs `speed` times faster than real time
+type replayClock struct {
+	start time.Time
+	began time.Time
+	speed float64
+}
+
+func (c replayClock) at(real time.Time) time.Time {
+	elapsed := float64(real.Sub(c.began)) * c.speed
+	return c.start.Add(time.Duration(elapsed))
+}
+
+// Publishes a snapshot of the replayed feed after every fetchFrequency of replayed time, in
+// the same way as the pollers do for live data. The first snapshot is published before
+// returning, so the HTTP server can start up. Replaying stops once the end time is reached.
+func initialiseReplay(source replaySource, config replayConfig, snapshots *snapshotStore, dataWritten chan *snapshot) {
+	clock := replayClock{start: config.Start, began: time.Now(), speed: config.Speed}
+	r := newReplayer(source, config.Start)
+	publishReplay(r, clock.start, snapshots, dataWritten)
+	log.Printf("Replaying from %s at %.1fx speed\n", config.Start.Format(database.TimeFormat), config.Speed)
+	ticker := time.NewTicker(config.interval())
+	go func() {
+		defer ticker.Stop()
+		for {
+			now := clock.at(<-ticker.C)
+			if !config.End.IsZero() && now.After(config.End) {
+				log.Printf("Replay reached its end time (%s), no more snapshots will be published\n", config.End.Format(database.TimeFormat))
+				return
+			}
+			publishReplay(r, now, snapshots, dataWritten)
+		}
+	}()
+}
+
+// Publishes the replayed feed at time `t`, with the same timestamps a live response would have had
+func publishReplay(r *replayer, t time.Time, snapshots *snapshotStore, dataWritten chan *snapshot) {
+	journeys, err := r.at(t)
+	if err != nil {
+		log.Printf("publishReplay: failed to load movements at %s: %s\n", t.Format(database.TimeFormat), err)
+		return
+	}
+	vm := vehicleMonitoring{Journeys: journeys, ResponseTimestamp: t, ValidUntil: t.Add(fetchFrequency)}
+	snap := snapshots.publish(vm, time.Now())
+	log.Printf("Published replay snapshot version %d at %s with %d vehicles\n", snap.Version, t.Format(database.TimeFormat), len(journeys))
+	dataWritten <- snap
+}
+
+// loadReplaySource opens the source named by the -source flag
+func loadReplaySource(name string) (replaySource, error) {
+	if strings.EqualFold(name, dbReplaySource) {
+		return dbSource{db: database.OpenDBConnection()}, nil
+	}
+	return newFileSource(name)
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+	"time"
+	"transport/lib/bus"
+	"transport/lib/database"
+	"transport/lib/testhelper"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// memorySource is a replaySource holding its movements in memory, which records each window loaded
+type memorySource struct {
+	mvmts []bus.VehicleJourney
+	loads [][2]time.Time
+}
+
+func (s *memorySource) Load(from time.Time, to time.Time) ([]bus.VehicleJourney, error) {
+	s.loads = append(s.loads, [2]time.Time{from, to})
+	var mvmts []bus.VehicleJourney
+	for _, vj := range s.mvmts {
+		if !vj.Timestamp.Before(from) && vj.Timestamp.Before(to) {
+			mvmts = append(mvmts, vj)
+		}
+	}
+	return mvmts, nil
+}
+
+var replayStart = time.Date(2019, 6, 3, 7, 0, 0, 0, database.TimeLoc)
+
+func recorded(vehicleRef string, offset time.Duration) bus.VehicleJourney {
+	return bus.ExampleJourney(bus.VehicleRefOption(vehicleRef), bus.TimestampOption(replayStart.Add(offset)))
+}
+
+func replayedRefs(journeys []bus.VehicleJourney) []string {
+	refs := []string{}
+	for _, vj := range journeys {
+		refs = append(refs, vj.VehicleRef.String)
+	}
+	return refs
+}
+
+func TestReplayer_At(t *testing.T) {
+	source := &memorySource{mvmts: []bus.VehicleJourney{
+		recorded("B", -time.Minute),
+		recorded("A", 0),
+		recorded("B", 30*time.Second),
+		recorded("A", time.Minute),
+		recorded("C", 90*time.Minute),
+	}}
+	r := newReplayer(source, replayStart)
+
+	// Vehicles which reported shortly before the start are included
+	journeys, err := r.at(replayStart)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"A", "B"}, replayedRefs(journeys))
+	assert.Equal(t, replayStart.Add(-time.Minute), journeys[1].Timestamp.Time)
+
+	// Only the latest report from each vehicle is kept
+	journeys, err = r.at(replayStart.Add(time.Minute))
+	assert.Nil(t, err)
+	assert.Equal(t, replayStart.Add(time.Minute), journeys[0].Timestamp.Time)
+	assert.Equal(t, replayStart.Add(30*time.Second), journeys[1].Timestamp.Time)
+
+	// Vehicles drop out once they stop reporting
+	journeys, err = r.at(replayStart.Add(2*time.Minute + 45*time.Second))
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"A"}, replayedRefs(journeys))
+
+	// Later windows are loaded as the replay reaches them
+	journeys, err = r.at(replayStart.Add(91 * time.Minute))
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"C"}, replayedRefs(journeys))
+	assert.Len(t, source.loads, 2)
+	assert.Equal(t, source.loads[0][1], source.loads[1][0])
+}
+
+func TestReplayClock(t *testing.T) {
+	began := time.Now()
+	clock := replayClock{start: replayStart, began: began, speed: 10}
+	assert.Equal(t, replayStart, clock.at(began))
+	assert.Equal(t, replayStart.Add(5*time.Minute), clock.at(began.Add(30*time.Second)))
+}
+
+func TestDBSource_Load(t *testing.T) {
+	columns := append(database.VehicleJourneyTable.Columns, "entry_id")
+	expectedQuery := `SELECT \* FROM vehicle_journey WHERE TIMESTAMP >= '2019-04-21 02:00:00' AND TIMESTAMP < '2019-04-21 03:00:00' ORDER BY TIMESTAMP ASC`
+	db, mock := testhelper.SetupDBMock(t, columns, bus.ExampleVJRows, expectedQuery)
+	defer db.Close()
+
+	from := time.Date(2019, 4, 21, 2, 0, 0, 0, database.TimeLoc)
+	mvmts, err := dbSource{db: db}.Load(from, from.Add(time.Hour))
+	assert.Nil(t, err)
+	assert.Equal(t, []bus.VehicleJourney{bus.ExampleVJs[0], bus.ExampleVJs[1]}, mvmts)
+	assert.Nil(t, mock.ExpectationsWereMet())
+}
+
+func TestFileSource_Load(t *testing.T) {
+	dir, err := ioutil.TempDir("", "replay")
+	assert.Nil(t, err)
+	snapshots := []snapshot{
+		{Version: 1, Journeys: []bus.VehicleJourney{recorded("A", time.Minute), recorded("B", 0)}},
+		{Version: 2, Journeys: []bus.VehicleJourney{recorded("A", 2*time.Hour)}},
+	}
+	for _, snap := range snapshots {
+		data, err := json.Marshal(snap)
+		assert.Nil(t, err)
+		assert.Nil(t, ioutil.WriteFile(filepath.Join(dir, fmt.Sprintf("%d.json", snap.Version)), data, 0644))
+	}
+
+	source, err := newFileSource(dir)
+	assert.Nil(t, err)
+	mvmts, err := source.Load(replayStart, replayStart.Add(time.Hour))
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"B", "A"}, replayedRefs(mvmts))
+
+	_, err = newFileSource(filepath.Join(dir, "missing"))
+	assert.NotNil(t, err)
+}
+
+func TestParseReplayConfig(t *testing.T) {
+	config, err := parseReplayConfig([]string{"-start", "2019-06-03 07:00:00", "-speed", "4", "-source", "/data"})
+	assert.Nil(t, err)
+	assert.Equal(t, replayConfig{Source: "/data", Start: replayStart, Speed: 4}, config)
+
+	_, err = parseReplayConfig([]string{"-start", "yesterday"})
+	assert.NotNil(t, err)
+	_, err = parseReplayConfig([]string{"-start", "2019-06-03 07:00:00", "-speed", "0"})
+	assert.NotNil(t, err)
+	// Speeds so high that snapshots would be published continuously are rejected
+	_, err = parseReplayConfig([]string{"-start", "2019-06-03 07:00:00", "-speed", "1e12"})
+	assert.EqualError(t, err, "-speed must be at most 350, got 1000000000000.000000")
+}
@@ -3,31 +3,69 @@ package main
 import (
 	"log"
 	"net/http"
+	"strconv"
+	"time"
+	"transport/lib/metrics"
 )
 
+// Header containing the number of journeys that matched a query, before pagination
+const totalCountHeader = "X-Total-Count"
+
 // Starts the HTTP server which serves the live data
 func initialiseServer() {
 	port := ":8090"
 	log.Printf("Starting HTTP server at http://localhost:%s", port)
 
 	// Attach request handlers
-	http.HandleFunc("/api/v1/vehicles", liveDataRequestHandler)
-	http.HandleFunc("/health", healthEndpoint)
-	http.HandleFunc("/", healthEndpoint)
+	handle := func(pattern string, name string, h http.HandlerFunc) {
+		http.Handle(pattern, metrics.InstrumentHandlerFunc(name, h))
+	}
+	handle("/api/v1/vehicles", "vehicles", liveDataRequestHandler)
+	handle("/api/v1/vehicles/stream", "vehicles_stream", sseRequestHandler)
+	handle("/api/v1/vehicles/ws", "vehicles_ws", webSocketRequestHandler)
+	handle("/api/v1/headways", "headways", headwayRequestHandler)
+	handle("/api/v1/alerts", "alerts", alertRequestHandler)
+	http.Handle("/healthz", liveness)
+	http.Handle("/readyz", readiness)
+	// Older clients check /health, which is kept as an alias of /readyz
+	http.Handle("/health", readiness)
+	http.Handle("/metrics", metrics.Handler())
+	http.Handle("/", liveness)
 
 	// Start HTTP server
 	log.Fatal(http.ListenAndServe(port, nil))
 }
 
 func liveDataRequestHandler(w http.ResponseWriter, req *http.Request) {
-	// Construct response based on currently cached data (declared in main.go)
+	// Construct response based on the current snapshot (declared in main.go)
 	// and the query params from the request
-	response := createVehicleDataResponse(vehicleData, req.URL.Query())
+	log.Println("Creating response...")
+	query, err := parseVehicleQuery(req.URL.RawQuery)
+	if err != nil {
+		log.Printf("liveDataRequestHandler: invalid query: %s\n", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	snap := snapshots.load()
+	if writeUnavailable(w, snap) {
+		return
+	}
+	journeys, total := query.apply(snap.Journeys)
+	response, contentType, err := encodeVehicleData(journeys, query.fields, query.format, req.Header.Get("Accept"), snap.ResponseTimestamp)
+	if err != nil {
+		log.Printf("liveDataRequestHandler: %s\n", err)
+		http.Error(w, "Failed to create response", http.StatusInternalServerError)
+		return
+	}
 
 	log.Printf("Response created succesfully, writing to output...")
 
 	// Write response
-	_, err := w.Write([]byte(response))
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set(totalCountHeader, strconv.Itoa(total))
+	snapshots.writeHeaders(w, snap, time.Now())
+	w.Header().Set("Vary", "Accept")
+	_, err = w.Write(response)
 	if err != nil {
 		log.Printf("error occurred whilst writing response in liveDataRequestHandler: %s\n", err)
 	}
@@ -35,9 +73,14 @@ func liveDataRequestHandler(w http.ResponseWriter, req *http.Request) {
 	log.Printf("Response completed succesfully!")
 }
 
-func healthEndpoint(w http.ResponseWriter, _ *http.Request) {
-	_, err := w.Write([]byte("Healthy!"))
-	if err != nil {
-		log.Printf("error occurred in healthEndpoint: %s\n", err)
+// writeUnavailable responds with 503 Service Unavailable if no data has been fetched (or
+// restored) yet, so clients can tell a failed fetch apart from no vehicles running.
+// Returns true if the response was written.
+func writeUnavailable(w http.ResponseWriter, snap *snapshot) bool {
+	if snap.Version > 0 {
+		return false
 	}
+	w.Header().Set("Retry-After", strconv.Itoa(int(fetchFrequency.Seconds())))
+	http.Error(w, "No vehicle data has been fetched yet", http.StatusServiceUnavailable)
+	return true
 }
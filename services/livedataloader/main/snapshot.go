@@ -0,0 +1,222 @@
+package main
+
+import (
+	"net/http"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+	"transport/lib/bus"
+)
+
+// Headers that describe the snapshot a response was created from
+const (
+	versionHeader           = "X-Snapshot-Version"
+	ageHeader               = "X-Snapshot-Age"
+	fetchedAtHeader         = "X-Snapshot-Fetched-At"
+	responseTimestampHeader = "X-Snapshot-Response-Timestamp"
+	validUntilHeader        = "X-Snapshot-Valid-Until"
+	staleHeader             = "X-Snapshot-Stale"
+	failedFetchesHeader     = "X-Snapshot-Failed-Fetches"
+)
+
+// staleAfter is the age at which a snapshot is stale, even if no fetch has failed,
+// e.g. if fetches are hanging rather than failing
+const staleAfter = 3 * fetchFrequency
+
+// snapshot is the vehicle data from a single fetch. Snapshots are never modified after they
+// are published, so they can be read by any number of goroutines without locking.
+type snapshot struct {
+	// Increases by one every time a snapshot is published, starting from 1
+	Version uint64
+	// When the data was fetched from the MTA
+	FetchedAt time.Time
+	// When the MTA created the response, and how long it said the data would remain valid
+	ResponseTimestamp time.Time
+	ValidUntil        time.Time
+	Journeys          []bus.VehicleJourney
+	Alerts            []bus.ServiceAlert
+	// Whether the snapshot was restored from disk at startup, rather than fetched
+	Restored bool `json:"-"`
+}
+
+// age returns how long ago the snapshot's data was fetched
+func (s *snapshot) age(now time.Time) time.Duration {
+	if s.FetchedAt.IsZero() {
+		return 0
+	}
+	return now.Sub(s.FetchedAt)
+}
+
+// writeHeaders adds the snapshot's version, age and validity to the response headers
+func (s *snapshot) writeHeaders(w http.ResponseWriter, now time.Time) {
+	w.Header().Set(versionHeader, strconv.FormatUint(s.Version, 10))
+	w.Header().Set(ageHeader, strconv.Itoa(int(s.age(now).Seconds())))
+	for header, t := range map[string]time.Time{
+		fetchedAtHeader:         s.FetchedAt,
+		responseTimestampHeader: s.ResponseTimestamp,
+		validUntilHeader:        s.ValidUntil,
+	} {
+		if !t.IsZero() {
+			w.Header().Set(header, t.Format(time.RFC3339))
+		}
+	}
+}
+
+// vehicleMonitoring is the contents of a single vehicle monitoring response from the MTA
+type vehicleMonitoring struct {
+	Journeys          []bus.VehicleJourney
+	Alerts            []bus.ServiceAlert
+	ResponseTimestamp time.Time
+	ValidUntil        time.Time
+}
+
+// historySize is the number of recent snapshots kept, so that streaming
+// clients can resume from the version they last received
+const historySize = 20
+
+// snapshotStore holds the latest snapshot. Publishing swaps the whole snapshot atomically,
+// so readers always see every field of a single snapshot, never a mix of two.
+type snapshotStore struct {
+	current atomic.Value
+	// Serialises publishing, so that versions are assigned in order,
+	// and guards the fields below
+	mutex       sync.Mutex
+	version     uint64
+	history     []*snapshot
+	subscribers map[chan *snapshot]bool
+	// The number of fetches in a row that have failed for each feed, ignoring feeds
+	// whose last fetch succeeded
+	failures map[string]int
+}
+
+// newSnapshotStore creates a store holding an empty snapshot with version 0
+func newSnapshotStore() *snapshotStore {
+	ss := &snapshotStore{subscribers: map[chan *snapshot]bool{}, failures: map[string]int{}}
+	ss.current.Store(&snapshot{})
+	return ss
+}
+
+// publish makes the fetched data the current snapshot, and returns it
+func (ss *snapshotStore) publish(vm vehicleMonitoring, fetchedAt time.Time) *snapshot {
+	ss.mutex.Lock()
+	defer ss.mutex.Unlock()
+	ss.version++
+	snap := &snapshot{
+		Version:           ss.version,
+		FetchedAt:         fetchedAt,
+		ResponseTimestamp: vm.ResponseTimestamp,
+		ValidUntil:        vm.ValidUntil,
+		Journeys:          vm.Journeys,
+		Alerts:            vm.Alerts,
+	}
+	ss.current.Store(snap)
+	ss.history = append(ss.history, snap)
+	if len(ss.history) > historySize {
+		ss.history = ss.history[1:]
+	}
+	for ch := range ss.subscribers {
+		// Subscribers only need the latest snapshot, so replace any they haven't received yet
+		select {
+		case <-ch:
+		default:
+		}
+		ch <- snap
+	}
+	return snap
+}
+
+// restore makes a snapshot saved by a previous run the current snapshot. Versions carry on
+// from the restored snapshot's version, so they keep increasing across restarts.
+func (ss *snapshotStore) restore(saved *snapshot) {
+	ss.mutex.Lock()
+	defer ss.mutex.Unlock()
+	snap := *saved
+	snap.Restored = true
+	ss.version = snap.Version
+	ss.current.Store(&snap)
+	ss.history = []*snapshot{&snap}
+}
+
+// fetchFailed records that fetching new data for `feed` failed, so the current snapshot
+// is stale until that feed is fetched successfully
+func (ss *snapshotStore) fetchFailed(feed string) {
+	ss.mutex.Lock()
+	defer ss.mutex.Unlock()
+	ss.failures[feed]++
+}
+
+// fetchSucceeded records that `feed` was fetched successfully, clearing its failures.
+// Failures of the other feeds still mark the snapshot as stale.
+func (ss *snapshotStore) fetchSucceeded(feed string) {
+	ss.mutex.Lock()
+	defer ss.mutex.Unlock()
+	delete(ss.failures, feed)
+}
+
+// failedFetches returns the number of failed fetches in a row, summed over every feed
+func (ss *snapshotStore) failedFetches() int {
+	ss.mutex.Lock()
+	defer ss.mutex.Unlock()
+	total := 0
+	for _, failures := range ss.failures {
+		total += failures
+	}
+	return total
+}
+
+// stale returns true if `snap` is out of date: either it was restored from disk, the last
+// fetch of any feed failed, or it is older than staleAfter
+func (ss *snapshotStore) stale(snap *snapshot, now time.Time) bool {
+	return snap.Restored || ss.failedFetches() > 0 || snap.age(now) > staleAfter
+}
+
+// writeHeaders adds the snapshot's headers to the response, along with whether it is stale.
+// Stale responses also get a standard HTTP Warning header.
+func (ss *snapshotStore) writeHeaders(w http.ResponseWriter, snap *snapshot, now time.Time) {
+	snap.writeHeaders(w, now)
+	stale := ss.stale(snap, now)
+	w.Header().Set(staleHeader, strconv.FormatBool(stale))
+	w.Header().Set(failedFetchesHeader, strconv.Itoa(ss.failedFetches()))
+	if stale {
+		w.Header().Set("Warning", `110 - "Response is Stale"`)
+	}
+}
+
+// subscribe returns a channel that receives each snapshot as it is published. Slow subscribers
+// skip snapshots rather than blocking publishing. Call `cancel` to stop receiving snapshots.
+func (ss *snapshotStore) subscribe() (snapshots <-chan *snapshot, cancel func()) {
+	ch := make(chan *snapshot, 1)
+	ss.mutex.Lock()
+	defer ss.mutex.Unlock()
+	ss.subscribers[ch] = true
+	return ch, func() {
+		ss.mutex.Lock()
+		defer ss.mutex.Unlock()
+		delete(ss.subscribers, ch)
+	}
+}
+
+// subscriberCount returns the number of subscribers currently receiving snapshots
+func (ss *snapshotStore) subscriberCount() int {
+	ss.mutex.Lock()
+	defer ss.mutex.Unlock()
+	return len(ss.subscribers)
+}
+
+// find returns the snapshot with the given version, if it is still in the history
+func (ss *snapshotStore) find(version uint64) (*snapshot, bool) {
+	ss.mutex.Lock()
+	defer ss.mutex.Unlock()
+	for _, snap := range ss.history {
+		if snap.Version == version {
+			return snap, true
+		}
+	}
+	return nil, false
+}
+
+// load returns the current snapshot
+func (ss *snapshotStore) load() *snapshot {
+	return ss.current.Load().(*snapshot)
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+	"transport/lib/bus"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSnapshotStore_VersionsAreConsistent(t *testing.T) {
+	ss := newSnapshotStore()
+	assert.Equal(t, uint64(0), ss.load().Version)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				// Every snapshot contains as many journeys as its version, so a reader
+				// seeing a mix of two snapshots would be caught below
+				snap := ss.load()
+				ss.publish(vehicleMonitoring{Journeys: make([]bus.VehicleJourney, snap.Version+1)}, time.Now())
+			}
+		}()
+	}
+	var previous uint64
+	for i := 0; i < 200; i++ {
+		snap := ss.load()
+		assert.True(t, snap.Version >= previous)
+		previous = snap.Version
+	}
+	wg.Wait()
+	assert.Equal(t, uint64(400), ss.load().Version)
+}
+
+func TestSnapshot_WriteHeaders(t *testing.T) {
+	fetchedAt := time.Date(2019, 6, 3, 8, 0, 0, 0, time.UTC)
+	ss := newSnapshotStore()
+	snap := ss.publish(vehicleMonitoring{ResponseTimestamp: fetchedAt.Add(-time.Second), ValidUntil: fetchedAt.Add(time.Minute)}, fetchedAt)
+
+	rec := httptest.NewRecorder()
+	snap.writeHeaders(rec, fetchedAt.Add(20*time.Second))
+	assert.Equal(t, "1", rec.Header().Get(versionHeader))
+	assert.Equal(t, "20", rec.Header().Get(ageHeader))
+	assert.Equal(t, "2019-06-03T08:00:00Z", rec.Header().Get(fetchedAtHeader))
+	assert.Equal(t, "2019-06-03T08:01:00Z", rec.Header().Get(validUntilHeader))
+}
+
+func TestSnapshotStore_Staleness(t *testing.T) {
+	fetchedAt := time.Date(2019, 6, 3, 8, 0, 0, 0, time.UTC)
+	ss := newSnapshotStore()
+	snap := ss.publish(vehicleMonitoring{}, fetchedAt)
+	assert.False(t, ss.stale(snap, fetchedAt.Add(fetchFrequency)))
+	assert.True(t, ss.stale(snap, fetchedAt.Add(staleAfter+time.Second)))
+
+	// Failed fetches leave the previous snapshot in place, but mark it as stale
+	ss.fetchFailed("all routes")
+	ss.fetchFailed("all routes")
+	rec := httptest.NewRecorder()
+	ss.writeHeaders(rec, ss.load(), fetchedAt.Add(fetchFrequency))
+	assert.Equal(t, "1", rec.Header().Get(versionHeader))
+	assert.Equal(t, "true", rec.Header().Get(staleHeader))
+	assert.Equal(t, "2", rec.Header().Get(failedFetchesHeader))
+	assert.NotEmpty(t, rec.Header().Get("Warning"))
+
+	// Until the next successful fetch
+	ss.fetchSucceeded("all routes")
+	snap = ss.publish(vehicleMonitoring{}, fetchedAt.Add(2*fetchFrequency))
+	rec = httptest.NewRecorder()
+	ss.writeHeaders(rec, snap, fetchedAt.Add(2*fetchFrequency))
+	assert.Equal(t, "false", rec.Header().Get(staleHeader))
+	assert.Equal(t, "0", rec.Header().Get(failedFetchesHeader))
+	assert.Empty(t, rec.Header().Get("Warning"))
+
+	// Staleness is tracked per feed, so a successful fetch of one feed doesn't hide failures of another
+	ss.fetchFailed("MTA NYCT_B41")
+	ss.fetchSucceeded("all routes")
+	snap = ss.publish(vehicleMonitoring{}, fetchedAt.Add(3*fetchFrequency))
+	assert.True(t, ss.stale(snap, fetchedAt.Add(3*fetchFrequency)))
+	ss.fetchSucceeded("MTA NYCT_B41")
+	assert.False(t, ss.stale(snap, fetchedAt.Add(3*fetchFrequency)))
+}
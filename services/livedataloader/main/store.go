@@ -2,55 +2,99 @@ package main
 
 import (
 	"database/sql"
+	"fmt"
 	"log"
+	"time"
 	"transport/lib/bus"
 	"transport/lib/database"
 
 	"github.com/lib/pq"
 )
 
-// Parses and stores data when notified that data has been received
-func store(liveVehicleData *[]bus.VehicleJourney, dataIncoming chan bool) {
-	db := database.OpenDBConnection()
+// Stores each snapshot in the DB when notified that it has been published
+func store(db *sql.DB, dataIncoming chan *snapshot) {
+	alerts := loadStoredAlerts(db, time.Now())
 	for {
-		<-dataIncoming
-		log.Printf("Vehicle entries received: %d\n", len(*liveVehicleData))
-		insert(db, *liveVehicleData)
-		log.Println("Finished sending vehicle entries to DB")
+		snap := <-dataIncoming
+		log.Printf("Vehicle entries received: %d (snapshot version %d)\n", len(snap.Journeys), snap.Version)
+		err := insert(db, snap.Journeys)
+		writer.stored(snap, err)
+		if err != nil {
+			log.Printf("Failed to store snapshot version %d: %s\n", snap.Version, err)
+		} else {
+			log.Println("Finished sending vehicle entries to DB")
+		}
+		storeHeadways(db, snap.Journeys)
+		storeAlerts(db, alerts, snap.Alerts, time.Now())
 	}
 }
 
 // Batch inserts all vehicle entries in `vehicleActivity` into the DB
-func insert(db *sql.DB, vehicleJourneys []bus.VehicleJourney) {
+func insert(db *sql.DB, vehicleJourneys []bus.VehicleJourney) error {
 	// Start transaction
 	transaction := database.CreateTransaction(db)
-	stmt := createStatement(transaction)
+	stmt, err := createStatement(transaction, database.VehicleJourneyTable)
+	if err != nil {
+		transaction.Rollback()
+		return err
+	}
 	// Add all vehicle journeys to the insertion statement
-	addEntriesToStatement(vehicleJourneys, stmt)
-	database.CommitTransaction(stmt, transaction)
+	rows := addEntriesToStatement(vehicleJourneys, stmt)
+	err = database.FlushAndCommit(stmt, transaction)
+	database.RecordCommit(database.VehicleJourneyTable, rows, err)
+	return err
 }
 
-// Creates an SQL statement for batch insertion into the `arrivals` table
-func createStatement(txn *sql.Tx) *sql.Stmt {
-	table := database.VehicleJourneyTable
+// Creates an SQL statement for batch insertion into `table`
+func createStatement(txn *sql.Tx, table database.DBTable) (*sql.Stmt, error) {
 	// Prepare insertion statement
 	stmt, err := txn.Prepare(pq.CopyIn(
 		table.Name,
 		table.Columns...,
 	))
 	if err != nil {
-		log.Fatal(err)
+		return nil, fmt.Errorf("error whilst preparing insertion statement for %s: %s", table.Name, err)
+	}
+	return stmt, nil
+}
+
+// Batch inserts `entries` into `table` in a single transaction. Unlike database.CopyIntoDB,
+// errors are returned rather than exiting, so that a bad row can't stop the server.
+func copyIntoTable(db *sql.DB, table database.DBTable, columnExtractor func(interface{}) []interface{}, entries []interface{}) error {
+	transaction := database.CreateTransaction(db)
+	stmt, err := createStatement(transaction, table)
+	if err != nil {
+		transaction.Rollback()
+		return err
+	}
+	rows := 0
+	for _, entry := range entries {
+		_, err := stmt.Exec(columnExtractor(entry)...)
+		if err != nil {
+			log.Printf("error occurred whilst executing insert statement for %s:\n%v\n", table.Name, err)
+			database.RowsRejected.Inc(table.Name)
+		} else {
+			rows++
+		}
 	}
-	return stmt
+	err = database.FlushAndCommit(stmt, transaction)
+	database.RecordCommit(table, rows, err)
+	return err
 }
 
-// Adds an insertion statement for each vehicle activity entry in `vehicleActivity` into `stmt`
-func addEntriesToStatement(vehicleJourneys []bus.VehicleJourney, stmt *sql.Stmt) {
+// Adds an insertion statement for each vehicle activity entry in `vehicleActivity` into `stmt`,
+// returning the number of entries that were added
+func addEntriesToStatement(vehicleJourneys []bus.VehicleJourney, stmt *sql.Stmt) int {
+	rows := 0
 	for _, j := range vehicleJourneys {
 		// Construct a DB row from each vehicle activity entry and insert the row into the DB
 		_, err := stmt.Exec(j.Value()...)
 		if err != nil {
 			log.Printf("error occurred whilst executing insert statement for %v:\n%v\n", j, err)
+			database.RowsRejected.Inc(database.VehicleJourneyTable.Name)
+		} else {
+			rows++
 		}
 	}
+	return rows
 }
@@ -0,0 +1,253 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+	"transport/lib/bus"
+
+	"github.com/gorilla/websocket"
+)
+
+// Query parameter and SSE header used to resume a stream from the last version received
+const (
+	resumeParam       = "resume"
+	lastEventIDHeader = "Last-Event-ID"
+)
+
+// keepAliveInterval is how often an idle stream sends something, so that proxies don't close it
+const keepAliveInterval = 15 * time.Second
+
+// vehicleUpdate is the message sent to streaming clients each time a snapshot is published
+type vehicleUpdate struct {
+	// The snapshot version, which clients can use to resume the stream after reconnecting
+	Version uint64
+	// True if the client should discard the vehicles it has before applying this update,
+	// which happens when a stream starts, or can't be resumed from the client's version
+	Reset   bool
+	Added   []bus.VehicleJourney
+	Changed []bus.VehicleJourney
+	// The VehicleRefs of vehicles that no longer match the query (or are no longer running)
+	Removed []string
+}
+
+// empty returns true if the update doesn't change any vehicles
+func (u vehicleUpdate) empty() bool {
+	return !u.Reset && len(u.Added) == 0 && len(u.Changed) == 0 && len(u.Removed) == 0
+}
+
+// vehicleStream tracks the vehicles that a streaming client has been sent,
+// so that it can be sent only the differences in each new snapshot
+type vehicleStream struct {
+	query   *vehicleQuery
+	version uint64
+	view    map[string]bus.VehicleJourney
+}
+
+// newVehicleStream parses a stream's query, which supports the same filters as /api/v1/vehicles,
+// but not projection, sorting or pagination, as updates only contain the vehicles that changed
+func newVehicleStream(rawQuery string) (*vehicleStream, error) {
+	query, err := parseVehicleQuery(rawQuery)
+	if err != nil {
+		return nil, err
+	}
+	if len(query.fields) > 0 || len(query.sortKeys) > 0 || query.limit > 0 || query.offset > 0 {
+		return nil, fmt.Errorf("streams don't support %s, %s, %s or %s", fieldsParam, sortParam, limitParam, offsetParam)
+	}
+	return &vehicleStream{query: query, view: map[string]bus.VehicleJourney{}}, nil
+}
+
+// start returns the first update to send to the client. If the client's resume token is a
+// version that is still in the store's history, only the changes since then are sent.
+// Otherwise, the update resets the client's state to the current snapshot.
+func (vs *vehicleStream) start(snapshots *snapshotStore, resumeToken string) vehicleUpdate {
+	current := snapshots.load()
+	if version, err := strconv.ParseUint(resumeToken, 10, 64); err == nil {
+		if previous, found := snapshots.find(version); found {
+			vs.view = vs.filter(previous)
+			vs.version = previous.Version
+			return vs.update(current)
+		}
+	}
+	update := vs.update(current)
+	update.Reset = true
+	return update
+}
+
+// update returns the differences between the vehicles the client has and `snap`
+func (vs *vehicleStream) update(snap *snapshot) vehicleUpdate {
+	update := vehicleUpdate{Version: snap.Version, Added: []bus.VehicleJourney{}, Changed: []bus.VehicleJourney{}, Removed: []string{}}
+	current := vs.filter(snap)
+	for _, vj := range snap.Journeys {
+		vehicleRef := vj.VehicleRef.String
+		matched, inCurrent := current[vehicleRef]
+		if !inCurrent {
+			continue
+		}
+		previous, inView := vs.view[vehicleRef]
+		if !inView {
+			update.Added = append(update.Added, matched)
+		} else if !reflect.DeepEqual(previous, matched) {
+			update.Changed = append(update.Changed, matched)
+		}
+	}
+	for vehicleRef := range vs.view {
+		if _, found := current[vehicleRef]; !found {
+			update.Removed = append(update.Removed, vehicleRef)
+		}
+	}
+	vs.view, vs.version = current, snap.Version
+	return update
+}
+
+// filter returns the journeys in the snapshot that match the stream's query, keyed by VehicleRef
+func (vs *vehicleStream) filter(snap *snapshot) map[string]bus.VehicleJourney {
+	matches := map[string]bus.VehicleJourney{}
+	for i := range snap.Journeys {
+		if vs.query.matches(&snap.Journeys[i]) {
+			matches[snap.Journeys[i].VehicleRef.String] = snap.Journeys[i]
+		}
+	}
+	return matches
+}
+
+// splitResumeToken removes the resume parameter from a raw query string, returning the rest of
+// the query and the token. Resuming SSE streams usually use the Last-Event-ID header instead.
+func splitResumeToken(rawQuery string) (string, string) {
+	var terms []string
+	token := ""
+	for _, term := range strings.Split(rawQuery, "&") {
+		if strings.HasPrefix(term, resumeParam+"=") {
+			token = strings.TrimPrefix(term, resumeParam+"=")
+			continue
+		}
+		terms = append(terms, term)
+	}
+	return strings.Join(terms, "&"), token
+}
+
+// runStream sends the first update and then an update after every snapshot that changes the
+// client's vehicles, until `send` fails or the client disconnects (closing `done`).
+// `keepAlive` is called when there haven't been any updates for the keepAliveInterval.
+func runStream(vs *vehicleStream, resumeToken string, done <-chan struct{}, send func(vehicleUpdate) error, keepAlive func() error) {
+	// Subscribe before sending the first update, so that no snapshots are missed in between
+	published, cancel := snapshots.subscribe()
+	defer cancel()
+	if err := send(vs.start(snapshots, resumeToken)); err != nil {
+		log.Printf("runStream: error sending first update: %s\n", err)
+		return
+	}
+	ticker := time.NewTicker(keepAliveInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-done:
+			return
+		case snap := <-published:
+			if snap.Version <= vs.version {
+				continue
+			}
+			update := vs.update(snap)
+			if update.empty() {
+				continue
+			}
+			if err := send(update); err != nil {
+				log.Printf("runStream: error sending update: %s\n", err)
+				return
+			}
+		case <-ticker.C:
+			if err := keepAlive(); err != nil {
+				log.Printf("runStream: error sending keep-alive: %s\n", err)
+				return
+			}
+		}
+	}
+}
+
+// Streams vehicle updates as Server-Sent Events. Each event's ID is the snapshot version, so
+// browsers' EventSource automatically resumes from the last update they received.
+func sseRequestHandler(w http.ResponseWriter, req *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "Streaming is not supported", http.StatusInternalServerError)
+		return
+	}
+	rawQuery, resumeToken := splitResumeToken(req.URL.RawQuery)
+	if header := req.Header.Get(lastEventIDHeader); header != "" {
+		resumeToken = header
+	}
+	vs, err := newVehicleStream(rawQuery)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	send := func(update vehicleUpdate) error {
+		data, err := json.Marshal(update)
+		if err != nil {
+			return err
+		}
+		if _, err := fmt.Fprintf(w, "id: %d\nevent: update\ndata: %s\n\n", update.Version, data); err != nil {
+			return err
+		}
+		flusher.Flush()
+		return nil
+	}
+	keepAlive := func() error {
+		if _, err := fmt.Fprint(w, ": keep-alive\n\n"); err != nil {
+			return err
+		}
+		flusher.Flush()
+		return nil
+	}
+	runStream(vs, resumeToken, req.Context().Done(), send, keepAlive)
+}
+
+var upgrader = websocket.Upgrader{
+	// Streams are read-only and public, like the rest of the API
+	CheckOrigin: func(*http.Request) bool { return true },
+}
+
+// Streams vehicle updates over a WebSocket, as one JSON message per update
+func webSocketRequestHandler(w http.ResponseWriter, req *http.Request) {
+	rawQuery, resumeToken := splitResumeToken(req.URL.RawQuery)
+	vs, err := newVehicleStream(rawQuery)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	conn, err := upgrader.Upgrade(w, req, nil)
+	if err != nil {
+		log.Printf("webSocketRequestHandler: failed to upgrade connection: %s\n", err)
+		return
+	}
+	defer conn.Close()
+
+	// Clients don't send anything, but reading is needed to notice when they disconnect
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+	send := func(update vehicleUpdate) error {
+		return conn.WriteJSON(update)
+	}
+	keepAlive := func() error {
+		return conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(keepAliveInterval))
+	}
+	runStream(vs, resumeToken, done, send, keepAlive)
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+	"transport/lib/bus"
+
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+)
+
+func streamJourney(vehicleRef string, lineRef string, stopsAway int64) bus.VehicleJourney {
+	return bus.ExampleJourney(bus.VehicleRefOption(vehicleRef), bus.LineRefOption(lineRef), bus.NumberOfStopsAwayOption(stopsAway))
+}
+
+func TestVehicleStream_Update(t *testing.T) {
+	ss := newSnapshotStore()
+	first := ss.publish(vehicleMonitoring{Journeys: []bus.VehicleJourney{
+		streamJourney("1", "MTA NYCT_B41", 3),
+		streamJourney("2", "MTA NYCT_B41", 5),
+		streamJourney("3", "MTA NYCT_B59", 1),
+	}}, time.Now())
+	second := ss.publish(vehicleMonitoring{Journeys: []bus.VehicleJourney{
+		streamJourney("1", "MTA NYCT_B41", 2),
+		streamJourney("3", "MTA NYCT_B59", 1),
+		streamJourney("4", "MTA NYCT_B41", 9),
+	}}, time.Now())
+
+	vs, err := newVehicleStream("LineRef=MTA+NYCT_B41")
+	assert.NoError(t, err)
+	update := vs.update(first)
+	assert.Len(t, update.Added, 2)
+	update = vs.update(second)
+	assert.Equal(t, uint64(2), update.Version)
+	assert.Equal(t, []bus.VehicleJourney{streamJourney("4", "MTA NYCT_B41", 9)}, update.Added)
+	assert.Equal(t, []bus.VehicleJourney{streamJourney("1", "MTA NYCT_B41", 2)}, update.Changed)
+	assert.Equal(t, []string{"2"}, update.Removed)
+	assert.True(t, vs.update(second).empty())
+
+	// Resuming from a version in the history only sends the differences
+	vs, _ = newVehicleStream("LineRef=MTA+NYCT_B41")
+	update = vs.start(ss, "1")
+	assert.False(t, update.Reset)
+	assert.Len(t, update.Added, 1)
+	// Unknown versions reset the client's state
+	vs, _ = newVehicleStream("LineRef=MTA+NYCT_B41")
+	update = vs.start(ss, "100")
+	assert.True(t, update.Reset)
+	assert.Len(t, update.Added, 2)
+
+	_, err = newVehicleStream("sort=Timestamp")
+	assert.Error(t, err)
+}
+
+// readEvent reads a single SSE event, skipping keep-alive comments
+func readEvent(t *testing.T, r *bufio.Reader) (string, vehicleUpdate) {
+	var id string
+	var update vehicleUpdate
+	for {
+		line, err := r.ReadString('\n')
+		assert.NoError(t, err)
+		line = strings.TrimSuffix(line, "\n")
+		switch {
+		case line == "" && id != "":
+			return id, update
+		case strings.HasPrefix(line, "id: "):
+			id = strings.TrimPrefix(line, "id: ")
+		case strings.HasPrefix(line, "data: "):
+			assert.NoError(t, json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &update))
+		}
+	}
+}
+
+func TestSSERequestHandler(t *testing.T) {
+	snapshots = newSnapshotStore()
+	snapshots.publish(vehicleMonitoring{Journeys: []bus.VehicleJourney{streamJourney("1", "MTA NYCT_B41", 3)}}, time.Now())
+	server := httptest.NewServer(http.HandlerFunc(sseRequestHandler))
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "?LineRef=MTA+NYCT_B41")
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+	r := bufio.NewReader(resp.Body)
+	id, update := readEvent(t, r)
+	assert.Equal(t, "1", id)
+	assert.True(t, update.Reset)
+	assert.Len(t, update.Added, 1)
+
+	snapshots.publish(vehicleMonitoring{Journeys: []bus.VehicleJourney{streamJourney("2", "MTA NYCT_B41", 1)}}, time.Now())
+	id, update = readEvent(t, r)
+	assert.Equal(t, "2", id)
+	assert.False(t, update.Reset)
+	assert.Equal(t, []string{"1"}, update.Removed)
+	assert.Len(t, update.Added, 1)
+}
+
+func TestWebSocketRequestHandler(t *testing.T) {
+	snapshots = newSnapshotStore()
+	snapshots.publish(vehicleMonitoring{Journeys: []bus.VehicleJourney{streamJourney("1", "MTA NYCT_B41", 3)}}, time.Now())
+	server := httptest.NewServer(http.HandlerFunc(webSocketRequestHandler))
+	defer server.Close()
+	wsURL := "ws" + strings.TrimPrefix(server.URL, "http")
+
+	conn, _, err := websocket.DefaultDialer.Dial(wsURL+"?NumberOfStopsAway>=2", nil)
+	assert.NoError(t, err)
+	var update vehicleUpdate
+	assert.NoError(t, conn.ReadJSON(&update))
+	assert.True(t, update.Reset)
+	assert.Len(t, update.Added, 1)
+	conn.Close()
+
+	// Reconnecting after missing an update only sends the changes since the last version received
+	snapshots.publish(vehicleMonitoring{Journeys: []bus.VehicleJourney{streamJourney("1", "MTA NYCT_B41", 2)}}, time.Now())
+	conn, _, err = websocket.DefaultDialer.Dial(wsURL+"?NumberOfStopsAway>=2&resume=1", nil)
+	assert.NoError(t, err)
+	defer conn.Close()
+	assert.NoError(t, conn.ReadJSON(&update))
+	assert.False(t, update.Reset)
+	assert.Equal(t, uint64(2), update.Version)
+	assert.Len(t, update.Changed, 1)
+}
@@ -9,22 +9,25 @@ import (
 	"transport/lib/bustime"
 	"transport/lib/mapping"
 	"transport/services/labeller/stopdistance"
-
-	"googlemaps.github.io/maps"
 )
 
 type distanceResponse struct {
-	res []bus.StopDistance
-	err error
+	route mapping.RouteKey
+	res   []bus.StopDistance
+	err   error
 }
 
-func GetDistances(mc *maps.Client, stopDetails map[string]map[int][]bustime.BusStop, existingSDs map[stopdistance.Key]float64) []bus.StopDistance {
-	var distances []bus.StopDistance
+// GetDistances calculates the distance between every ordered pair of stops along each route
+// and direction, skipping any pairs in existingSDs. Along with the distances, it returns the
+// routes (and directions) for which every distance was successfully calculated.
+// If `onRoute` isn't nil, it is called with the distances of each route as soon as they have
+// all been calculated, so that progress can be saved before the remaining routes finish.
+func GetDistances(dp mapping.DistanceProvider, stopDetails map[string]map[int][]bustime.BusStop, existingSDs map[stopdistance.Key]float64, onRoute func(mapping.RouteKey, []bus.StopDistance)) (distances []bus.StopDistance, completed []mapping.RouteKey) {
 	mux := &sync.Mutex{}
 	fetched, count := make(chan distanceResponse), 0
 	for routeID, directionIDs := range stopDetails {
 		for directionID, stopsForDirectionID := range directionIDs {
-			go getDistancesAlongRoute(mc, routeID, directionID, stopsForDirectionID, fetched, existingSDs)
+			go getDistancesAlongRoute(dp, routeID, directionID, stopsForDirectionID, fetched, existingSDs)
 			count++
 		}
 	}
@@ -35,19 +38,25 @@ func GetDistances(mc *maps.Client, stopDetails map[string]map[int][]bustime.BusS
 		} else {
 			mux.Lock()
 			distances = append(distances, distResp.res...)
+			completed = append(completed, distResp.route)
 			mux.Unlock()
+			if onRoute != nil {
+				onRoute(distResp.route, distResp.res)
+			}
 		}
 	}
-	return distances
+	return distances, completed
 }
 
-func getDistancesAlongRoute(mc *maps.Client, routeID string, directionID int, stops []bustime.BusStop, fetched chan distanceResponse, existingSDs map[stopdistance.Key]float64) {
+func getDistancesAlongRoute(dp mapping.DistanceProvider, routeID string, directionID int, stops []bustime.BusStop, fetched chan distanceResponse, existingSDs map[stopdistance.Key]float64) {
+	route := mapping.RouteKey{RouteID: routeID, DirectionID: directionID}
 	if len(stops) < 2 {
-		fetched <- distanceResponse{nil, errors.New("getDistancesAlongRoute: fewer than 2 stops in list provided")}
+		fetched <- distanceResponse{route, nil, errors.New("getDistancesAlongRoute: fewer than 2 stops in list provided")}
 		return
 	}
 	log.Printf("Fetching distances for routeID: %s, directionID: %d\n", routeID, directionID)
-	dists := make([]bus.StopDistance, len(stops)-1)
+	// Collect all the ordered stop pairs that we don't have a distance for yet
+	var pairs []mapping.StopPair
 	for i := 0; i < len(stops); i++ {
 		for j := i + 1; j < len(stops); j++ {
 			from, to := stops[i], stops[j]
@@ -55,13 +64,40 @@ func getDistancesAlongRoute(mc *maps.Client, routeID string, directionID int, st
 			if _, exists := existingSDs[k]; exists {
 				continue
 			}
-			dists[i] = bus.StopDistance{
-				RouteID: routeID, DirectionID: directionID, FromID: from.ID, ToID: to.ID,
-				Distance: mapping.RoadDistance(mc, from.Latitude, from.Longitude, to.Latitude, to.Longitude),
-			}
-			existingSDs[k] = 123.0
+			pairs = append(pairs, mapping.StopPair{From: from, To: to})
+		}
+	}
+	distances, err := distancesForPairs(dp, routeID, directionID, pairs)
+	if err != nil {
+		fetched <- distanceResponse{route, nil, fmt.Errorf("getDistancesAlongRoute: %s", err)}
+		return
+	}
+	dists := make([]bus.StopDistance, len(pairs))
+	for i, pair := range pairs {
+		dists[i] = bus.StopDistance{
+			RouteID: routeID, DirectionID: directionID, FromID: pair.From.ID, ToID: pair.To.ID, Distance: distances[i],
 		}
 	}
 	log.Printf("Succesfully fetched distances for routeID: %s, directionID: %d\n", routeID, directionID)
-	fetched <- distanceResponse{dists, nil}
+	fetched <- distanceResponse{route, dists, nil}
+}
+
+// distancesForPairs uses a single batched call if the provider supports it,
+// or else calculates the distance for each pair individually.
+func distancesForPairs(dp mapping.DistanceProvider, routeID string, directionID int, pairs []mapping.StopPair) ([]float64, error) {
+	if len(pairs) == 0 {
+		return nil, nil
+	}
+	if bp, ok := dp.(mapping.BatchProvider); ok {
+		return bp.Distances(routeID, directionID, pairs)
+	}
+	distances := make([]float64, len(pairs))
+	for i, pair := range pairs {
+		distance, err := dp.Distance(routeID, directionID, pair.From, pair.To)
+		if err != nil {
+			return nil, err
+		}
+		distances[i] = distance
+	}
+	return distances, nil
 }
@@ -3,13 +3,16 @@ package main
 import (
 	"fmt"
 	"log"
-	"net/url"
+	"net/http"
+	"net/http/httptest"
 	"sort"
+	"strconv"
 	"strings"
 	"testing"
 	"transport/lib/bus"
 	"transport/lib/bustime"
-	"transport/lib/testhelper"
+	"transport/lib/mapping"
+	"transport/services/labeller/stopdistance"
 
 	"github.com/stretchr/testify/assert"
 
@@ -17,7 +20,7 @@ import (
 )
 
 func TestGetDistances(t *testing.T) {
-	ts := testhelper.ServeMultiResponseMock(mockedDistanceResponses, extractCoordsFromURL)
+	ts := serveDistanceMatrix(mockedDistances)
 	defer ts.Close()
 
 	mc, err := maps.NewClient(maps.WithAPIKey("TEST"), maps.WithBaseURL(ts.URL))
@@ -26,29 +29,53 @@ func TestGetDistances(t *testing.T) {
 	}
 
 	expected := stopDistances
-	actual := GetDistances(mc, stopDetails)
+	actual, _ := GetDistances(mapping.NewGoogleProvider(mc), stopDetails, map[stopdistance.Key]float64{}, nil)
 	sort.Slice(actual, func(i, j int) bool {
+		if actual[i].FromID == actual[j].FromID {
+			return actual[i].ToID < actual[j].ToID
+		}
 		return actual[i].FromID < actual[j].FromID
 	})
 	assert.Equal(t, expected, actual)
 }
 
-func extractCoordsFromURL(fullURL *url.URL) string {
-	from, err := url.PathUnescape(strings.Replace(fullURL.Query().Get("origins"), "00000", "", -1))
-	if err != nil {
-		log.Panicf("extractCoordsFromURL: %s", err)
-	}
-	to, err := url.PathUnescape(strings.Replace(fullURL.Query().Get("destinations"), "00000", "", -1))
-	if err != nil {
-		log.Panicf("extractCoordsFromURL: %s", err)
+// serveDistanceMatrix creates a mock Distance Matrix API which looks up each element
+// of the requested matrix in `distances`, keyed by "fromLat,fromLon;toLat,toLon"
+func serveDistanceMatrix(distances map[string]int) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		origins := strings.Split(r.URL.Query().Get("origins"), "|")
+		destinations := strings.Split(r.URL.Query().Get("destinations"), "|")
+		rows := make([]string, len(origins))
+		for i, origin := range origins {
+			elements := make([]string, len(destinations))
+			for j, destination := range destinations {
+				key := fmt.Sprintf("%s;%s", trimCoords(origin), trimCoords(destination))
+				if distance, found := distances[key]; found {
+					elements[j] = fmt.Sprintf(`{"status": "OK", "distance": {"value": %d}}`, distance)
+				} else {
+					elements[j] = `{"status": "NOT_FOUND"}`
+				}
+			}
+			rows[i] = fmt.Sprintf(`{"elements": [%s]}`, strings.Join(elements, ","))
+		}
+		fmt.Fprintf(w, `{"rows": [%s], "status": "OK"}`, strings.Join(rows, ","))
+	}))
+}
+
+// trimCoords removes trailing zeroes from coordinates, e.g. "12.300000,34.500000" -> "12.3,34.5"
+func trimCoords(coords string) string {
+	var lat, lon float64
+	if _, err := fmt.Sscanf(coords, "%f,%f", &lat, &lon); err != nil {
+		log.Panicf("trimCoords: %s", err)
 	}
-	return fmt.Sprintf("%s;%s", from, to)
+	return fmt.Sprintf("%s,%s", strconv.FormatFloat(lat, 'f', -1, 64), strconv.FormatFloat(lon, 'f', -1, 64))
 }
 
-var mockedDistanceResponses = map[string]string{
-	"12.3,34.5;67.8,90.1": `{"rows": [{"elements": [{"distance": {"value": 157}}]}], "status": "OK"}`,
-	"67.8,90.1;23.4,56.7": `{"rows": [{"elements": [{"distance": {"value": 148}}]}], "status": "OK"}`,
-	"14.1,23.5;13.8,83.1": `{"rows": [{"elements": [{"distance": {"value": 127}}]}], "status": "OK"}`,
+var mockedDistances = map[string]int{
+	"12.3,34.5;67.8,90.1": 157,
+	"67.8,90.1;23.4,56.7": 148,
+	"12.3,34.5;23.4,56.7": 305,
+	"14.1,23.5;13.8,83.1": 127,
 }
 
 var stopDetails = map[string]map[int][]bustime.BusStop{
@@ -73,6 +100,7 @@ var stopDetails = map[string]map[int][]bustime.BusStop{
 
 var stopDistances = []bus.StopDistance{
 	{RouteID: "MTA M1", DirectionID: 0, FromID: "Stop1", ToID: "Stop2", Distance: 157},
+	{RouteID: "MTA M1", DirectionID: 0, FromID: "Stop1", ToID: "Stop3", Distance: 305},
 	{RouteID: "MTA M1", DirectionID: 0, FromID: "Stop2", ToID: "Stop3", Distance: 148},
 	{RouteID: "MTA M1", DirectionID: 1, FromID: "Stop4", ToID: "Stop5", Distance: 127},
 }
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"transport/lib/bustime"
+	"transport/lib/mapping"
+)
+
+// jobState records the progress of a stop distance computation so that a run which
+// is interrupted (or runs out of API quota) can be resumed without repeating work.
+type jobState struct {
+	path string
+	// The Distance Matrix quota day and the number of elements used on it
+	QuotaDay     string
+	ElementsUsed int
+	// Routes (and directions) whose distances have all been calculated and stored,
+	// keyed by routeKeyString
+	Completed map[string]bool
+}
+
+// loadJobState reads the job state stored at `path`, or starts a new job if there isn't one
+func loadJobState(path string) (*jobState, error) {
+	job := &jobState{path: path, Completed: map[string]bool{}}
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return job, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("loadJobState: failed to read '%s': %s", path, err)
+	}
+	if err := json.Unmarshal(data, job); err != nil {
+		return nil, fmt.Errorf("loadJobState: failed to parse '%s': %s", path, err)
+	}
+	if job.Completed == nil {
+		job.Completed = map[string]bool{}
+	}
+	log.Printf("Resuming job with %d completed routes from %s\n", len(job.Completed), path)
+	return job, nil
+}
+
+// remaining returns the subset of stopDetails that hasn't been completed yet
+func (job *jobState) remaining(stopDetails map[string]map[int][]bustime.BusStop) map[string]map[int][]bustime.BusStop {
+	result := map[string]map[int][]bustime.BusStop{}
+	for routeID, directions := range stopDetails {
+		for directionID, stops := range directions {
+			if job.Completed[routeKeyString(mapping.RouteKey{RouteID: routeID, DirectionID: directionID})] {
+				continue
+			}
+			if _, exists := result[routeID]; !exists {
+				result[routeID] = map[int][]bustime.BusStop{}
+			}
+			result[routeID][directionID] = stops
+		}
+	}
+	return result
+}
+
+func (job *jobState) markCompleted(routes []mapping.RouteKey) {
+	for _, route := range routes {
+		job.Completed[routeKeyString(route)] = true
+	}
+}
+
+// reset clears the list of completed routes so that the next run starts from scratch.
+// Quota usage is kept, as it still counts towards today's quota.
+func (job *jobState) reset() {
+	job.Completed = map[string]bool{}
+}
+
+// save writes the job state to the path it was loaded from. The file is replaced
+// atomically, so an interrupted save never leaves a job that can't be resumed.
+func (job *jobState) save() error {
+	data, err := json.Marshal(job)
+	if err != nil {
+		return fmt.Errorf("jobState.save: failed to marshal job state: %s", err)
+	}
+	tmpPath := job.path + ".tmp"
+	if err := ioutil.WriteFile(tmpPath, data, 0644); err != nil {
+		return fmt.Errorf("jobState.save: failed to write '%s': %s", tmpPath, err)
+	}
+	if err := os.Rename(tmpPath, job.path); err != nil {
+		return fmt.Errorf("jobState.save: failed to replace '%s': %s", job.path, err)
+	}
+	return nil
+}
+
+func routeKeyString(route mapping.RouteKey) string {
+	return fmt.Sprintf("%s|%d", route.RouteID, route.DirectionID)
+}
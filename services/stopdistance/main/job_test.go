@@ -0,0 +1,33 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"transport/lib/mapping"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJobState_SaveAndLoad(t *testing.T) {
+	dir, err := ioutil.TempDir("", "stopdistance")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "job.json")
+
+	job, err := loadJobState(path)
+	assert.Nil(t, err)
+	job.markCompleted([]mapping.RouteKey{{RouteID: "MTA NYCT_B41", DirectionID: 1}})
+	job.ElementsUsed = 10
+	assert.Nil(t, job.save())
+
+	// The state is written to a temporary file and then moved into place
+	_, err = os.Stat(path + ".tmp")
+	assert.True(t, os.IsNotExist(err))
+
+	resumed, err := loadJobState(path)
+	assert.Nil(t, err)
+	assert.Equal(t, 10, resumed.ElementsUsed)
+	assert.True(t, resumed.Completed["MTA NYCT_B41|1"])
+}
@@ -2,25 +2,33 @@ package main
 
 import (
 	"log"
+	"os"
+	"os/signal"
+	"strconv"
+	"sync"
+	"syscall"
 	"transport/lib/bus"
 	"transport/lib/bustime"
 	"transport/lib/database"
 	"transport/lib/iohelper"
+	"transport/lib/mapping"
+	"transport/lib/metrics"
 	"transport/services/labeller/stopdistance"
 
 	"googlemaps.github.io/maps"
 )
 
+const (
+	defaultQPS           = "10"
+	defaultDailyElements = "100000"
+)
+
 func main() {
 	db := database.OpenDBConnection()
 	defer db.Close()
 	existingSDs := stopdistance.Get(db)
 
 	bt := bustime.NewClient(iohelper.GetEnv("MTA_API_KEY"))
-	mc, err := maps.NewClient(maps.WithAPIKey(iohelper.GetEnv("GOOGLE_MAPS_API_KEY")))
-	if err != nil {
-		log.Panicf("main: failed to initialise Maps API client: %s", err)
-	}
 
 	// Get stopDetails in map with format routeID -> directionID -> []BusStop
 	agencies := bt.GetAgencies()
@@ -29,9 +37,121 @@ func main() {
 	log.Printf("%d routes fetched\n", len(agencies))
 	stopDetails := bt.GetStops(routes...)
 
-	// Calculate distances between stops and store in DB
-	distances := GetDistances(mc, stopDetails, existingSDs)
-	storeDistances(distances)
+	// Resume any previous run, skipping the routes it completed
+	job, err := loadJobState(iohelper.GetEnvOrDefault("STOP_DISTANCE_JOB_PATH", "stopDistanceJob.json"))
+	if err != nil {
+		log.Fatalf("main: %s", err)
+	}
+	remaining := job.remaining(stopDetails)
+
+	// Calculate distances between stops, storing each route's distances (and saving the
+	// job's progress) as soon as they arrive, so that an interrupted job loses as little as possible
+	dp, saveProvider := createDistanceProvider(providerName(), bt, routes, job)
+	progress := &sync.Mutex{}
+	checkpoint := func() {
+		progress.Lock()
+		defer progress.Unlock()
+		saveProvider()
+		if err := job.save(); err != nil {
+			log.Printf("main: %s", err)
+		}
+	}
+	saveOnSignal(checkpoint)
+	GetDistances(dp, remaining, existingSDs, func(route mapping.RouteKey, distances []bus.StopDistance) {
+		if len(distances) > 0 {
+			storeDistances(distances)
+		}
+		progress.Lock()
+		job.markCompleted([]mapping.RouteKey{route})
+		progress.Unlock()
+		checkpoint()
+	})
+
+	if len(job.remaining(stopDetails)) == 0 {
+		log.Println("Distances calculated for all routes, resetting job state")
+		progress.Lock()
+		job.reset()
+		progress.Unlock()
+	}
+	checkpoint()
+	metrics.PushToFile()
+}
+
+// saveOnSignal calls `checkpoint` and exits if the process is interrupted or terminated,
+// so that the cache and job state are saved even if the run is stopped part way through
+func saveOnSignal(checkpoint func()) {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+	go func() {
+		sig := <-signals
+		log.Printf("Received %s, saving progress before exiting\n", sig)
+		checkpoint()
+		os.Exit(1)
+	}()
+}
+
+// providerName returns the distance provider selected in the CLI args,
+// defaulting to the Google Distance Matrix API.
+func providerName() string {
+	if len(os.Args) < 2 {
+		return "google"
+	}
+	return os.Args[1]
+}
+
+// createDistanceProvider returns either a provider which calculates distances using
+// the Google Distance Matrix API ("google"), or one which measures them along the
+// route polylines fetched from BusTime without any further requests ("offline").
+// The returned function persists the provider's cache and quota usage, and is called
+// after each route's distances have been calculated.
+func createDistanceProvider(name string, bt *bustime.Client, routes []string, job *jobState) (mapping.DistanceProvider, func()) {
+	switch name {
+	case "google":
+		return createGoogleProvider(job)
+	case "offline":
+		gp, err := mapping.NewGeometryProvider(bt.GetPolylines(routes...))
+		if err != nil {
+			log.Panicf("main: failed to initialise geometry provider: %s", err)
+		}
+		return gp, func() {}
+	default:
+		log.Fatalf("%s is not a valid distance provider, you can pick either 'google' or 'offline'", name)
+		return nil, nil
+	}
+}
+
+// createGoogleProvider configures a cached, rate limited Google provider using the
+// following environment variables:
+//     DISTANCE_CACHE_PATH: file the request cache is persisted to
+//     GOOGLE_MAPS_QPS: maximum number of requests per second
+//     GOOGLE_MAPS_DAILY_ELEMENTS: maximum number of elements (origins * destinations) per day
+func createGoogleProvider(job *jobState) (mapping.DistanceProvider, func()) {
+	mc, err := maps.NewClient(maps.WithAPIKey(iohelper.GetEnv("GOOGLE_MAPS_API_KEY")))
+	if err != nil {
+		log.Panicf("main: failed to initialise Maps API client: %s", err)
+	}
+	cache, err := mapping.LoadDistanceCache(iohelper.GetEnvOrDefault("DISTANCE_CACHE_PATH", "distanceCache.json"))
+	if err != nil {
+		log.Fatalf("main: %s", err)
+	}
+	qps, err := strconv.ParseFloat(iohelper.GetEnvOrDefault("GOOGLE_MAPS_QPS", defaultQPS), 64)
+	if err != nil {
+		log.Fatalf("main: GOOGLE_MAPS_QPS must be a number: %s", err)
+	}
+	dailyElements, err := strconv.Atoi(iohelper.GetEnvOrDefault("GOOGLE_MAPS_DAILY_ELEMENTS", defaultDailyElements))
+	if err != nil {
+		log.Fatalf("main: GOOGLE_MAPS_DAILY_ELEMENTS must be an integer: %s", err)
+	}
+	limiter := mapping.NewLimiter(qps, dailyElements)
+	limiter.Restore(job.QuotaDay, job.ElementsUsed)
+	gp := mapping.NewGoogleProvider(mc, mapping.CacheOption(cache), mapping.LimiterOption(limiter))
+	return gp, func() {
+		if err := cache.Save(); err != nil {
+			log.Printf("main: %s", err)
+		}
+		job.QuotaDay, job.ElementsUsed = limiter.Usage()
+		log.Printf("%d Distance Matrix elements used on %s\n", job.ElementsUsed, job.QuotaDay)
+	}
 }
 
 func storeDistances(distances []bus.StopDistance) {
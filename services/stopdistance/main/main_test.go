@@ -1,7 +1,6 @@
 package main
 
 import (
-	"fmt"
 	"log"
 	"net/url"
 	"sort"
@@ -9,7 +8,9 @@ import (
 	"testing"
 	"transport/lib/bus"
 	"transport/lib/bustime"
+	"transport/lib/mapping"
 	"transport/lib/testhelper"
+	"transport/services/labeller/stopdistance"
 
 	"github.com/stretchr/testify/assert"
 	"googlemaps.github.io/maps"
@@ -19,7 +20,7 @@ func TestGetDistancesIntegration(t *testing.T) {
 	btMock := testhelper.ServeMultiResponseMock(bustimeResponses, extractBustimeEndpoint)
 	bt := bustime.NewClient("TEST", bustime.CustomBaseURLOption(btMock.URL))
 
-	mapsMock := testhelper.ServeMultiResponseMock(distanceResponses, extractCoordsFromURL)
+	mapsMock := serveDistanceMatrix(distances)
 	mc, err := maps.NewClient(maps.WithAPIKey("TEST"), maps.WithBaseURL(mapsMock.URL))
 	if err != nil {
 		log.Panicf("main: failed to initialise Maps API client: %s", err)
@@ -34,12 +35,17 @@ func TestGetDistancesIntegration(t *testing.T) {
 	// Calculate distances between stops and store in DB
 	expected := []bus.StopDistance{
 		{RouteID: "MTA NYCT_M1", DirectionID: 0, FromID: "MTA_100001", ToID: "MTA_100002", Distance: 123},
+		{RouteID: "MTA NYCT_M1", DirectionID: 0, FromID: "MTA_100001", ToID: "MTA_100003", Distance: 579},
 		{RouteID: "MTA NYCT_M1", DirectionID: 0, FromID: "MTA_100002", ToID: "MTA_100003", Distance: 456},
 		{RouteID: "MTA NYCT_M1", DirectionID: 1, FromID: "MTA_100004", ToID: "MTA_100005", Distance: 789},
+		{RouteID: "MTA NYCT_M1", DirectionID: 1, FromID: "MTA_100004", ToID: "MTA_100006", Distance: 1800},
 		{RouteID: "MTA NYCT_M1", DirectionID: 1, FromID: "MTA_100005", ToID: "MTA_100006", Distance: 1011},
 	}
-	actual := GetDistances(mc, stopDetails)
+	actual, _ := GetDistances(mapping.NewGoogleProvider(mc), stopDetails, map[stopdistance.Key]float64{}, nil)
 	sort.Slice(actual, func(i, j int) bool {
+		if actual[i].FromID == actual[j].FromID {
+			return actual[i].ToID < actual[j].ToID
+		}
 		return actual[i].FromID < actual[j].FromID
 	})
 	assert.Equal(t, expected, actual)
@@ -57,15 +63,13 @@ func extractBustimeEndpoint(fullURL *url.URL) string {
 	}
 }
 
-func distanceJSON(distance int) string {
-	return fmt.Sprintf(`{"rows": [{"elements": [{"distance": {"value": %d}}]}], "status": "OK"}`, distance)
-}
-
-var distanceResponses = map[string]string{
-	"40.7,-73.9;40.8,-73.3": distanceJSON(123),
-	"40.8,-73.3;40.1,-72.8": distanceJSON(456),
-	"49.7,-73.9;48.8,-72.3": distanceJSON(789),
-	"48.8,-72.3;49.1,-73.8": distanceJSON(1011),
+var distances = map[string]int{
+	"40.7,-73.9;40.8,-73.3": 123,
+	"40.8,-73.3;40.1,-72.8": 456,
+	"40.7,-73.9;40.1,-72.8": 579,
+	"49.7,-73.9;48.8,-72.3": 789,
+	"48.8,-72.3;49.1,-73.8": 1011,
+	"49.7,-73.9;49.1,-73.8": 1800,
 }
 
 var bustimeResponses = map[string]string{
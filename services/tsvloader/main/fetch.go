@@ -65,25 +65,31 @@ func decompressFile(compressedPath string) (decompressedPath string) {
 	return newPath
 }
 
-// removeNullRows removes all rows in the TSV file at 'path' that have null values in any column.
-// Returns a pointer to a []byte containing only the valid rows.
-func removeNullRows(path string) (validRows *[]byte) {
-	cleanedRows, err := csvhelper.RemoveNullRows(path, "\t")
+// loadCleanedMTAData streams the TSV file at 'path' through a csvhelper.Cleaner, removing
+// all rows that have null values in any column, and unmarshals the remaining rows into
+// ArrivalEntry structs. The file is never held in memory as a whole.
+func loadCleanedMTAData(path string) []ArrivalEntry {
+	file, err := os.Open(path)
 	if err != nil {
-		panic(fmt.Sprintf(
-			"failed to remove null rows from '%s' due to the following error: %v\n",
-			path,
-			err,
-		))
+		panic(fmt.Sprintf("failed to open '%s' due to the following error: %v\n", path, err))
 	}
-	log.Printf("Successfully removed null rows from %s...\n", path)
-	return cleanedRows
+	defer file.Close()
+	reader, writer := io.Pipe()
+	// Closing the reader unblocks the cleaner if unmarshalling stops early
+	defer reader.Close()
+	go func() {
+		stats, err := csvhelper.NewCleaner("\t").Clean(file, writer)
+		if err == nil {
+			log.Printf("Successfully removed null rows from %s: %s\n", path, stats)
+		}
+		writer.CloseWithError(err)
+	}()
+	return unmarshalMTAData(reader)
 }
 
-// Takes the MTA data in TSV format (as a []byte) and returns an array
-// of marshalled ArrivalEntry structs
-func unmarshalMTADataBytes(bytes *[]byte) []ArrivalEntry {
-	// Our structs will be added to the 'entries' slice as the bytes are being unmarshalled
+// Takes the MTA data in TSV format and returns an array of marshalled ArrivalEntry structs
+func unmarshalMTAData(in io.Reader) []ArrivalEntry {
+	// Our structs will be added to the 'entries' slice as the rows are being unmarshalled
 	var entries []ArrivalEntry
 
 	// Tell gocsv we're using tabs (TSVs) instead of commas (CSVs)
@@ -96,9 +102,8 @@ func unmarshalMTADataBytes(bytes *[]byte) []ArrivalEntry {
 	log.Println("Unmarshalling rows...")
 
 	// Unmarshal the .tsv file into an array of ArrivalEntry structs
-	if err := gocsv.UnmarshalBytes(*bytes, &entries); err != nil {
+	if err := gocsv.Unmarshal(in, &entries); err != nil {
 		log.Printf("Following error whilst unmarshalling: %s\n", err)
-		log.Printf("Error occurred whilst unmarshalling the following: %s\n", string(*bytes))
 		panic(err)
 	}
 
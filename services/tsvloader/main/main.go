@@ -1,10 +1,13 @@
 package main
 
 import (
+	"fmt"
 	"log"
 	"os"
 	"strconv"
 	"transport/lib/database"
+	"transport/lib/metrics"
+	"transport/lib/progress"
 )
 
 func main() {
@@ -22,6 +25,7 @@ func main() {
 	}
 	// Store archives in DB
 	fetchAndStoreArchives(hostID, hostCount, storageDirectory)
+	metrics.PushToFile()
 }
 
 // Gets URLs for the mtaArchive date range and concurrently
@@ -39,17 +43,19 @@ func fetchAndStoreArchives(hostID int, hostCount int, storageDirectory string) {
 	log.Printf("Last Task Index: %d\n", firstTaskIndex+taskCount-1)
 
 	// Process 'taskCount' URLs starting from firstTaskIndex
+	tracker := progress.NewTracker(fmt.Sprintf("Loading archives on host %d", hostID), taskCount)
 	for i := firstTaskIndex; i < firstTaskIndex+taskCount; i++ {
 		fetchAndStore(URLs[i], storageDirectory)
+		tracker.Increment()
 	}
+	tracker.Done()
 }
 
 // Fetches and stores the data for a single URL
 func fetchAndStore(URL string, storageDirectory string) {
 	compressedFile := fetchSingleDay(URL, storageDirectory)
 	decompressedFile := decompressFile(compressedFile)
-	validRows := removeNullRows(decompressedFile)
-	arrivalEntries := unmarshalMTADataBytes(validRows)
+	arrivalEntries := loadCleanedMTAData(decompressedFile)
 	removeDataFiles(compressedFile, decompressedFile)
 	database.Store(database.VehicleJourneyTable, extractColsFromArrivalEntry, arrivalEntries)
 }
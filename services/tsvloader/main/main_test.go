@@ -1,8 +1,8 @@
 package main
 
 import (
-	"io/ioutil"
 	"log"
+	"os"
 	"reflect"
 	"testing"
 	"time"
@@ -37,11 +37,12 @@ var expectedStructs = []ArrivalEntry{{
 }}
 
 func TestLoadMTAData(t *testing.T) {
-	data, err := ioutil.ReadFile("./sampledata_test.tsv")
+	data, err := os.Open("./sampledata_test.tsv")
 	if err != nil {
 		log.Fatalf("Error whilst loading file for test: %v\n", err)
 	}
-	parsedData := unmarshalMTADataBytes(&data)
+	defer data.Close()
+	parsedData := unmarshalMTAData(data)
 
 	for i, expectedEntry := range expectedStructs {
 		if !reflect.DeepEqual(parsedData[i], expectedEntry) {
@@ -53,3 +54,10 @@ func TestLoadMTAData(t *testing.T) {
 		}
 	}
 }
+
+func TestLoadCleanedMTAData(t *testing.T) {
+	parsedData := loadCleanedMTAData("./sampledata_test.tsv")
+	if !reflect.DeepEqual(parsedData, expectedStructs) {
+		t.Errorf("Mismatch between entries:\n Expected: %+v\n Received: %+v", expectedStructs, parsedData)
+	}
+}